@@ -4,7 +4,6 @@ import (
 	"bytes"
 	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"os/exec"
@@ -139,31 +138,6 @@ func RunAhkCode(script string) (string, error) {
 	return runAHKScriptViaStdin(context.Background(), script)
 }
 
-// GetScriptCountdownSeconds 从脚本文件中解析倒计时配置，支持多种命名（例如 countdownSeconds 或 DisplaySeconds）。
-func GetScriptCountdownSeconds(scriptFullPath string) int {
-	data, err := ioutil.ReadFile(scriptFullPath)
-	if err != nil {
-		return 5
-	}
-	content := string(data)
-	lines := strings.Split(content, "\n")
-	for _, ln := range lines {
-		ln = strings.TrimSpace(ln)
-		// 支持多种写法，例如: countdownSeconds := 5 或 DisplaySeconds := 5 或 DisplaySeconds = 5
-		if strings.Contains(ln, "countdownSeconds") || strings.Contains(ln, "DisplaySeconds") {
-			// 从该行中提取最后一个整数
-			parts := strings.FieldsFunc(ln, func(r rune) bool { return r == ':' || r == '=' || r == ' ' || r == '\t' || r == ',' })
-			for i := len(parts) - 1; i >= 0; i-- {
-				p := strings.Trim(parts[i], " \t\r\n")
-				if n, err := strconv.Atoi(p); err == nil {
-					return n
-				}
-			}
-		}
-	}
-	return 5
-}
-
 // SetSystemVolumeAHK 使用 AHK 设置系统主音量（0-100）
 func SetSystemVolumeAHK(ctx context.Context, v int) error {
 	if v < 0 {
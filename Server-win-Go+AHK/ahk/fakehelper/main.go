@@ -0,0 +1,73 @@
+// fakehelper 是 ahk/script/helper.ahk 的替身，在内存中模拟音量状态并实现相同的行协议，
+// 用于在没有 AutoHotkey 的环境（包括非 Windows 平台）中测试 ahk.Helper：
+//
+//	go build -o fakehelper ./ahk/fakehelper
+//	ahk.NewHelper("./fakehelper")
+//
+// 除正式命令外还支持三条测试命令：CRASH 立即以非零状态退出，HANG 不作应答，
+// SLOW <毫秒> 等待指定时间后才回复 "OK SLOW"。
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+func main() {
+	volume, muted := 50, false
+	scanner := bufio.NewScanner(os.Stdin)
+	out := bufio.NewWriter(os.Stdout)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		var reply string
+		switch fields[0] {
+		case "PING":
+			reply = "OK PONG"
+		case "GET_VOLUME":
+			reply = fmt.Sprintf("OK %d", volume)
+		case "SET_VOLUME":
+			if len(fields) < 2 {
+				reply = "ERR 缺少音量参数"
+				break
+			}
+			v, err := strconv.Atoi(fields[1])
+			if err != nil || v < 0 || v > 100 {
+				reply = "ERR 无效的音量: " + fields[1]
+				break
+			}
+			volume = v
+			reply = "OK"
+		case "GET_MUTE":
+			if muted {
+				reply = "OK On"
+			} else {
+				reply = "OK Off"
+			}
+		case "MUTE":
+			muted = !muted
+			reply = "OK"
+		case "CRASH":
+			os.Exit(3)
+		case "HANG":
+			continue
+		case "SLOW":
+			ms := 0
+			if len(fields) > 1 {
+				ms, _ = strconv.Atoi(fields[1])
+			}
+			time.Sleep(time.Duration(ms) * time.Millisecond)
+			reply = "OK SLOW"
+		default:
+			reply = "ERR 未知命令: " + fields[0]
+		}
+		fmt.Fprintln(out, reply)
+		out.Flush()
+	}
+}
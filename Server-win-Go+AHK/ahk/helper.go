@@ -0,0 +1,186 @@
+package ahk
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// HelperScriptName 常驻辅助脚本的文件名，位于 ahk/script 下。
+const HelperScriptName = "helper.ahk"
+
+// defaultHelperTimeout 单条命令等待应答的最长时间。
+const defaultHelperTimeout = 3 * time.Second
+
+// ErrHelperTimeout 辅助进程在超时时间内没有应答。
+var ErrHelperTimeout = errors.New("AHK 辅助进程应答超时")
+
+// Helper 管理一个常驻的辅助进程（默认为 AutoHotkey.exe helper.ahk），
+// 通过 stdin/stdout 以行为单位交换命令和应答，避免每次操作都启动新进程。
+//
+// 协议：每条命令占一行，例如 "GET_VOLUME"、"SET_VOLUME 40"、"MUTE"；
+// 辅助进程对每条命令回复一行 "OK" / "OK <结果>" 或 "ERR <原因>"。
+// 进程崩溃或退出后，下一次调用会自动重新启动它。
+// 任何实现了同样协议的可执行文件都可以替代 AHK 脚本（见 ahk/fakehelper）。
+type Helper struct {
+	path    string
+	args    []string
+	timeout time.Duration
+
+	mu     sync.Mutex
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	lines  chan string   // 辅助进程的输出行，进程退出后关闭
+	exited chan struct{} // 进程退出后关闭
+}
+
+// NewHelper 创建辅助进程管理器，进程在第一次调用时才启动。
+func NewHelper(path string, args ...string) *Helper {
+	return &Helper{path: path, args: args, timeout: defaultHelperTimeout}
+}
+
+// NewScriptHelper 创建运行 ahk/script 下 helper.ahk 的辅助进程管理器。
+func NewScriptHelper() (*Helper, error) {
+	ahkPath, err := getAhkPath()
+	if err != nil {
+		return nil, err
+	}
+	scriptFullPath := filepath.Join(filepath.Dir(os.Args[0]), "ahk", "script", HelperScriptName)
+	return NewHelper(ahkPath, "/ErrorStdOut", scriptFullPath), nil
+}
+
+// SetTimeout 设置单条命令等待应答的最长时间。
+func (h *Helper) SetTimeout(d time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.timeout = d
+}
+
+// Call 发送一条命令并返回应答中 "OK" 之后的内容。
+// 辅助进程回复 "ERR" 时返回包含原因的错误。
+func (h *Helper) Call(command string) (string, error) {
+	if strings.ContainsAny(command, "\r\n") {
+		return "", fmt.Errorf("命令中不能包含换行: %q", command)
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if err := h.ensureRunning(); err != nil {
+		return "", err
+	}
+	if _, err := io.WriteString(h.stdin, command+"\n"); err != nil {
+		// 命令未送达，重启后重试一次
+		log.Printf("[AHK Helper] 写入命令失败，重启辅助进程: %v", err)
+		h.stopLocked()
+		if err := h.ensureRunning(); err != nil {
+			return "", err
+		}
+		if _, err := io.WriteString(h.stdin, command+"\n"); err != nil {
+			h.stopLocked()
+			return "", fmt.Errorf("向 AHK 辅助进程发送命令失败: %w", err)
+		}
+	}
+
+	// 命令已送达后不再重试，避免 MUTE 之类的非幂等命令被执行两次
+	select {
+	case line, ok := <-h.lines:
+		if !ok {
+			h.stopLocked()
+			return "", fmt.Errorf("AHK 辅助进程在处理 %q 时退出", command)
+		}
+		return parseHelperReply(line)
+	case <-time.After(h.timeout):
+		h.stopLocked()
+		return "", fmt.Errorf("%w: %q", ErrHelperTimeout, command)
+	}
+}
+
+// parseHelperReply 解析一行应答。
+func parseHelperReply(line string) (string, error) {
+	line = strings.TrimSpace(line)
+	switch {
+	case line == "OK":
+		return "", nil
+	case strings.HasPrefix(line, "OK "):
+		return strings.TrimSpace(line[len("OK "):]), nil
+	case line == "ERR" || strings.HasPrefix(line, "ERR "):
+		return "", fmt.Errorf("AHK 辅助进程返回错误: %s", strings.TrimSpace(strings.TrimPrefix(line, "ERR")))
+	}
+	return "", fmt.Errorf("无法解析 AHK 辅助进程的应答: %q", line)
+}
+
+// ensureRunning 在辅助进程未运行（或已退出）时启动它，调用方需持有 h.mu。
+func (h *Helper) ensureRunning() error {
+	if h.cmd != nil {
+		select {
+		case <-h.exited:
+			log.Println("[AHK Helper] 辅助进程已退出，正在重新启动...")
+			h.stopLocked()
+		default:
+			return nil
+		}
+	}
+
+	cmd := exec.Command(h.path, h.args...)
+	hideWindow(cmd)
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return fmt.Errorf("创建 AHK 辅助进程 stdin 失败: %w", err)
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("创建 AHK 辅助进程 stdout 失败: %w", err)
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("启动 AHK 辅助进程失败 (请确认 %s 存在): %w", h.path, err)
+	}
+
+	lines := make(chan string, 16)
+	exited := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+		// 输出读完后再 Wait，避免 Wait 提前关闭 stdout 丢失应答
+		err := cmd.Wait()
+		log.Printf("[AHK Helper] 辅助进程 (PID: %d) 已退出: %v", cmd.Process.Pid, err)
+		close(exited)
+	}()
+
+	h.cmd, h.stdin, h.lines, h.exited = cmd, stdin, lines, exited
+	log.Printf("[AHK Helper] 辅助进程已启动 (PID: %d)", cmd.Process.Pid)
+	return nil
+}
+
+// stopLocked 结束当前辅助进程，调用方需持有 h.mu。
+func (h *Helper) stopLocked() {
+	if h.cmd == nil {
+		return
+	}
+	h.stdin.Close()
+	h.cmd.Process.Kill()
+	// 丢弃尚未读取的输出，让读取 goroutine 能够结束
+	go func(lines chan string) {
+		for range lines {
+		}
+	}(h.lines)
+	h.cmd, h.stdin, h.lines, h.exited = nil, nil, nil, nil
+}
+
+// Close 结束辅助进程。之后再调用 Call 会重新启动它。
+func (h *Helper) Close() error {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.stopLocked()
+	return nil
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package ahk
+
+import "os/exec"
+
+// hideWindow 在非 Windows 平台上无需处理。
+func hideWindow(cmd *exec.Cmd) {}
@@ -0,0 +1,211 @@
+package ahk
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHelperPath 由 TestMain 编译的 ahk/fakehelper 可执行文件路径。
+var fakeHelperPath string
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bealink-fakehelper")
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fakeHelperPath = filepath.Join(dir, "fakehelper")
+	if runtime.GOOS == "windows" {
+		fakeHelperPath += ".exe"
+	}
+	build := exec.Command("go", "build", "-o", fakeHelperPath, "./fakehelper")
+	build.Stdout, build.Stderr = os.Stderr, os.Stderr
+	if err := build.Run(); err != nil {
+		fmt.Fprintf(os.Stderr, "编译 fakehelper 失败: %v\n", err)
+		os.RemoveAll(dir)
+		os.Exit(1)
+	}
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+func newFakeHelper(t *testing.T) *Helper {
+	t.Helper()
+	h := NewHelper(fakeHelperPath)
+	t.Cleanup(func() { h.Close() })
+	return h
+}
+
+// helperPid 返回当前辅助进程的 PID，未运行时返回 0。
+func helperPid(h *Helper) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.cmd == nil {
+		return 0
+	}
+	return h.cmd.Process.Pid
+}
+
+func mustCall(t *testing.T, h *Helper, command string) string {
+	t.Helper()
+	result, err := h.Call(command)
+	if err != nil {
+		t.Fatalf("Call(%q) 返回错误: %v", command, err)
+	}
+	return result
+}
+
+func TestHelperRepliesMatchRequests(t *testing.T) {
+	h := newFakeHelper(t)
+	steps := []struct{ command, want string }{
+		{"PING", "PONG"},
+		{"GET_VOLUME", "50"},
+		{"SET_VOLUME 30", ""},
+		{"GET_VOLUME", "30"},
+		{"GET_MUTE", "Off"},
+		{"MUTE", ""},
+		{"GET_MUTE", "On"},
+	}
+	for _, s := range steps {
+		if got := mustCall(t, h, s.command); got != s.want {
+			t.Errorf("Call(%q) = %q, 期望 %q", s.command, got, s.want)
+		}
+	}
+	if _, err := h.Call("SET_VOLUME 200"); err == nil {
+		t.Error("辅助进程回复 ERR 时 Call 应返回错误")
+	}
+	if _, err := h.Call("GET_VOLUME\nMUTE"); err == nil {
+		t.Error("含换行的命令应被拒绝")
+	}
+	if got := mustCall(t, h, "GET_MUTE"); got != "On" {
+		t.Errorf("被拒绝的命令不应送达辅助进程，GET_MUTE = %q", got)
+	}
+}
+
+func TestHelperConcurrentCalls(t *testing.T) {
+	h := newFakeHelper(t)
+	mustCall(t, h, "SET_VOLUME 42")
+	var wg sync.WaitGroup
+	errs := make(chan error, 40)
+	for i := 0; i < 20; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			if got, err := h.Call("GET_VOLUME"); err != nil || got != "42" {
+				errs <- fmt.Errorf("GET_VOLUME = %q, %v", got, err)
+			}
+		}()
+		go func() {
+			defer wg.Done()
+			if got, err := h.Call("PING"); err != nil || got != "PONG" {
+				errs <- fmt.Errorf("PING = %q, %v", got, err)
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
+
+func TestHelperRestartsAfterCrash(t *testing.T) {
+	h := newFakeHelper(t)
+	mustCall(t, h, "SET_VOLUME 10")
+	firstPid := helperPid(h)
+
+	if _, err := h.Call("CRASH"); err == nil {
+		t.Fatal("辅助进程崩溃时 Call 应返回错误")
+	}
+	if got := mustCall(t, h, "GET_VOLUME"); got != "50" {
+		t.Errorf("重启后的辅助进程应处于初始状态，GET_VOLUME = %q", got)
+	}
+	if pid := helperPid(h); pid == 0 || pid == firstPid {
+		t.Errorf("崩溃后应启动新进程，PID %d -> %d", firstPid, pid)
+	}
+}
+
+func TestHelperRestartsAfterExternalExit(t *testing.T) {
+	h := newFakeHelper(t)
+	mustCall(t, h, "PING")
+	h.mu.Lock()
+	h.cmd.Process.Kill()
+	exited := h.exited
+	h.mu.Unlock()
+	<-exited
+
+	if got := mustCall(t, h, "PING"); got != "PONG" {
+		t.Errorf("进程被结束后 PING = %q", got)
+	}
+}
+
+func TestHelperTimeout(t *testing.T) {
+	h := newFakeHelper(t)
+	h.SetTimeout(100 * time.Millisecond)
+	mustCall(t, h, "SET_VOLUME 20")
+
+	start := time.Now()
+	if _, err := h.Call("HANG"); !errors.Is(err, ErrHelperTimeout) {
+		t.Fatalf("无应答时应返回 ErrHelperTimeout，实际: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("超时返回过慢: %s", elapsed)
+	}
+	if pid := helperPid(h); pid != 0 {
+		t.Error("超时后应结束无响应的辅助进程")
+	}
+	if got := mustCall(t, h, "GET_VOLUME"); got != "50" {
+		t.Errorf("超时后应重启辅助进程，GET_VOLUME = %q", got)
+	}
+}
+
+// TestHelperLateReplyNotMismatched 超时命令的迟到应答不能被当作下一条命令的应答。
+func TestHelperLateReplyNotMismatched(t *testing.T) {
+	h := newFakeHelper(t)
+	h.SetTimeout(100 * time.Millisecond)
+	if _, err := h.Call("SLOW 300"); !errors.Is(err, ErrHelperTimeout) {
+		t.Fatalf("慢应答应超时，实际: %v", err)
+	}
+	h.SetTimeout(defaultHelperTimeout)
+	time.Sleep(400 * time.Millisecond)
+	if got := mustCall(t, h, "PING"); got != "PONG" {
+		t.Errorf("PING = %q, 期望 PONG（不应收到迟到的 SLOW 应答）", got)
+	}
+}
+
+func TestHelperStartFailure(t *testing.T) {
+	h := NewHelper(filepath.Join(t.TempDir(), "missing-helper"))
+	if _, err := h.Call("PING"); err == nil {
+		t.Error("辅助进程不存在时 Call 应返回错误")
+	}
+}
+
+func TestParseHelperReply(t *testing.T) {
+	tests := []struct {
+		line    string
+		want    string
+		wantErr bool
+	}{
+		{line: "OK", want: ""},
+		{line: "OK 42", want: "42"},
+		{line: "OK   On  \r", want: "On"},
+		{line: "ERR", wantErr: true},
+		{line: "ERR 未知命令", wantErr: true},
+		{line: "OKAY", wantErr: true},
+		{line: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parseHelperReply(tt.line)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("parseHelperReply(%q) = %q, %v", tt.line, got, err)
+		}
+	}
+}
@@ -0,0 +1,11 @@
+package ahk
+
+import (
+	"os/exec"
+	"syscall"
+)
+
+// hideWindow 隐藏辅助进程的控制台窗口。
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
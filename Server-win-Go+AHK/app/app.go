@@ -0,0 +1,125 @@
+// Package app 负责组装并启动 Bealink 的核心服务（日志 Hub、HTTP、mDNS、Bark 通知、电源事件监听），
+// 与系统托盘无关，托盘模式和无托盘 (serve) 模式共用同一套启动流程。
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net"
+	"path/filepath"
+	"time"
+
+	"bealinkserver/bark"
+	"bealinkserver/logging"
+	"bealinkserver/platform"
+	"bealinkserver/server"
+	"bealinkserver/tlscert"
+)
+
+// App 表示一次运行中的核心服务。
+type App struct {
+	backend *platform.Backend
+	server  *server.Server
+
+	addr                string
+	scheme              string // "http" 或仅提供 HTTPS 时的 "https"
+	usedAlternativePort bool
+}
+
+// New 创建使用指定平台后端的 App。调用前应已完成日志和 Bark 配置的初始化。
+func New(backend *platform.Backend) *App {
+	return &App{backend: backend}
+}
+
+// Addr 返回 HTTP 服务实际监听的地址，Start 成功后有效。
+func (a *App) Addr() string { return a.addr }
+
+// LocalURL 返回本机访问 path 的完整地址（例如托盘打开设置页面），Start 成功后有效。
+func (a *App) LocalURL(path string) string {
+	host, port, err := net.SplitHostPort(a.addr)
+	if err != nil {
+		port = a.addr
+	}
+	// 监听所有网卡或环回地址时使用 localhost，只监听特定地址时直接使用该地址
+	if ip := net.ParseIP(host); ip == nil || ip.IsUnspecified() || ip.IsLoopback() {
+		host = "localhost"
+	}
+	return fmt.Sprintf("%s://%s%s", a.scheme, net.JoinHostPort(host, port), path)
+}
+
+// UsedAlternativePort 返回是否使用了备用端口。
+func (a *App) UsedAlternativePort() bool { return a.usedAlternativePort }
+
+// Start 启动全部核心服务后立即返回，ctx 取消时各服务自行停止。
+func (a *App) Start(ctx context.Context) error {
+	hub := logging.GetHub()
+	go hub.Run(ctx)
+
+	tlsOpts, err := loadTLSOptions()
+	if err != nil {
+		return err
+	}
+	cfg := bark.GetConfig()
+	listen := server.ListenOptions{Addresses: cfg.ListenAddresses, Ports: cfg.ListenPorts}
+	a.server = server.New(a.backend, hub)
+	addr, usedAlternativePort, err := a.server.Start(ctx, listen, tlsOpts)
+	if err != nil {
+		return err
+	}
+	a.addr, a.usedAlternativePort = addr, usedAlternativePort
+	a.scheme = "http"
+	if tlsOpts != nil && tlsOpts.DisableHTTP {
+		a.scheme = "https"
+	}
+	if usedAlternativePort {
+		log.Printf("服务已在备用地址 %s 上启动。", addr)
+	}
+
+	go func() {
+		select {
+		case <-time.After(2 * time.Second):
+			bark.NotifyEvent("system_ready")
+		case <-ctx.Done():
+		}
+	}()
+
+	startPowerEventListener(ctx)
+	return nil
+}
+
+// loadTLSOptions 按配置加载 HTTPS 证书（首次运行时生成），未启用 HTTPS 时返回 nil。
+// 证书加载失败时：若仍保留 HTTP 则只提供 HTTP，否则返回错误。
+func loadTLSOptions() (*server.TLSOptions, error) {
+	cfg := bark.GetConfig()
+	if !cfg.TLSEnabled {
+		if !cfg.HTTPEnabled {
+			log.Println("警告: HTTP 和 HTTPS 均已关闭，仍将启动 HTTP 服务。")
+		}
+		return nil, nil
+	}
+	cert, fingerprint, err := tlscert.LoadOrCreate(filepath.Dir(bark.GetConfigFilePath()))
+	if err != nil {
+		if !cfg.HTTPEnabled {
+			return nil, fmt.Errorf("加载 TLS 证书失败: %w", err)
+		}
+		log.Printf("警告: 加载 TLS 证书失败，仅提供 HTTP 服务: %v", err)
+		return nil, nil
+	}
+	return &server.TLSOptions{
+		Port:        cfg.TLSPort,
+		Certificate: cert,
+		Fingerprint: fingerprint,
+		DisableHTTP: !cfg.HTTPEnabled,
+	}, nil
+}
+
+// Run 启动核心服务并阻塞，直到 ctx 取消且 HTTP 服务完成关闭。
+func (a *App) Run(ctx context.Context) error {
+	if err := a.Start(ctx); err != nil {
+		return err
+	}
+	<-ctx.Done()
+	<-a.server.Done()
+	return nil
+}
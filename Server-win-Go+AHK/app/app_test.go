@@ -0,0 +1,94 @@
+package app
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+)
+
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bealink-app-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("APPDATA", dir)
+	os.Setenv("HOME", dir)
+	bark.InitConfig()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// freePort 返回本机环回地址上当前空闲的端口。
+func freePort(t *testing.T) string {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	return port
+}
+
+// TestRunStopsOnCancel 模拟 serve / --no-tray 模式：Run 启动服务后阻塞，ctx 取消后关闭 HTTP 服务并返回。
+func TestRunStopsOnCancel(t *testing.T) {
+	port := freePort(t)
+	if err := bark.UpdateConfig(func(c *bark.BarkConfig) {
+		c.ListenAddresses = []string{"127.0.0.1"}
+		c.ListenPorts = []string{":" + port}
+		c.TLSEnabled = false
+		c.HTTPEnabled = true
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	done := make(chan error, 1)
+	go func() { done <- New(platform.NewSimulator().Backend()).Run(ctx) }()
+
+	url := "http://127.0.0.1:" + port + "/api/v1/ping"
+	client := &http.Client{Timeout: time.Second}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		resp, err := client.Get(url)
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				t.Fatalf("GET /api/v1/ping 返回 %d, 期望 200", resp.StatusCode)
+			}
+			break
+		}
+		select {
+		case err := <-done:
+			t.Fatalf("Run 提前返回: %v", err)
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("服务未在 5 秒内启动: %v", err)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Errorf("ctx 取消后 Run 应返回 nil，实际: %v", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatal("ctx 取消后 Run 未在 10 秒内返回")
+	}
+	if resp, err := client.Get(url); err == nil {
+		resp.Body.Close()
+		t.Error("Run 返回后 HTTP 服务仍在响应")
+	}
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package app
+
+import (
+	"context"
+	"log"
+)
+
+// startPowerEventListener 非 Windows 系统暂不监听睡眠唤醒事件。
+func startPowerEventListener(ctx context.Context) {
+	log.Println("信息: 非 Windows 系统，不启动电源事件监听。")
+}
@@ -0,0 +1,225 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"syscall"
+	"unsafe"
+
+	"bealinkserver/bark"
+	"bealinkserver/winapi"
+)
+
+const (
+	WM_POWERBROADCAST      = 0x0218
+	PBT_APMRESUMEAUTOMATIC = 0x0012
+	PBT_APMRESUMESUSPEND   = 0x0007
+	PBT_APMRESUMECRITICAL  = 0x0006
+	PBT_POWERSETTINGCHANGE = 0x8013
+	WM_DESTROY_VALUE       = 0x0002
+	WM_NULL                = 0x0000
+
+	DEVICE_NOTIFY_WINDOW_HANDLE = 0
+)
+
+// GUID_CONSOLE_DISPLAY_STATE 控制台显示器状态的电源设置通知，数据为 0 (关闭)、1 (开启) 或 2 (变暗)。
+// 注册后系统会立即发送一次当前状态。
+var guidConsoleDisplayState = syscall.GUID{Data1: 0x6fe69556, Data2: 0x704a, Data3: 0x47a0, Data4: [8]byte{0x8f, 0x24, 0xc2, 0x8d, 0x93, 0x6f, 0xda, 0x47}}
+
+// POWERBROADCAST_SETTING PBT_POWERSETTINGCHANGE 消息 lParam 指向的结构。
+type POWERBROADCAST_SETTING struct {
+	PowerSetting syscall.GUID
+	DataLength   uint32
+	Data         [1]byte
+}
+
+var powerEventHWND syscall.Handle
+
+var (
+	user32DLL            = syscall.NewLazyDLL("user32.dll")
+	kernel32DLL          = syscall.NewLazyDLL("kernel32.dll")
+	procGetModuleHandleW = kernel32DLL.NewProc("GetModuleHandleW")
+	procRegisterClassExW = user32DLL.NewProc("RegisterClassExW")
+	procCreateWindowExW  = user32DLL.NewProc("CreateWindowExW")
+	procDefWindowProcW   = user32DLL.NewProc("DefWindowProcW")
+	procGetMessageW      = user32DLL.NewProc("GetMessageW")
+	procTranslateMessage = user32DLL.NewProc("TranslateMessage")
+	procDispatchMessageW = user32DLL.NewProc("DispatchMessageW")
+	procDestroyWindow    = user32DLL.NewProc("DestroyWindow")
+	procUnregisterClassW = user32DLL.NewProc("UnregisterClassW")
+	procPostMessageW     = user32DLL.NewProc("PostMessageW")
+
+	procRegisterPowerSettingNotification   = user32DLL.NewProc("RegisterPowerSettingNotification")
+	procUnregisterPowerSettingNotification = user32DLL.NewProc("UnregisterPowerSettingNotification")
+)
+
+func PostMessage(hwnd syscall.Handle, msg uint32, wParam, lParam uintptr) error {
+	r1, _, e1 := procPostMessageW.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+	if r1 == 0 {
+		if e1 != nil {
+			return e1
+		}
+		return fmt.Errorf("PostMessageW 调用失败但无明确错误")
+	}
+	return nil
+}
+
+const powerEventWindowClassName = "BealinkPowerEventWnd"
+
+type WNDCLASSEX struct {
+	CbSize        uint32
+	Style         uint32
+	LpfnWndProc   uintptr
+	ClsExtra      int32
+	WndExtra      int32
+	HInstance     syscall.Handle
+	HIcon         syscall.Handle
+	HCursor       syscall.Handle
+	HbrBackground syscall.Handle
+	LpszMenuName  *uint16
+	LpszClassName *uint16
+	HIconSm       syscall.Handle
+}
+type MSG struct {
+	Hwnd    syscall.Handle
+	Message uint32
+	WParam  uintptr
+	LParam  uintptr
+	Time    uint32
+	Pt      struct{ X, Y int32 }
+}
+
+func powerEventWindowProc(hwnd syscall.Handle, msg uint32, wParam uintptr, lParam uintptr) uintptr {
+	switch msg {
+	case WM_POWERBROADCAST:
+		if wParam == PBT_POWERSETTINGCHANGE {
+			// lParam 是系统传入的指针，经 *unsafe.Pointer 转换以免被 vet 误报
+			handlePowerSettingChange((*POWERBROADCAST_SETTING)(*(*unsafe.Pointer)(unsafe.Pointer(&lParam))))
+			return 1
+		}
+		if wParam == PBT_APMRESUMEAUTOMATIC || wParam == PBT_APMRESUMESUSPEND || wParam == PBT_APMRESUMECRITICAL {
+			log.Println("检测到系统从睡眠/休眠状态唤醒。")
+			go bark.NotifyEvent("system_ready") // <--- 统一事件名
+		}
+		return 0
+	case WM_DESTROY_VALUE:
+		log.Println("电源事件监听窗口已销毁 (WM_DESTROY)。")
+		return 0
+	}
+	ret, _, _ := procDefWindowProcW.Call(uintptr(hwnd), uintptr(msg), wParam, lParam)
+	return ret
+}
+
+// handlePowerSettingChange 处理电源设置变化通知，目前只关心显示器状态。变暗仍视为开启。
+func handlePowerSettingChange(setting *POWERBROADCAST_SETTING) {
+	if setting == nil || setting.PowerSetting != guidConsoleDisplayState || setting.DataLength < 1 {
+		return
+	}
+	winapi.UpdateMonitorState(setting.Data[0] == 0)
+}
+
+// registerDisplayStateNotification 为 hwnd 注册 GUID_CONSOLE_DISPLAY_STATE 通知，失败时显示器状态只能根据本程序的操作推测。
+func registerDisplayStateNotification(hwnd syscall.Handle) uintptr {
+	handle, _, err := procRegisterPowerSettingNotification.Call(uintptr(hwnd), uintptr(unsafe.Pointer(&guidConsoleDisplayState)), DEVICE_NOTIFY_WINDOW_HANDLE)
+	if handle == 0 {
+		log.Printf("警告: 注册显示器状态通知失败: %v。显示器状态将无法感知系统自行开关屏幕。", err)
+		return 0
+	}
+	log.Println("已注册显示器状态通知 (GUID_CONSOLE_DISPLAY_STATE)。")
+	return handle
+}
+
+func createPowerEventWindow(hInstance syscall.Handle) (syscall.Handle, error) {
+	classNamePtr, _ := syscall.UTF16PtrFromString(powerEventWindowClassName)
+	windowTitlePtr, _ := syscall.UTF16PtrFromString("Bealink Power Listener")
+	wc := WNDCLASSEX{CbSize: uint32(unsafe.Sizeof(WNDCLASSEX{})), LpfnWndProc: syscall.NewCallback(powerEventWindowProc), HInstance: hInstance, LpszClassName: classNamePtr}
+	atom, _, errReg := procRegisterClassExW.Call(uintptr(unsafe.Pointer(&wc)))
+	if atom == 0 && (errReg == nil || errReg.(syscall.Errno) != 1410) {
+		return 0, fmt.Errorf("注册电源事件窗口类失败: %v (atom: %d)", errReg, atom)
+	}
+	if atom == 0 && errReg != nil && errReg.(syscall.Errno) == 1410 {
+		log.Println("信息: 电源事件窗口类已注册。")
+	}
+	hwnd, _, errCreate := procCreateWindowExW.Call(0, uintptr(unsafe.Pointer(classNamePtr)), uintptr(unsafe.Pointer(windowTitlePtr)), 0, 0, 0, 0, 0, 0, 0, uintptr(hInstance), 0)
+	if hwnd == 0 {
+		return 0, fmt.Errorf("创建电源事件窗口失败: %v", errCreate)
+	}
+	log.Printf("电源事件监听窗口创建成功 (HWND: 0x%X)。", hwnd)
+	return syscall.Handle(hwnd), nil
+}
+
+func powerEventMessageLoop(ctx context.Context, hInstance syscall.Handle) { /* ... (代码同前，确保 PostMessage 使用 WM_NULL) ... */
+	log.Println("启动电源事件消息循环...")
+	var errLoop error
+	powerEventHWND, errLoop = createPowerEventWindow(hInstance)
+	if errLoop != nil {
+		log.Printf("!!! 致命错误: 无法创建电源事件监听窗口: %v。", errLoop)
+		return
+	}
+	displayNotify := registerDisplayStateNotification(powerEventHWND)
+	defer func() {
+		log.Println("开始清理电源事件消息循环资源...")
+		if displayNotify != 0 {
+			procUnregisterPowerSettingNotification.Call(displayNotify)
+		}
+		if powerEventHWND != 0 {
+			log.Printf("正在销毁电源事件窗口 (HWND: 0x%X)...", powerEventHWND)
+			if ret, _, destroyErr := procDestroyWindow.Call(uintptr(powerEventHWND)); ret == 0 {
+				log.Printf("错误: 销毁电源事件窗口失败: %v", destroyErr)
+			} else {
+				log.Println("电源事件窗口已成功请求销毁。")
+			}
+			powerEventHWND = 0
+		}
+		classNamePtr, _ := syscall.UTF16PtrFromString(powerEventWindowClassName)
+		if retUnregister, _, unregisterErr := procUnregisterClassW.Call(uintptr(unsafe.Pointer(classNamePtr)), uintptr(hInstance)); retUnregister == 0 {
+			log.Printf("警告: 注销电源事件窗口类失败: %v", unregisterErr)
+		} else {
+			log.Println("电源事件窗口类已注销。")
+		}
+		log.Println("电源事件消息循环资源清理完毕。")
+	}()
+	var msg MSG
+	running := true
+	for running {
+		select {
+		case <-ctx.Done():
+			log.Println("收到上下文取消信号，停止电源事件消息循环...")
+			if powerEventHWND != 0 {
+				PostMessage(powerEventHWND, WM_NULL, 0, 0)
+			} // 使用我们定义的 WM_NULL
+			running = false
+		default:
+			ret, _, getMsgErr := procGetMessageW.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0)
+			if !running {
+				log.Println("GetMessageW 返回，但循环已被指示停止。")
+				break
+			}
+			if int32(ret) == 0 {
+				log.Println("GetMessageW 返回 0，电源事件消息循环将退出。")
+				running = false
+				break
+			}
+			if int32(ret) == -1 {
+				log.Printf("错误: GetMessageW 返回 -1: %v", getMsgErr)
+				running = false
+				break
+			}
+			procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+			procDispatchMessageW.Call(uintptr(unsafe.Pointer(&msg)))
+		}
+	}
+	log.Println("电源事件消息循环已结束。")
+}
+
+// startPowerEventListener 创建隐藏窗口监听 WM_POWERBROADCAST，系统从睡眠唤醒时发送 system_ready 通知，
+// 并跟踪显示器的真实开关状态。
+func startPowerEventListener(ctx context.Context) {
+	hInst, _, errHInst := procGetModuleHandleW.Call(0)
+	if hInst == 0 || (errHInst != nil && errHInst.(syscall.Errno) != 0) {
+		log.Printf("警告: GetModuleHandleW(nil) 失败 (err: %v, handle: %v)。睡眠唤醒通知将不可用。", errHInst, hInst)
+		return
+	}
+	go powerEventMessageLoop(ctx, syscall.Handle(hInst))
+}
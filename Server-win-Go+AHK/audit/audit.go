@@ -0,0 +1,194 @@
+// Package audit 记录远程操作的审计日志。
+//
+// 审计日志与调试日志（logging.RingBuffer）分开保存：每条记录以一行 JSON 追加写入文件，
+// 重启后不会丢失，也不会被修改，可按条件查询并导出为 CSV / JSONL。
+package audit
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// FileName 审计日志文件名，与配置文件放在同一目录。
+const FileName = "bealink_audit.jsonl"
+
+// Entry 一条审计记录。
+type Entry struct {
+	Time       time.Time         `json:"time"`
+	IP         string            `json:"ip"`
+	Client     string            `json:"client"`             // 令牌或配对设备名称，未认证时为空
+	TokenID    string            `json:"token_id,omitempty"` // 使用令牌访问时的令牌 ID
+	UserAgent  string            `json:"user_agent,omitempty"`
+	Method     string            `json:"method"`
+	Action     string            `json:"action"` // 请求路径
+	Params     map[string]string `json:"params,omitempty"`
+	Status     int               `json:"status"`
+	Result     string            `json:"result,omitempty"`
+	DurationMs int64             `json:"duration_ms"`
+}
+
+// OK 判断操作是否成功（2xx 状态码）。
+func (e Entry) OK() bool {
+	return e.Status >= 200 && e.Status < 300
+}
+
+// Filter 查询条件，零值字段不参与过滤。
+type Filter struct {
+	Since  time.Time
+	Until  time.Time
+	IP     string
+	Client string // 不区分大小写的子串匹配
+	Action string // 请求路径子串匹配
+	Result string // "ok" 只返回成功的记录，"error" 只返回失败的记录
+	Limit  int    // 最多返回的条数，0 表示不限制
+}
+
+// Match 判断 e 是否满足过滤条件（不考虑 Limit）。
+func (f Filter) Match(e Entry) bool {
+	switch {
+	case !f.Since.IsZero() && e.Time.Before(f.Since):
+		return false
+	case !f.Until.IsZero() && !e.Time.Before(f.Until):
+		return false
+	case f.IP != "" && e.IP != f.IP:
+		return false
+	case f.Client != "" && !strings.Contains(strings.ToLower(e.Client), strings.ToLower(f.Client)):
+		return false
+	case f.Action != "" && !strings.Contains(e.Action, f.Action):
+		return false
+	case f.Result == "ok" && !e.OK(), f.Result == "error" && e.OK():
+		return false
+	}
+	return true
+}
+
+// Log 追加写入的审计日志文件。
+type Log struct {
+	mu   sync.Mutex
+	path string
+}
+
+// New 返回写入 path 的审计日志，文件在首次写入时创建。
+func New(path string) *Log {
+	return &Log{path: path}
+}
+
+// Path 返回审计日志文件路径。
+func (l *Log) Path() string { return l.path }
+
+// Append 追加一条记录。
+func (l *Log) Append(e Entry) error {
+	line, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(l.path), 0750); err != nil {
+		return fmt.Errorf("创建审计日志目录失败: %w", err)
+	}
+	f, err := os.OpenFile(l.path, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0640)
+	if err != nil {
+		return fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("写入审计日志失败: %w", err)
+	}
+	return nil
+}
+
+// Query 返回满足 f 的记录，按时间从新到旧排列。无法解析的行会被跳过。
+func (l *Log) Query(f Filter) ([]Entry, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return []Entry{}, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("打开审计日志失败: %w", err)
+	}
+	defer file.Close()
+
+	entries := []Entry{}
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		var e Entry
+		if json.Unmarshal(scanner.Bytes(), &e) != nil || !f.Match(e) {
+			continue
+		}
+		entries = append(entries, e)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取审计日志失败: %w", err)
+	}
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].Time.After(entries[j].Time) })
+	if f.Limit > 0 && len(entries) > f.Limit {
+		entries = entries[:f.Limit]
+	}
+	return entries, nil
+}
+
+// csvHeader CSV 导出的表头，与 WriteCSV 中的列顺序一致。
+var csvHeader = []string{"time", "ip", "client", "token_id", "user_agent", "method", "action", "params", "status", "result", "duration_ms"}
+
+// WriteCSV 将 entries 以 CSV 格式写入 w，params 列为 key=value 以空格分隔。
+// 可能以公式字符开头的文本列会加上单引号前缀，见 csvCell。
+func WriteCSV(w io.Writer, entries []Entry) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(csvHeader); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		keys := make([]string, 0, len(e.Params))
+		for k := range e.Params {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		params := make([]string, len(keys))
+		for i, k := range keys {
+			params[i] = k + "=" + e.Params[k]
+		}
+		record := []string{
+			e.Time.Format(time.RFC3339), csvCell(e.IP), csvCell(e.Client), csvCell(e.TokenID), csvCell(e.UserAgent), e.Method, csvCell(e.Action),
+			csvCell(strings.Join(params, " ")), strconv.Itoa(e.Status), csvCell(e.Result), strconv.FormatInt(e.DurationMs, 10),
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell 在以 = + - @ 或制表符、回车开头的值前加单引号，避免来自请求的内容（User-Agent、参数等）
+// 在 Excel 中被当作公式执行。
+func csvCell(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
+
+// WriteJSONL 将 entries 每条一行 JSON 写入 w。
+func WriteJSONL(w io.Writer, entries []Entry) error {
+	enc := json.NewEncoder(w)
+	for _, e := range entries {
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"encoding/csv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteCSVEscapesFormulas(t *testing.T) {
+	entries := []Entry{{
+		Time:      time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		IP:        "192.168.1.50",
+		Client:    "@SUM(A1)",
+		UserAgent: "=HYPERLINK(\"http://evil\")",
+		Method:    "POST",
+		Action:    "/clip",
+		Params:    map[string]string{"b": "2", "a": "1"},
+		Status:    400,
+		Result:    "-1+1",
+	}, {
+		Time:   time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		IP:     "127.0.0.1",
+		Client: "+cmd",
+		Method: "GET",
+		Action: "/getclip",
+		Status: 200,
+		Result: "[3 字节]",
+	}}
+	var sb strings.Builder
+	if err := WriteCSV(&sb, entries); err != nil {
+		t.Fatal(err)
+	}
+	records, err := csv.NewReader(strings.NewReader(sb.String())).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 3 || strings.Join(records[0], ",") != strings.Join(csvHeader, ",") {
+		t.Fatalf("CSV = %q", records)
+	}
+	col := func(row int, name string) string {
+		for i, h := range csvHeader {
+			if h == name {
+				return records[row][i]
+			}
+		}
+		t.Fatalf("没有 %s 列", name)
+		return ""
+	}
+	tests := []struct {
+		row        int
+		name, want string
+	}{
+		{1, "client", "'@SUM(A1)"},
+		{1, "user_agent", "'=HYPERLINK(\"http://evil\")"},
+		{1, "result", "'-1+1"},
+		{1, "params", "a=1 b=2"},
+		{1, "ip", "192.168.1.50"},
+		{1, "status", "400"},
+		{2, "client", "'+cmd"},
+		{2, "result", "[3 字节]"},
+		{2, "time", "2026-01-02T03:04:05Z"},
+	}
+	for _, tt := range tests {
+		if got := col(tt.row, tt.name); got != tt.want {
+			t.Errorf("第 %d 行 %s = %q, 期望 %q", tt.row, tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,365 @@
+// Package auth 实现 API 访问令牌：令牌保存在配置文件中（仅保存哈希），每个令牌带有一组权限范围。
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// 权限范围。每条路由声明访问所需的范围，令牌必须包含该范围。
+const (
+	ScopePower          = "power"
+	ScopeClipboardRead  = "clipboard:read"
+	ScopeClipboardWrite = "clipboard:write"
+	ScopeInput          = "input"
+	ScopeMedia          = "media"
+	ScopeAdminSettings  = "admin:settings"
+)
+
+// ScopeInfo 权限范围及其说明，供设置页面展示。
+type ScopeInfo struct {
+	Scope       string `json:"scope"`
+	Description string `json:"description"`
+}
+
+// Scopes 所有可分配的权限范围。
+var Scopes = []ScopeInfo{
+	{ScopePower, "电源与显示器（睡眠、关机、倒计时、显示器开关）"},
+	{ScopeClipboardRead, "读取剪贴板"},
+	{ScopeClipboardWrite, "写入剪贴板文本和图片"},
+	{ScopeInput, "向当前窗口输入文本、模拟粘贴"},
+	{ScopeMedia, "音量与媒体控制"},
+	{ScopeAdminSettings, "管理设置、令牌和调试日志"},
+}
+
+// tokenPrefix 令牌明文的前缀，便于识别。
+const tokenPrefix = "bl_"
+
+// lastUsedSaveInterval 令牌最近使用时间写回配置文件的最小间隔，避免每个请求都写盘。
+const lastUsedSaveInterval = 5 * time.Minute
+
+var (
+	// ErrUnauthorized 请求未携带令牌或令牌无效。
+	ErrUnauthorized = errors.New("未提供有效的访问令牌")
+	// ErrForbidden 令牌有效但缺少所需的权限范围。
+	ErrForbidden = errors.New("访问令牌缺少所需的权限")
+	// ErrTokenNotFound 要操作的令牌不存在。
+	ErrTokenNotFound = errors.New("令牌不存在")
+	// ErrInvalidToken 创建令牌时名称或权限范围无效。
+	ErrInvalidToken = errors.New("令牌参数无效")
+)
+
+// TokenInfo 令牌的公开信息（不含哈希）。
+type TokenInfo struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Scopes     []string  `json:"scopes"`
+	PairedFrom string    `json:"paired_from,omitempty"` // 非空表示通过配对创建，值为配对设备的 IP
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	Guest      bool      `json:"guest,omitempty"` // 访客令牌，见 CreateGuestToken
+	ExpiresAt  time.Time `json:"expires_at,omitzero"`
+	MaxUses    int       `json:"max_uses,omitempty"`
+	Uses       int       `json:"uses,omitempty"`
+}
+
+func tokenInfo(t bark.APIToken) TokenInfo {
+	return TokenInfo{ID: t.ID, Name: t.Name, Scopes: t.Scopes, PairedFrom: t.PairedFrom, CreatedAt: t.CreatedAt, LastUsedAt: t.LastUsedAt,
+		Guest: t.Guest, ExpiresAt: t.ExpiresAt, MaxUses: t.MaxUses, Uses: t.Uses}
+}
+
+// Identity 通过认证的请求方。Local 表示来自本机的请求，TokenID 为空时未使用令牌，Guest 表示使用访客令牌。
+type Identity struct {
+	TokenID string
+	Name    string
+	Scopes  []string
+	Local   bool
+	Guest   bool
+}
+
+// Has 判断请求方是否拥有 scope 权限。
+func (id *Identity) Has(scope string) bool {
+	for _, s := range id.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+type contextKey struct{}
+
+// WithIdentity 返回携带请求方信息的 context。
+func WithIdentity(ctx context.Context, id *Identity) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext 取出 WithIdentity 保存的请求方，没有时返回 nil。
+func FromContext(ctx context.Context) *Identity {
+	id, _ := ctx.Value(contextKey{}).(*Identity)
+	return id
+}
+
+// ValidScope 判断 scope 是否为已知的权限范围。
+func ValidScope(scope string) bool {
+	for _, s := range Scopes {
+		if s.Scope == scope {
+			return true
+		}
+	}
+	return false
+}
+
+func allScopes() []string {
+	scopes := make([]string, len(Scopes))
+	for i, s := range Scopes {
+		scopes[i] = s.Scope
+	}
+	return scopes
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomBytes(n int) ([]byte, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return nil, fmt.Errorf("生成随机数失败: %w", err)
+	}
+	return b, nil
+}
+
+// CreateToken 创建一个令牌并保存到配置。返回的明文令牌只在此时可见，配置中只保存其哈希。
+func CreateToken(name string, scopes []string) (string, TokenInfo, error) {
+	name, err := validateToken(name, scopes)
+	if err != nil {
+		return "", TokenInfo{}, err
+	}
+	return createToken(bark.APIToken{Name: name, Scopes: scopes})
+}
+
+// validateToken 校验令牌名称和权限范围，返回去除空白的名称。
+func validateToken(name string, scopes []string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: 名称不能为空", ErrInvalidToken)
+	}
+	if len(scopes) == 0 {
+		return "", fmt.Errorf("%w: 至少需要一个权限范围", ErrInvalidToken)
+	}
+	for _, s := range scopes {
+		if !ValidScope(s) {
+			return "", fmt.Errorf("%w: 未知的权限范围 %s", ErrInvalidToken, s)
+		}
+	}
+	return name, nil
+}
+
+// createToken 为 token 生成 ID 和明文并保存，token 中已填写名称、权限范围以及配对来源或访客限制。
+func createToken(token bark.APIToken) (string, TokenInfo, error) {
+	secret, err := randomBytes(32)
+	if err != nil {
+		return "", TokenInfo{}, err
+	}
+	idBytes, err := randomBytes(6)
+	if err != nil {
+		return "", TokenInfo{}, err
+	}
+	plain := tokenPrefix + base64.RawURLEncoding.EncodeToString(secret)
+	token.ID = hex.EncodeToString(idBytes)
+	token.Hash = hashToken(plain)
+	token.Scopes = append([]string(nil), token.Scopes...)
+	token.CreatedAt = time.Now()
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+		cfg.APITokens = append(cfg.APITokens, token)
+	}); err != nil {
+		return "", TokenInfo{}, fmt.Errorf("保存令牌失败: %w", err)
+	}
+	log.Printf("[Auth] 已创建令牌 %q (%s)，权限: %s", token.Name, token.ID, strings.Join(token.Scopes, ", "))
+	return plain, tokenInfo(token), nil
+}
+
+// ListTokens 返回所有令牌的公开信息，已到期的访客令牌先被吊销。
+func ListTokens() []TokenInfo {
+	PruneExpiredGuests()
+	tokens := bark.GetConfig().APITokens
+	list := make([]TokenInfo, 0, len(tokens))
+	for _, t := range tokens {
+		list = append(list, tokenInfo(t))
+	}
+	return list
+}
+
+// RevokeToken 删除 ID 为 id 的令牌，之后使用该令牌的请求都会被拒绝。
+func RevokeToken(id string) error {
+	var found *bark.APIToken
+	err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+		for i, t := range cfg.APITokens {
+			if t.ID == id {
+				found = &t
+				cfg.APITokens = append(cfg.APITokens[:i:i], cfg.APITokens[i+1:]...)
+				return
+			}
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+	if found == nil {
+		return ErrTokenNotFound
+	}
+	lastUsedMu.Lock()
+	delete(lastUsedSaved, id)
+	lastUsedMu.Unlock()
+	log.Printf("[Auth] 已吊销令牌 %q (%s)", found.Name, found.ID)
+	return nil
+}
+
+// tokenFromRequest 从 Authorization: Bearer 头或 access_token 查询参数中取出令牌。
+// 查询参数用于无法设置请求头的场景（WebSocket、页面跳转）。
+func tokenFromRequest(r *http.Request) string {
+	if h := r.Header.Get("Authorization"); h != "" {
+		if scheme, token, ok := strings.Cut(h, " "); ok && strings.EqualFold(scheme, "Bearer") {
+			return strings.TrimSpace(token)
+		}
+	}
+	return r.URL.Query().Get("access_token")
+}
+
+// HasToken 判断请求是否携带了访问令牌（不校验令牌是否有效）。
+func HasToken(r *http.Request) bool {
+	return tokenFromRequest(r) != ""
+}
+
+// ClientIP 返回请求方的 IP（不含端口）。
+func ClientIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// isLoopback 判断请求是否来自本机。
+func isLoopback(r *http.Request) bool {
+	ip := net.ParseIP(ClientIP(r))
+	return ip != nil && ip.IsLoopback()
+}
+
+// Authenticate 识别请求方。优先使用请求携带的令牌；没有令牌时，
+// 本机请求或未启用认证 (require_auth=false) 时拥有全部权限，否则返回 ErrUnauthorized。
+func Authenticate(r *http.Request) (*Identity, error) {
+	return authenticate(r, bark.GetConfig().RequireAuth)
+}
+
+func authenticate(r *http.Request, requireAuth bool) (*Identity, error) {
+	if plain := tokenFromRequest(r); plain != "" {
+		id, err := lookupToken(plain)
+		if err != nil && !errors.Is(err, ErrGuestExpired) {
+			RecordFailure(ClientIP(r), "无效的访问令牌", hashToken(plain))
+		}
+		return id, err
+	}
+	if isLoopback(r) {
+		return &Identity{Name: "本机", Scopes: allScopes(), Local: true}, nil
+	}
+	if !requireAuth {
+		return &Identity{Name: "未启用认证", Scopes: allScopes()}, nil
+	}
+	return nil, ErrUnauthorized
+}
+
+// Authorize 识别请求方并检查其是否拥有 scope 权限；scope 为空表示无需权限。
+func Authorize(r *http.Request, scope string) (*Identity, error) {
+	return authorize(r, scope, bark.GetConfig().RequireAuth)
+}
+
+// AuthorizeLegacy 同 Authorize，用于旧接口：开启 legacy_allow_no_token 时，
+// 没有令牌的局域网请求也会被放行。携带的令牌仍然会被校验。
+func AuthorizeLegacy(r *http.Request, scope string) (*Identity, error) {
+	cfg := bark.GetConfig()
+	return authorize(r, scope, cfg.RequireAuth && !cfg.LegacyAllowNoToken)
+}
+
+func authorize(r *http.Request, scope string, requireAuth bool) (*Identity, error) {
+	id, err := authenticate(r, requireAuth)
+	if err != nil {
+		if scope == "" {
+			return nil, nil
+		}
+		return nil, err
+	}
+	if scope != "" && !id.Has(scope) {
+		return id, ErrForbidden
+	}
+	if id.Guest && scope != "" && r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if err := useGuestToken(id.TokenID); err != nil {
+			return nil, err
+		}
+	}
+	return id, nil
+}
+
+func lookupToken(plain string) (*Identity, error) {
+	hash := []byte(hashToken(plain))
+	for _, t := range bark.GetConfig().APITokens {
+		if subtle.ConstantTimeCompare(hash, []byte(t.Hash)) == 1 {
+			if t.Guest && guestExpired(t, time.Now()) {
+				go PruneExpiredGuests()
+				return nil, ErrGuestExpired
+			}
+			if t.Guest && t.LastUsedAt.IsZero() {
+				notifyGuestFirstUse(t)
+			}
+			touchToken(t.ID)
+			return &Identity{TokenID: t.ID, Name: t.Name, Scopes: t.Scopes, Guest: t.Guest}, nil
+		}
+	}
+	return nil, ErrUnauthorized
+}
+
+var (
+	lastUsedMu    sync.Mutex
+	lastUsedSaved = make(map[string]time.Time)
+)
+
+// touchToken 更新令牌的最近使用时间，每个令牌最多每 lastUsedSaveInterval 写一次配置文件。
+func touchToken(id string) {
+	now := time.Now()
+	lastUsedMu.Lock()
+	if now.Sub(lastUsedSaved[id]) < lastUsedSaveInterval {
+		lastUsedMu.Unlock()
+		return
+	}
+	lastUsedSaved[id] = now
+	lastUsedMu.Unlock()
+
+	go func() {
+		err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+			for i := range cfg.APITokens {
+				if cfg.APITokens[i].ID == id {
+					cfg.APITokens[i].LastUsedAt = now
+				}
+			}
+		})
+		if err != nil {
+			log.Printf("[Auth] 保存令牌使用时间失败: %v", err)
+		}
+	}()
+}
@@ -0,0 +1,135 @@
+package auth
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+
+	"bealinkserver/bark"
+)
+
+// tokenRequest 构造来自 remote 的请求，token 非空时以 Authorization: Bearer 携带。
+func tokenRequest(remote, token string) *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/volume", nil)
+	r.RemoteAddr = remote + ":50000"
+	if token != "" {
+		r.Header.Set("Authorization", "Bearer "+token)
+	}
+	return r
+}
+
+func TestCreateTokenValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		token  string
+		scopes []string
+	}{
+		{"空名称", "  ", []string{ScopePower}},
+		{"没有权限范围", "手机", nil},
+		{"未知权限范围", "手机", []string{"root"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, _, err := CreateToken(tt.token, tt.scopes); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("CreateToken(%q, %v) 应返回 ErrInvalidToken，实际: %v", tt.token, tt.scopes, err)
+			}
+		})
+	}
+}
+
+func TestTokenCreateListRevoke(t *testing.T) {
+	plain, info, err := CreateToken(" 手机 ", []string{ScopePower})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(plain, tokenPrefix) || info.Name != "手机" || !slices.Equal(info.Scopes, []string{ScopePower}) {
+		t.Errorf("CreateToken 返回 %q, %+v", plain, info)
+	}
+	for _, tok := range bark.GetConfig().APITokens {
+		if tok.ID == info.ID && (tok.Hash != hashToken(plain) || strings.Contains(tok.Hash, plain)) {
+			t.Error("配置中应只保存令牌的哈希")
+		}
+	}
+	if !slices.Contains(tokenIDs(), info.ID) {
+		t.Fatalf("新令牌 %s 未保存到配置", info.ID)
+	}
+	listed := false
+	for _, ti := range ListTokens() {
+		listed = listed || ti.ID == info.ID
+	}
+	if !listed {
+		t.Errorf("ListTokens 中没有新令牌 %s", info.ID)
+	}
+
+	id, err := Authorize(tokenRequest("192.0.2.10", plain), ScopePower)
+	if err != nil || id.TokenID != info.ID || id.Name != "手机" {
+		t.Fatalf("使用新令牌认证失败: %+v, %v", id, err)
+	}
+
+	if err := RevokeToken(info.ID); err != nil {
+		t.Fatal(err)
+	}
+	if slices.Contains(tokenIDs(), info.ID) {
+		t.Error("吊销后令牌仍在配置中")
+	}
+	if err := RevokeToken(info.ID); !errors.Is(err, ErrTokenNotFound) {
+		t.Errorf("再次吊销应返回 ErrTokenNotFound，实际: %v", err)
+	}
+	if _, err := Authorize(tokenRequest("192.0.2.10", plain), ScopePower); !errors.Is(err, ErrUnauthorized) {
+		t.Errorf("已吊销的令牌应返回 ErrUnauthorized，实际: %v", err)
+	}
+	Unblock("192.0.2.10")
+}
+
+func TestAuthorizeScopes(t *testing.T) {
+	power, _, err := CreateToken("电源", []string{ScopePower})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name        string
+		remote      string
+		token       string
+		scope       string
+		requireAuth bool
+		wantErr     error
+		wantID      bool
+	}{
+		{"本机无令牌", "127.0.0.1", "", ScopeAdminSettings, true, nil, true},
+		{"局域网无令牌", "192.0.2.20", "", ScopePower, true, ErrUnauthorized, false},
+		{"局域网无令牌且未启用认证", "192.0.2.20", "", ScopeAdminSettings, false, nil, true},
+		{"无需权限的接口", "192.0.2.20", "", "", true, nil, false},
+		{"令牌拥有权限", "192.0.2.20", power, ScopePower, true, nil, true},
+		{"令牌缺少权限", "192.0.2.20", power, ScopeClipboardRead, true, ErrForbidden, true},
+		{"本机令牌缺少权限", "127.0.0.1", power, ScopeClipboardRead, true, ErrForbidden, true},
+		{"无效令牌", "192.0.2.21", "bl_invalid", ScopePower, true, ErrUnauthorized, false},
+		{"未启用认证时无效令牌", "192.0.2.21", "bl_invalid", ScopePower, false, ErrUnauthorized, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			id, err := authorize(tokenRequest(tt.remote, tt.token), tt.scope, tt.requireAuth)
+			if !errors.Is(err, tt.wantErr) || (err == nil) != (tt.wantErr == nil) {
+				t.Errorf("authorize 返回错误 %v, 期望 %v", err, tt.wantErr)
+			}
+			if (id != nil) != tt.wantID {
+				t.Errorf("authorize 返回请求方 %+v, 期望非 nil = %v", id, tt.wantID)
+			}
+		})
+	}
+	Unblock("192.0.2.21")
+}
+
+func TestTokenFromQuery(t *testing.T) {
+	plain, _, err := CreateToken("网页", []string{ScopePower})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodGet, "/ws/display?access_token="+plain, nil)
+	r.RemoteAddr = "192.0.2.30:50000"
+	if _, err := Authorize(r, ScopePower); err != nil {
+		t.Errorf("access_token 查询参数应可用于认证: %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// 访客令牌：带有效期和可选使用次数上限的令牌，用于临时把部分控制权交给访客。
+// 到期或用完后自动吊销，首次使用时发送 Bark 通知。
+const (
+	// GuestMaxTTL 访客令牌的最长有效期。
+	GuestMaxTTL = 7 * 24 * time.Hour
+	// GuestMinTTL 访客令牌的最短有效期。
+	GuestMinTTL = time.Minute
+)
+
+// ErrGuestExpired 访客令牌已过期或使用次数已用完。
+var ErrGuestExpired = fmt.Errorf("%w: 访客令牌已过期或已用完", ErrUnauthorized)
+
+var (
+	guestMu sync.Mutex
+	// guestNotified 已发送首次使用通知的访客令牌，避免在 LastUsedAt 写回配置前重复通知。
+	guestNotified = make(map[string]bool)
+)
+
+// CreateGuestToken 创建访客令牌。访客令牌不能包含 admin:settings 权限；ttl 必须在 GuestMinTTL 到 GuestMaxTTL 之间，
+// maxUses 为 0 表示不限制使用次数（只统计执行操作的非 GET 请求）。
+func CreateGuestToken(name string, scopes []string, ttl time.Duration, maxUses int) (string, TokenInfo, error) {
+	name, err := validateToken(name, scopes)
+	if err != nil {
+		return "", TokenInfo{}, err
+	}
+	if slices.Contains(scopes, ScopeAdminSettings) {
+		return "", TokenInfo{}, fmt.Errorf("%w: 访客令牌不能包含 %s 权限", ErrInvalidToken, ScopeAdminSettings)
+	}
+	if ttl < GuestMinTTL || ttl > GuestMaxTTL {
+		return "", TokenInfo{}, fmt.Errorf("%w: 有效期必须在 %s 到 %s 之间", ErrInvalidToken, GuestMinTTL, GuestMaxTTL)
+	}
+	if maxUses < 0 {
+		return "", TokenInfo{}, fmt.Errorf("%w: 使用次数上限不能为负数", ErrInvalidToken)
+	}
+	return createToken(bark.APIToken{Name: name, Scopes: scopes, Guest: true, ExpiresAt: time.Now().Add(ttl), MaxUses: maxUses})
+}
+
+// guestExpired 判断访客令牌是否已到期或用完。
+func guestExpired(t bark.APIToken, now time.Time) bool {
+	return !now.Before(t.ExpiresAt) || (t.MaxUses > 0 && t.Uses >= t.MaxUses)
+}
+
+// ListGuests 返回仍然有效的访客令牌。
+func ListGuests() []TokenInfo {
+	PruneExpiredGuests()
+	list := []TokenInfo{}
+	for _, t := range bark.GetConfig().APITokens {
+		if t.Guest {
+			list = append(list, tokenInfo(t))
+		}
+	}
+	return list
+}
+
+// PruneExpiredGuests 吊销已到期或用完的访客令牌。
+func PruneExpiredGuests() {
+	now := time.Now()
+	var expired []bark.APIToken
+	for _, t := range bark.GetConfig().APITokens {
+		if t.Guest && guestExpired(t, now) {
+			expired = append(expired, t)
+		}
+	}
+	if len(expired) == 0 {
+		return
+	}
+	err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+		cfg.APITokens = slices.DeleteFunc(cfg.APITokens, func(t bark.APIToken) bool { return t.Guest && guestExpired(t, now) })
+	})
+	if err != nil {
+		log.Printf("[Auth] 吊销过期的访客令牌失败: %v", err)
+		return
+	}
+	guestMu.Lock()
+	for _, t := range expired {
+		delete(guestNotified, t.ID)
+		log.Printf("[Auth] 访客令牌 %q (%s) 已到期或用完，已自动吊销", t.Name, t.ID)
+	}
+	guestMu.Unlock()
+}
+
+// useGuestToken 为访客令牌记一次使用。超过使用次数上限时返回 ErrGuestExpired，
+// 本次用完最后一次后令牌立即吊销。
+func useGuestToken(id string) error {
+	var exhausted bool
+	var name string
+	found := false
+	err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+		for i := range cfg.APITokens {
+			t := &cfg.APITokens[i]
+			if t.ID != id {
+				continue
+			}
+			if guestExpired(*t, time.Now()) {
+				return
+			}
+			found = true
+			t.Uses++
+			name = t.Name
+			if t.MaxUses > 0 && t.Uses >= t.MaxUses {
+				exhausted = true
+				cfg.APITokens = append(cfg.APITokens[:i:i], cfg.APITokens[i+1:]...)
+			}
+			return
+		}
+	})
+	if err != nil {
+		return fmt.Errorf("保存访客令牌使用次数失败: %w", err)
+	}
+	if !found {
+		return ErrGuestExpired
+	}
+	if exhausted {
+		log.Printf("[Auth] 访客令牌 %q (%s) 已用完，已自动吊销", name, id)
+	}
+	return nil
+}
+
+// notifyGuestFirstUse 访客令牌首次使用时发送 Bark 通知。
+func notifyGuestFirstUse(t bark.APIToken) {
+	guestMu.Lock()
+	if guestNotified[t.ID] {
+		guestMu.Unlock()
+		return
+	}
+	guestNotified[t.ID] = true
+	guestMu.Unlock()
+
+	log.Printf("[Auth] 访客令牌 %q (%s) 首次使用", t.Name, t.ID)
+	bark.NotifyMessage("guest", "Bealink 访客已开始使用",
+		fmt.Sprintf("访客「%s」开始使用，权限: %s，%s 到期。", t.Name, strings.Join(t.Scopes, ", "), t.ExpiresAt.Format("01-02 15:04")))
+}
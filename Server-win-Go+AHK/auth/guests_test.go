@@ -0,0 +1,45 @@
+package auth
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// tokenIDs 返回配置中所有令牌的 ID。
+func tokenIDs() []string {
+	var ids []string
+	for _, t := range bark.GetConfig().APITokens {
+		ids = append(ids, t.ID)
+	}
+	return ids
+}
+
+func TestPruneExpiredGuests(t *testing.T) {
+	now := time.Now()
+	tokens := []bark.APIToken{
+		{ID: "guest-expired", Guest: true, ExpiresAt: now.Add(-time.Minute)},
+		{ID: "guest-used-up", Guest: true, ExpiresAt: now.Add(time.Hour), MaxUses: 2, Uses: 2},
+		{ID: "guest-valid", Guest: true, ExpiresAt: now.Add(time.Hour), MaxUses: 2, Uses: 1},
+		{ID: "device", Name: "手机"},
+	}
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.APITokens = tokens }); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.APITokens = nil }) })
+
+	PruneExpiredGuests()
+	ids := tokenIDs()
+	for _, id := range []string{"guest-expired", "guest-used-up"} {
+		if slices.Contains(ids, id) {
+			t.Errorf("令牌 %s 应已被吊销，剩余: %v", id, ids)
+		}
+	}
+	for _, id := range []string{"guest-valid", "device"} {
+		if !slices.Contains(ids, id) {
+			t.Errorf("令牌 %s 不应被吊销，剩余: %v", id, ids)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// 签名链接：把一个操作、过期时间和随机 nonce 用 HMAC-SHA256 签名后放进 URL，
+// 只能发起 GET 请求的场景（快捷指令、NFC 标签、Bark 通知的 url）无需令牌即可执行该操作，且每个链接只能使用一次。
+const (
+	// LinkDefaultTTL 未指定有效期时签名链接的有效期。
+	LinkDefaultTTL = 24 * time.Hour
+	// LinkMaxTTL 签名链接的最长有效期，已使用的 nonce 需要保留到过期，因此不允许永久有效。
+	LinkMaxTTL = 30 * 24 * time.Hour
+)
+
+var (
+	// ErrLinkInvalid 签名链接参数缺失或签名不正确。
+	ErrLinkInvalid = errors.New("签名链接无效")
+	// ErrLinkExpired 签名链接已过期。
+	ErrLinkExpired = errors.New("签名链接已过期")
+	// ErrLinkUsed 签名链接已被使用过。
+	ErrLinkUsed = errors.New("签名链接已被使用")
+	// ErrLinkInUse 同一链接的另一个请求正在执行（或等待确认）。
+	ErrLinkInUse = errors.New("签名链接正在被另一个请求使用")
+)
+
+var (
+	linkMu sync.Mutex
+	// usedNonces 已使用的 nonce 及其过期时间，首次使用时从配置加载，修改时写回配置。
+	usedNonces     map[string]time.Time
+	usedNoncesOnce sync.Once
+	// reservedNonces 正在执行的链接的 nonce，只保存在内存中，见 ReserveLink。
+	reservedNonces = make(map[string]bool)
+)
+
+// linkSecretLocked 返回签名密钥，不存在时生成并保存。调用方需持有 linkMu。
+func linkSecretLocked() ([]byte, error) {
+	if secret := bark.GetConfig().LinkSecret; secret != "" {
+		return hex.DecodeString(secret)
+	}
+	secret, err := randomBytes(32)
+	if err != nil {
+		return nil, err
+	}
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.LinkSecret = hex.EncodeToString(secret) }); err != nil {
+		return nil, fmt.Errorf("保存签名链接密钥失败: %w", err)
+	}
+	log.Println("[Auth] 已生成签名链接密钥")
+	return secret, nil
+}
+
+func linkSignature(secret []byte, action, exp, nonce string) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(action + "\n" + exp + "\n" + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// SignLink 为 action 生成签名链接的查询参数 (exp, nonce, sig)。ttl 为 0 时使用 LinkDefaultTTL，超过 LinkMaxTTL 时返回错误。
+func SignLink(action string, ttl time.Duration) (url.Values, time.Time, error) {
+	if ttl == 0 {
+		ttl = LinkDefaultTTL
+	}
+	if ttl < 0 || ttl > LinkMaxTTL {
+		return nil, time.Time{}, fmt.Errorf("签名链接有效期必须在 %s 以内", LinkMaxTTL)
+	}
+	nonceBytes, err := randomBytes(12)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	linkMu.Lock()
+	secret, err := linkSecretLocked()
+	linkMu.Unlock()
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+
+	expiresAt := time.Now().Add(ttl).Truncate(time.Second)
+	exp := strconv.FormatInt(expiresAt.Unix(), 10)
+	nonce := base64.RawURLEncoding.EncodeToString(nonceBytes)
+	q := url.Values{}
+	q.Set("exp", exp)
+	q.Set("nonce", nonce)
+	q.Set("sig", linkSignature(secret, action, exp, nonce))
+	return q, expiresAt, nil
+}
+
+// LinkReservation 已通过校验并被预留的签名链接。预留期间同一链接的其他请求返回 ErrLinkInUse；
+// 操作成功后调用 Commit 将链接标记为已使用，失败时调用 Release 释放预留，链接仍可再次使用。
+type LinkReservation struct {
+	nonce     string
+	expiresAt time.Time
+	done      bool
+}
+
+// ReserveLink 校验 action 的签名链接参数并预留其 nonce，调用方必须在操作结束后调用 Commit 或 Release。
+func ReserveLink(action string, q url.Values) (*LinkReservation, error) {
+	linkMu.Lock()
+	defer linkMu.Unlock()
+	nonce, expiresAt, err := verifyLinkLocked(action, q)
+	if err != nil {
+		return nil, err
+	}
+	reservedNonces[nonce] = true
+	return &LinkReservation{nonce: nonce, expiresAt: expiresAt}, nil
+}
+
+// Commit 将预留的链接标记为已使用，之后同一链接再次访问返回 ErrLinkUsed。
+func (l *LinkReservation) Commit() {
+	linkMu.Lock()
+	defer linkMu.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+	delete(reservedNonces, l.nonce)
+	now := time.Now()
+	for n, t := range usedNonces {
+		if now.After(t) {
+			delete(usedNonces, n)
+		}
+	}
+	usedNonces[l.nonce] = l.expiresAt
+	snapshot := maps.Clone(usedNonces)
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.UsedLinkNonces = snapshot }); err != nil {
+		// 写盘失败时仍在内存中记录 nonce，本次运行期间不会被重放
+		log.Printf("[Auth] 保存已使用的签名链接失败: %v", err)
+	}
+}
+
+// Release 释放预留而不消耗链接，用于操作被拒绝或执行失败的情况。已 Commit 的链接不受影响。
+func (l *LinkReservation) Release() {
+	linkMu.Lock()
+	defer linkMu.Unlock()
+	if l.done {
+		return
+	}
+	l.done = true
+	delete(reservedNonces, l.nonce)
+}
+
+// verifyLinkLocked 校验签名、有效期和 nonce 是否已使用或被预留，返回 nonce 及链接的过期时间。调用方需持有 linkMu。
+func verifyLinkLocked(action string, q url.Values) (string, time.Time, error) {
+	exp, nonce, sig := q.Get("exp"), q.Get("nonce"), q.Get("sig")
+	if exp == "" || nonce == "" || sig == "" {
+		return "", time.Time{}, ErrLinkInvalid
+	}
+	if bark.GetConfig().LinkSecret == "" {
+		return "", time.Time{}, ErrLinkInvalid
+	}
+	secret, err := linkSecretLocked()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	// 先校验签名，避免伪造的请求消耗 nonce
+	if !hmac.Equal([]byte(sig), []byte(linkSignature(secret, action, exp, nonce))) {
+		return "", time.Time{}, ErrLinkInvalid
+	}
+	expUnix, err := strconv.ParseInt(exp, 10, 64)
+	if err != nil {
+		return "", time.Time{}, ErrLinkInvalid
+	}
+	expiresAt := time.Unix(expUnix, 0)
+	if time.Now().After(expiresAt) {
+		return "", time.Time{}, ErrLinkExpired
+	}
+
+	usedNoncesOnce.Do(func() {
+		usedNonces = maps.Clone(bark.GetConfig().UsedLinkNonces)
+		if usedNonces == nil {
+			usedNonces = make(map[string]time.Time)
+		}
+	})
+	if _, used := usedNonces[nonce]; used {
+		return "", time.Time{}, ErrLinkUsed
+	}
+	if reservedNonces[nonce] {
+		return "", time.Time{}, ErrLinkInUse
+	}
+	return nonce, expiresAt, nil
+}
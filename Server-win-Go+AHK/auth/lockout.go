@@ -0,0 +1,183 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"sort"
+	"sync"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+const (
+	// lockoutThreshold 在 failureWindow 内猜错这么多个不同的令牌或配对码后封禁来源 IP。
+	lockoutThreshold = 5
+	failureWindow    = 15 * time.Minute
+	// 首次封禁时长，之后每次翻倍，最长 lockoutMax。
+	lockoutBase = time.Minute
+	lockoutMax  = 24 * time.Hour
+	// lockoutForget 封禁结束后这么久没有再次封禁，则清除记录，下次封禁重新从 lockoutBase 开始。
+	lockoutForget = 24 * time.Hour
+)
+
+// lockoutNow 返回当前时间，测试中替换以模拟时间流逝。
+var lockoutNow = time.Now
+
+// ErrBlockNotFound 要解除的封禁记录不存在。
+var ErrBlockNotFound = errors.New("封禁记录不存在")
+
+// BlockInfo 一条封禁记录。Active 为 false 表示封禁已到期，记录保留用于计算下次封禁时长。
+type BlockInfo struct {
+	IP           string    `json:"ip"`
+	Reason       string    `json:"reason"`
+	Lockouts     int       `json:"lockouts"`
+	BlockedAt    time.Time `json:"blocked_at"`
+	BlockedUntil time.Time `json:"blocked_until"`
+	Active       bool      `json:"active"`
+}
+
+var (
+	lockoutMu sync.Mutex
+	// failures 按 IP 记录窗口期内猜错的不同凭据（哈希）及时间。同一个错误令牌反复使用
+	// （例如页面保存了已吊销的令牌并持续轮询）只计一次，避免把正常设备封禁。
+	failures = make(map[string]map[string]time.Time)
+	// blocked 封禁列表的内存副本，首次使用时从配置加载，修改时写回配置。
+	blocked     map[string]bark.BlockedClient
+	blockedOnce sync.Once
+)
+
+func loadBlockedLocked() {
+	blockedOnce.Do(func() {
+		blocked = make(map[string]bark.BlockedClient)
+		for _, b := range bark.GetConfig().BlockedClients {
+			blocked[b.IP] = b
+		}
+	})
+}
+
+// saveBlockedLocked 将封禁列表写回配置文件，调用方需持有 lockoutMu。
+func saveBlockedLocked() {
+	list := make([]bark.BlockedClient, 0, len(blocked))
+	for _, b := range blocked {
+		list = append(list, b)
+	}
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.BlockedClients = list }); err != nil {
+		log.Printf("[Auth] 保存封禁列表失败: %v", err)
+	}
+}
+
+// RecordFailure 记录来源 ip 的一次认证失败。guess 标识本次猜测的凭据（如令牌哈希），相同的 guess 在窗口期内只计一次。
+// 不同猜测达到阈值时封禁该 IP 并发送 Bark 通知。本机地址不计入。
+func RecordFailure(ip, reason, guess string) {
+	if parsed := net.ParseIP(ip); parsed == nil || parsed.IsLoopback() {
+		return
+	}
+	now := lockoutNow()
+	lockoutMu.Lock()
+	loadBlockedLocked()
+	for k, guesses := range failures {
+		for g, t := range guesses {
+			if now.Sub(t) >= failureWindow {
+				delete(guesses, g)
+			}
+		}
+		if len(guesses) == 0 {
+			delete(failures, k)
+		}
+	}
+	guesses := failures[ip]
+	if guesses == nil {
+		guesses = make(map[string]time.Time)
+		failures[ip] = guesses
+	}
+	guesses[guess] = now
+	if len(guesses) < lockoutThreshold {
+		lockoutMu.Unlock()
+		return
+	}
+	delete(failures, ip)
+
+	entry := bark.BlockedClient{IP: ip, Reason: reason, Lockouts: 1, BlockedAt: now}
+	if prev, ok := blocked[ip]; ok && now.Sub(prev.BlockedUntil) < lockoutForget {
+		entry.Lockouts = prev.Lockouts + 1
+	}
+	entry.BlockedUntil = now.Add(lockoutDuration(entry.Lockouts))
+	blocked[ip] = entry
+	saveBlockedLocked()
+	lockoutMu.Unlock()
+
+	d := entry.BlockedUntil.Sub(now)
+	log.Printf("[Auth] %s 在 %s 内认证失败 %d 次 (%s)，封禁 %s（第 %d 次）", ip, failureWindow, lockoutThreshold, reason, d, entry.Lockouts)
+	bark.NotifyMessage("lockout", "Bealink 已封禁可疑设备",
+		fmt.Sprintf("%s 多次认证失败 (%s)，已封禁 %s。\n如为本人设备，可在设置页面解除封禁。", ip, reason, d))
+}
+
+// lockoutDuration 第 n 次封禁的时长：lockoutBase × 2^(n-1)，不超过 lockoutMax。
+func lockoutDuration(n int) time.Duration {
+	d := lockoutBase
+	for i := 1; i < n && d < lockoutMax; i++ {
+		d *= 2
+	}
+	return min(d, lockoutMax)
+}
+
+// Blocked 返回 ip 是否处于封禁中及剩余时长。
+func Blocked(ip string) (time.Duration, bool) {
+	lockoutMu.Lock()
+	defer lockoutMu.Unlock()
+	loadBlockedLocked()
+	b, ok := blocked[ip]
+	if !ok {
+		return 0, false
+	}
+	left := b.BlockedUntil.Sub(lockoutNow())
+	return left, left > 0
+}
+
+// ListBlocked 返回全部封禁记录，并清理已过遗忘期的记录。
+func ListBlocked() []BlockInfo {
+	lockoutMu.Lock()
+	defer lockoutMu.Unlock()
+	loadBlockedLocked()
+	now := lockoutNow()
+	changed := false
+	list := make([]BlockInfo, 0, len(blocked))
+	for ip, b := range blocked {
+		if now.Sub(b.BlockedUntil) >= lockoutForget {
+			delete(blocked, ip)
+			changed = true
+			continue
+		}
+		list = append(list, BlockInfo{IP: b.IP, Reason: b.Reason, Lockouts: b.Lockouts,
+			BlockedAt: b.BlockedAt, BlockedUntil: b.BlockedUntil, Active: now.Before(b.BlockedUntil)})
+	}
+	if changed {
+		saveBlockedLocked()
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].BlockedAt.After(list[j].BlockedAt) })
+	return list
+}
+
+// Unblock 解除 ip 的封禁并清除其失败记录；ip 为空时解除全部封禁。
+func Unblock(ip string) error {
+	lockoutMu.Lock()
+	defer lockoutMu.Unlock()
+	loadBlockedLocked()
+	if ip == "" {
+		blocked = make(map[string]bark.BlockedClient)
+		failures = make(map[string]map[string]time.Time)
+		log.Println("[Auth] 已解除全部封禁")
+	} else {
+		if _, ok := blocked[ip]; !ok {
+			return ErrBlockNotFound
+		}
+		delete(blocked, ip)
+		delete(failures, ip)
+		log.Printf("[Auth] 已解除 %s 的封禁", ip)
+	}
+	saveBlockedLocked()
+	return nil
+}
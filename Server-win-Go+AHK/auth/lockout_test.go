@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// fakeLockoutClock 替换 lockoutNow 并清空封禁状态，返回推进时间的函数。
+func fakeLockoutClock(t *testing.T) (advance func(time.Duration)) {
+	t.Helper()
+	Unblock("")
+	cur := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	var mu sync.Mutex
+	lockoutNow = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return cur
+	}
+	t.Cleanup(func() {
+		lockoutNow = time.Now
+		Unblock("")
+	})
+	return func(d time.Duration) {
+		mu.Lock()
+		cur = cur.Add(d)
+		mu.Unlock()
+	}
+}
+
+// guessN 以 n 个不同的凭据为 ip 记录认证失败。
+func guessN(ip string, n int, prefix string) {
+	for i := 0; i < n; i++ {
+		RecordFailure(ip, "测试", fmt.Sprintf("%s-%d", prefix, i))
+	}
+}
+
+// persistedBlock 返回配置中 ip 的封禁记录。
+func persistedBlock(ip string) (bark.BlockedClient, bool) {
+	for _, b := range bark.GetConfig().BlockedClients {
+		if b.IP == ip {
+			return b, true
+		}
+	}
+	return bark.BlockedClient{}, false
+}
+
+func TestLockoutDuration(t *testing.T) {
+	tests := []struct {
+		n    int
+		want time.Duration
+	}{
+		{1, time.Minute},
+		{2, 2 * time.Minute},
+		{3, 4 * time.Minute},
+		{5, 16 * time.Minute},
+		{11, 1024 * time.Minute},
+		{12, 24 * time.Hour},
+		{100, 24 * time.Hour},
+	}
+	for _, tt := range tests {
+		if got := lockoutDuration(tt.n); got != tt.want {
+			t.Errorf("lockoutDuration(%d) = %s, 期望 %s", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestRecordFailureThreshold(t *testing.T) {
+	fakeLockoutClock(t)
+	const ip = "198.51.100.1"
+
+	guessN(ip, lockoutThreshold-1, "a")
+	if _, blocked := Blocked(ip); blocked {
+		t.Fatalf("%d 次不同的猜测不应封禁", lockoutThreshold-1)
+	}
+	// 同一个错误凭据反复使用只计一次
+	for i := 0; i < 10; i++ {
+		RecordFailure(ip, "测试", "a-0")
+	}
+	if _, blocked := Blocked(ip); blocked {
+		t.Fatal("重复使用同一个错误凭据不应封禁")
+	}
+	RecordFailure(ip, "测试", "a-last")
+	left, blocked := Blocked(ip)
+	if !blocked || left != lockoutBase {
+		t.Fatalf("第 %d 个不同的猜测后应封禁 %s，实际 blocked=%v left=%s", lockoutThreshold, lockoutBase, blocked, left)
+	}
+}
+
+func TestRecordFailureWindow(t *testing.T) {
+	advance := fakeLockoutClock(t)
+	const ip = "198.51.100.2"
+
+	guessN(ip, lockoutThreshold-1, "old")
+	advance(failureWindow)
+	RecordFailure(ip, "测试", "new")
+	if _, blocked := Blocked(ip); blocked {
+		t.Fatal("窗口期外的失败不应计入")
+	}
+	advance(failureWindow - time.Second)
+	guessN(ip, lockoutThreshold-1, "more")
+	if _, blocked := Blocked(ip); !blocked {
+		t.Fatal("窗口期内累计的不同猜测达到阈值时应封禁")
+	}
+}
+
+func TestRecordFailureIgnoresLoopback(t *testing.T) {
+	fakeLockoutClock(t)
+	for _, ip := range []string{"127.0.0.1", "::1", "not-an-ip"} {
+		guessN(ip, lockoutThreshold*2, "x")
+		if _, blocked := Blocked(ip); blocked {
+			t.Errorf("%s 不应被封禁", ip)
+		}
+	}
+}
+
+func TestLockoutEscalationAndExpiry(t *testing.T) {
+	advance := fakeLockoutClock(t)
+	const ip = "198.51.100.3"
+
+	guessN(ip, lockoutThreshold, "first")
+	advance(lockoutBase)
+	if _, blocked := Blocked(ip); blocked {
+		t.Fatal("封禁到期后应解除")
+	}
+	list := ListBlocked()
+	if len(list) != 1 || list[0].Active || list[0].Lockouts != 1 {
+		t.Fatalf("到期的封禁记录应保留且不再生效: %+v", list)
+	}
+
+	// 遗忘期内再次封禁，时长翻倍
+	guessN(ip, lockoutThreshold, "second")
+	if left, blocked := Blocked(ip); !blocked || left != 2*lockoutBase {
+		t.Fatalf("第二次封禁应为 %s，实际 blocked=%v left=%s", 2*lockoutBase, blocked, left)
+	}
+	if b, ok := persistedBlock(ip); !ok || b.Lockouts != 2 {
+		t.Errorf("配置中的封禁记录 = %+v, 期望第 2 次封禁", b)
+	}
+
+	// 遗忘期过后重新从 lockoutBase 开始
+	advance(2*lockoutBase + lockoutForget)
+	if list := ListBlocked(); len(list) != 0 {
+		t.Errorf("超过遗忘期的记录应被清理: %+v", list)
+	}
+	if _, ok := persistedBlock(ip); ok {
+		t.Error("清理后的记录仍保存在配置中")
+	}
+	guessN(ip, lockoutThreshold, "third")
+	if left, blocked := Blocked(ip); !blocked || left != lockoutBase {
+		t.Errorf("遗忘期后再次封禁应为 %s，实际 blocked=%v left=%s", lockoutBase, blocked, left)
+	}
+}
+
+func TestUnblockPersists(t *testing.T) {
+	fakeLockoutClock(t)
+	const ip, other = "198.51.100.4", "198.51.100.5"
+
+	guessN(ip, lockoutThreshold, "a")
+	guessN(other, lockoutThreshold, "b")
+	b, ok := persistedBlock(ip)
+	if !ok || b.Reason != "测试" || b.Lockouts != 1 || b.BlockedUntil.Sub(b.BlockedAt) != lockoutBase {
+		t.Fatalf("封禁应写入配置 BlockedClients，实际: %+v (%v)", b, ok)
+	}
+
+	if err := Unblock(ip); err != nil {
+		t.Fatal(err)
+	}
+	if _, blocked := Blocked(ip); blocked {
+		t.Error("解除封禁后仍被封禁")
+	}
+	if _, ok := persistedBlock(ip); ok {
+		t.Error("解除封禁后配置中仍有记录")
+	}
+	if _, ok := persistedBlock(other); !ok {
+		t.Error("解除一个 IP 不应影响其他封禁")
+	}
+	if err := Unblock(ip); !errors.Is(err, ErrBlockNotFound) {
+		t.Errorf("解除不存在的封禁应返回 ErrBlockNotFound，实际: %v", err)
+	}
+	// 解除封禁同时清除失败记录，需要重新累计
+	guessN(ip, lockoutThreshold-1, "c")
+	if _, blocked := Blocked(ip); blocked {
+		t.Error("解除封禁后失败次数应重新计算")
+	}
+
+	if err := Unblock(""); err != nil {
+		t.Fatal(err)
+	}
+	if len(bark.GetConfig().BlockedClients) != 0 || len(ListBlocked()) != 0 {
+		t.Error("解除全部封禁后仍有记录")
+	}
+}
+
+func TestBlockedLoadsFromConfig(t *testing.T) {
+	fakeLockoutClock(t)
+	const ip = "198.51.100.6"
+	now := lockoutNow()
+	entry := bark.BlockedClient{IP: ip, Reason: "测试", Lockouts: 3, BlockedAt: now, BlockedUntil: now.Add(time.Hour)}
+	if err := bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.BlockedClients = []bark.BlockedClient{entry} }); err != nil {
+		t.Fatal(err)
+	}
+	// 模拟重启：丢弃内存中的封禁列表，下次使用时重新从配置加载
+	lockoutMu.Lock()
+	blocked, blockedOnce = nil, sync.Once{}
+	lockoutMu.Unlock()
+
+	if left, isBlocked := Blocked(ip); !isBlocked || left != time.Hour {
+		t.Errorf("重启后应从配置恢复封禁，实际 blocked=%v left=%s", isBlocked, left)
+	}
+}
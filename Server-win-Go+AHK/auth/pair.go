@@ -0,0 +1,209 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"strings"
+	"sync"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+const (
+	// PairCodeTTL 配对码的有效期，也是本机通知的显示时长。
+	PairCodeTTL = 2 * time.Minute
+	// pairMaxAttempts 每个配对请求允许输错配对码的次数，用完后请求作废。
+	pairMaxAttempts = 5
+	// pairStartInterval 同一 IP 两次发起配对的最小间隔。
+	pairStartInterval = 10 * time.Second
+	// pairMaxPending 所有 IP 同时等待完成的配对请求上限。每个 IP 只保留最新的一个配对请求，
+	// 因此占满上限需要这么多台不同的设备，单台设备无法阻止其他设备配对。
+	pairMaxPending = 16
+	// pairNameMaxLen 设备名称的最大长度（字符数）。
+	pairNameMaxLen = 64
+)
+
+var (
+	// ErrPairNotFound 配对请求不存在、已过期或已完成。
+	ErrPairNotFound = errors.New("配对请求不存在或已过期")
+	// ErrPairCodeMismatch 配对码错误。
+	ErrPairCodeMismatch = errors.New("配对码错误")
+	// ErrPairRateLimited 配对请求过于频繁或输错次数过多。
+	ErrPairRateLimited = errors.New("配对请求过于频繁，请稍后再试")
+)
+
+// PairRequest 一个等待用户在本机确认的配对请求。
+type PairRequest struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Scopes    []string  `json:"scopes"`
+	Remote    string    `json:"remote"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type pendingPair struct {
+	PairRequest
+	code     string
+	attempts int
+}
+
+// PairNotifyFunc 在本机显示配对码。
+type PairNotifyFunc func(req PairRequest, code string) error
+
+// Pairing 管理设备配对：新设备发起配对后，本机显示 6 位配对码，
+// 设备在有效期内提交正确的配对码即可获得一个按设备命名、带指定权限的令牌。
+type Pairing struct {
+	mu        sync.Mutex
+	notify    PairNotifyFunc
+	pending   map[string]*pendingPair
+	lastStart map[string]time.Time // 按 IP 记录最近一次发起配对的时间
+}
+
+// NewPairing 创建配对管理器，notify 负责在本机显示配对码。
+func NewPairing(notify PairNotifyFunc) *Pairing {
+	return &Pairing{
+		notify:    notify,
+		pending:   make(map[string]*pendingPair),
+		lastStart: make(map[string]time.Time),
+	}
+}
+
+// PairableScopes 可以通过配对获得的权限范围。admin:settings 只能在本机设置页面创建。
+func PairableScopes() []string {
+	var scopes []string
+	for _, s := range Scopes {
+		if s.Scope != ScopeAdminSettings {
+			scopes = append(scopes, s.Scope)
+		}
+	}
+	return scopes
+}
+
+// Start 发起配对。scopes 为空时申请全部可配对的权限；remote 为发起方 IP，完成配对时必须一致。
+func (p *Pairing) Start(name string, scopes []string, remote string) (PairRequest, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return PairRequest{}, fmt.Errorf("%w: 设备名称不能为空", ErrInvalidToken)
+	}
+	if len([]rune(name)) > pairNameMaxLen {
+		return PairRequest{}, fmt.Errorf("%w: 设备名称不能超过 %d 个字符", ErrInvalidToken, pairNameMaxLen)
+	}
+	if len(scopes) == 0 {
+		scopes = PairableScopes()
+	}
+	for _, s := range scopes {
+		if s == ScopeAdminSettings {
+			return PairRequest{}, fmt.Errorf("%w: %s 权限不能通过配对获得，请在本机设置页面创建令牌", ErrInvalidToken, s)
+		}
+		if !ValidScope(s) {
+			return PairRequest{}, fmt.Errorf("%w: 未知的权限范围 %s", ErrInvalidToken, s)
+		}
+	}
+
+	idBytes, err := randomBytes(8)
+	if err != nil {
+		return PairRequest{}, err
+	}
+	n, err := rand.Int(rand.Reader, big.NewInt(1000000))
+	if err != nil {
+		return PairRequest{}, fmt.Errorf("生成配对码失败: %w", err)
+	}
+
+	now := time.Now()
+	p.mu.Lock()
+	p.pruneLocked(now)
+	if now.Sub(p.lastStart[remote]) < pairStartInterval {
+		p.mu.Unlock()
+		return PairRequest{}, ErrPairRateLimited
+	}
+	// 同一 IP 再次发起配对时，之前的配对请求作废
+	replaced := ""
+	for id, old := range p.pending {
+		if old.Remote == remote {
+			delete(p.pending, id)
+			replaced = id
+		}
+	}
+	if len(p.pending) >= pairMaxPending {
+		p.mu.Unlock()
+		return PairRequest{}, ErrPairRateLimited
+	}
+	p.lastStart[remote] = now
+	pending := &pendingPair{
+		PairRequest: PairRequest{
+			ID:        hex.EncodeToString(idBytes),
+			Name:      name,
+			Scopes:    append([]string(nil), scopes...),
+			Remote:    remote,
+			ExpiresAt: now.Add(PairCodeTTL),
+		},
+		code: fmt.Sprintf("%06d", n.Int64()),
+	}
+	p.pending[pending.ID] = pending
+	p.mu.Unlock()
+
+	if replaced != "" {
+		log.Printf("[Auth] %s 重新发起配对，之前的配对请求 %s 已作废", remote, replaced)
+	}
+	log.Printf("[Auth] 设备 %q (%s) 发起配对，权限: %s", name, remote, strings.Join(scopes, ", "))
+	if err := p.notify(pending.PairRequest, pending.code); err != nil {
+		// 本机无法弹出通知时（如无桌面的服务器），配对码只写入日志，需在本机查看
+		log.Printf("[Auth] 无法在本机显示配对码 (%v)，配对请求 %s 的配对码: %s", err, pending.ID, pending.code)
+	}
+	return pending.PairRequest, nil
+}
+
+// Complete 校验配对码，成功后创建并返回令牌明文。输错 pairMaxAttempts 次后配对请求作废。
+func (p *Pairing) Complete(id, code, remote string) (string, TokenInfo, error) {
+	now := time.Now()
+	p.mu.Lock()
+	p.pruneLocked(now)
+	pending, ok := p.pending[id]
+	if !ok || pending.Remote != remote {
+		p.mu.Unlock()
+		return "", TokenInfo{}, ErrPairNotFound
+	}
+	if subtle.ConstantTimeCompare([]byte(strings.TrimSpace(code)), []byte(pending.code)) != 1 {
+		pending.attempts++
+		left := pairMaxAttempts - pending.attempts
+		if left <= 0 {
+			delete(p.pending, id)
+		}
+		p.mu.Unlock()
+		log.Printf("[Auth] 设备 %q (%s) 输入的配对码错误，剩余 %d 次", pending.Name, remote, left)
+		RecordFailure(remote, "配对码错误", "pair:"+id+":"+strings.TrimSpace(code))
+		if left <= 0 {
+			return "", TokenInfo{}, fmt.Errorf("%w: 配对码错误次数过多，请重新发起配对", ErrPairRateLimited)
+		}
+		return "", TokenInfo{}, fmt.Errorf("%w，还可尝试 %d 次", ErrPairCodeMismatch, left)
+	}
+	delete(p.pending, id)
+	p.mu.Unlock()
+
+	plain, info, err := createToken(bark.APIToken{Name: pending.Name, Scopes: pending.Scopes, PairedFrom: remote})
+	if err != nil {
+		return "", TokenInfo{}, err
+	}
+	log.Printf("[Auth] 设备 %q (%s) 配对成功", pending.Name, remote)
+	return plain, info, nil
+}
+
+// pruneLocked 清理过期的配对请求和限流记录，调用方需持有 p.mu。
+func (p *Pairing) pruneLocked(now time.Time) {
+	for id, pending := range p.pending {
+		if now.After(pending.ExpiresAt) {
+			delete(p.pending, id)
+		}
+	}
+	for remote, t := range p.lastStart {
+		if now.Sub(t) >= pairStartInterval {
+			delete(p.lastStart, remote)
+		}
+	}
+}
@@ -0,0 +1,108 @@
+package auth
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// TestMain 将用户配置目录指向临时目录，避免测试读写真实的 bealink_config.json。
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bealink-auth-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("APPDATA", dir)
+	os.Setenv("HOME", dir)
+	bark.InitConfig()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// recordingNotify 记录每个配对请求的配对码。
+func recordingNotify(codes map[string]string) PairNotifyFunc {
+	return func(req PairRequest, code string) error {
+		codes[req.ID] = code
+		return nil
+	}
+}
+
+// allowRestart 让 remote 立即可以再次发起配对，模拟 pairStartInterval 已过去。
+func allowRestart(p *Pairing, remote string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.lastStart[remote] = time.Now().Add(-pairStartInterval)
+}
+
+func TestPairingPerIPInterval(t *testing.T) {
+	p := NewPairing(recordingNotify(map[string]string{}))
+	if _, err := p.Start("手机", nil, "192.168.1.10"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Start("手机", nil, "192.168.1.10"); !errors.Is(err, ErrPairRateLimited) {
+		t.Errorf("同一 IP 短时间内再次发起配对应被限流，实际: %v", err)
+	}
+	if _, err := p.Start("平板", nil, "192.168.1.11"); err != nil {
+		t.Errorf("其他 IP 不应受影响: %v", err)
+	}
+}
+
+// TestPairingOnePendingPerIP 同一 IP 反复发起配对只占用一个名额，之前的请求作废。
+func TestPairingOnePendingPerIP(t *testing.T) {
+	codes := map[string]string{}
+	p := NewPairing(recordingNotify(codes))
+	remote := "192.168.1.20"
+	var first PairRequest
+	for i := 0; i < pairMaxPending*2; i++ {
+		allowRestart(p, remote)
+		req, err := p.Start("攻击者", nil, remote)
+		if err != nil {
+			t.Fatalf("第 %d 次发起配对失败: %v", i+1, err)
+		}
+		if i == 0 {
+			first = req
+		}
+	}
+	p.mu.Lock()
+	pending := len(p.pending)
+	p.mu.Unlock()
+	if pending != 1 {
+		t.Errorf("同一 IP 的待完成配对请求数 = %d, 期望 1", pending)
+	}
+	if _, _, err := p.Complete(first.ID, codes[first.ID], remote); !errors.Is(err, ErrPairNotFound) {
+		t.Errorf("被替换的配对请求应已作废，实际: %v", err)
+	}
+	if _, err := p.Start("我的手机", nil, "192.168.1.21"); err != nil {
+		t.Errorf("单个 IP 不应阻止其他设备配对: %v", err)
+	}
+}
+
+func TestPairingGlobalCap(t *testing.T) {
+	p := NewPairing(recordingNotify(map[string]string{}))
+	for i := 0; i < pairMaxPending; i++ {
+		if _, err := p.Start("设备", nil, fmt.Sprintf("10.0.0.%d", i+1)); err != nil {
+			t.Fatalf("第 %d 个设备发起配对失败: %v", i+1, err)
+		}
+	}
+	if _, err := p.Start("设备", nil, "10.0.1.1"); !errors.Is(err, ErrPairRateLimited) {
+		t.Errorf("超过全局上限时应被限流，实际: %v", err)
+	}
+	// 已占用名额的 IP 重新发起时替换自己的请求，不受全局上限影响
+	allowRestart(p, "10.0.0.1")
+	if _, err := p.Start("设备", nil, "10.0.0.1"); err != nil {
+		t.Errorf("已有请求的 IP 重新发起配对失败: %v", err)
+	}
+}
+
+func TestPairingRejectsAdminScope(t *testing.T) {
+	p := NewPairing(recordingNotify(map[string]string{}))
+	if _, err := p.Start("手机", []string{ScopeAdminSettings}, "192.168.1.30"); !errors.Is(err, ErrInvalidToken) {
+		t.Errorf("admin:settings 不能通过配对获得，实际: %v", err)
+	}
+}
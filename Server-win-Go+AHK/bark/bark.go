@@ -333,6 +333,17 @@ func NotifyEvent(eventType string) {
 	}
 }
 
+// NotifyMessage 发送自定义标题和内容的通知（如安全告警）。Bark 未配置时静默跳过；
+// 同一 eventType 受 SendNotification 的频率限制。
+func NotifyMessage(eventType, title, body string) {
+	cfg := GetConfig()
+	if sufficient, _, _, _, _, _ := IsBarkConfigSufficient(cfg); !sufficient {
+		return
+	}
+	log.Printf("触发 Bark 通知: %s (%s)", eventType, title)
+	GetNotifier().SendNotification(eventType, title, body, "", "", "", "", "", false, false)
+}
+
 // GetIconURL 返回推送通知使用的图标 URL（始终返回深色版本）
 func GetIconURL() string {
 	return defaultIconURL
@@ -11,11 +11,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"maps"
 	"os"
 	"os/exec"
 	"path/filepath"
 	"runtime"
 	"sync"
+	"time"
 )
 
 const (
@@ -25,6 +27,17 @@ const (
 	defaultMaxRetries = 5
 	defaultIconURL    = "https://raw.githubusercontent.com/Brian-Lynn/Bealink/refs/heads/main/Server-win-Go%2BAHK/assets/dark_256.png"
 	defaultGroup      = "Bealink"
+	defaultTLSPort    = ":8443"
+
+	// DefaultConfirmTimeoutSec 本机确认框的默认等待时间（秒）。
+	DefaultConfirmTimeoutSec = 30
+	// 电源操作倒计时的默认时长和允许的范围（秒）。
+	DefaultCountdownSec    = 5
+	DefaultCountdownMinSec = 3
+	DefaultCountdownMaxSec = 3600
+
+	// DefaultScheduleReminderMin 定时电源操作执行前发送 Bark 提醒的默认提前量（分钟）。
+	DefaultScheduleReminderMin = 5
 )
 
 // BarkConfig 结构体定义了 Bark 推送所需的配置项
@@ -54,12 +67,94 @@ type BarkConfig struct {
 	// 需要 BarkFullURL 有效配置才会实际发送。
 	NotifyOnSystemReady bool `json:"notify_on_system_ready"`
 
+	// 是否要求非本机请求携带 API 访问令牌。本机 (127.0.0.1 / ::1) 请求始终放行，以便在电脑上管理令牌。
+	RequireAuth bool `json:"require_auth"`
+	// 旧接口 (/sleep、/clip 等) 是否免令牌。默认关闭，旧接口与 /api/v1 一样按 RequireAuth 检查；
+	// 仅为尚未配置令牌的旧版 Android 应用临时开启，开启后局域网设备无需令牌即可调用旧接口（仍受访问控制、CSRF 和操作策略约束）。
+	LegacyAllowNoToken bool `json:"legacy_allow_no_token"`
+
+	// HTTPS：启用后使用自签名证书在 TLSPort 上监听，HTTPEnabled 为 false 时不再提供明文 HTTP（需重启生效）。
+	TLSEnabled  bool   `json:"tls_enabled"`
+	TLSPort     string `json:"tls_port"`
+	HTTPEnabled bool   `json:"http_enabled"`
+
+	// 监听地址：IP（IPv6 链路本地地址可带 %网卡）或网卡名称，为空时监听所有网卡。
+	// ListenPorts 为 HTTP 依次尝试的端口，前一个被占用时使用下一个（需重启生效）。
+	ListenAddresses []string `json:"listen_addresses"`
+	ListenPorts     []string `json:"listen_ports"`
+
+	// 来源 IP 访问控制 (IP 或 CIDR)。拒绝列表优先；允许列表为空时允许所有未被拒绝的来源。本机请求始终放行。
+	AllowCIDRs []string `json:"allow_cidrs"`
+	DenyCIDRs  []string `json:"deny_cidrs"`
+	// 是否在拒绝访问时发送 Bark 通知（同一来源有频率限制）。
+	NotifyOnRejected bool `json:"notify_on_rejected"`
+
+	// 是否允许旧客户端用 GET 调用 /sleep、/shutdown 等操作接口。默认只接受 POST，防止网页通过 <img> 等方式跨站触发。
+	AllowLegacyGET bool `json:"allow_legacy_get"`
+	// 除同源页面外，允许发起 WebSocket 连接和跨站写操作的页面来源，例如 "https://dashboard.lan"。
+	AllowedOrigins []string `json:"allowed_origins"`
+
+	// 各远程操作的策略："allow" 直接执行，"confirm" 需电脑前的人在确认框中允许，"deny" 禁止远程执行。
+	// 键为操作名（sleep、shutdown 等），未列出的操作为 allow。
+	ActionPolicies map[string]string `json:"action_policies,omitempty"`
+	// confirm 策略下确认框的等待时间（秒），超时视为未批准。
+	ConfirmTimeoutSec int `json:"confirm_timeout_sec"`
+
+	// 各电源操作（sleep、shutdown 等）的默认倒计时时长（秒），未列出的操作为 DefaultCountdownSec。
+	// 请求中指定的倒计时时长必须在 CountdownMinSec 到 CountdownMaxSec 之间。
+	CountdownSeconds map[string]int `json:"countdown_seconds,omitempty"`
+	CountdownMinSec  int            `json:"countdown_min_sec"`
+	CountdownMaxSec  int            `json:"countdown_max_sec"`
+
+	// 定时电源操作执行前多少分钟发送 Bark 提醒，0 表示不提醒。定时任务本身保存在配置目录的 bealink_schedules.json 中。
+	ScheduleReminderMin int `json:"schedule_reminder_min"`
+
+	// API 访问令牌，只保存令牌的 SHA-256 哈希，明文仅在创建时显示一次。
+	APITokens []APIToken `json:"api_tokens,omitempty"`
+
+	// 因多次猜错令牌或配对码而被封禁的来源 IP，重启后仍然有效。
+	BlockedClients []BlockedClient `json:"blocked_clients,omitempty"`
+
+	// 签名链接 (/a/...) 的 HMAC 密钥，首次生成链接时创建；更换后已生成的链接全部失效。
+	LinkSecret string `json:"link_secret,omitempty"`
+	// 已使用过的签名链接 nonce 及其过期时间，防止重放；过期后清理。
+	UsedLinkNonces map[string]time.Time `json:"used_link_nonces,omitempty"`
+
 	// DefaultTestTitle string `json:"default_test_title"` // -- 已移除
 	// DefaultTestBody  string `json:"default_test_body"`  // -- 已移除
 
 	mu sync.RWMutex `json:"-"`
 }
 
+// APIToken 一个 API 访问令牌及其权限范围。
+type APIToken struct {
+	ID         string    `json:"id"`
+	Name       string    `json:"name"`
+	Hash       string    `json:"hash"` // 令牌明文的 SHA-256 (hex)
+	Scopes     []string  `json:"scopes"`
+	PairedFrom string    `json:"paired_from,omitempty"` // 通过配对创建时为配对设备的 IP
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+
+	// 以下字段仅用于访客令牌：到期后自动吊销，MaxUses 大于 0 时执行操作的次数达到上限后自动吊销。
+	Guest     bool      `json:"guest,omitempty"`
+	ExpiresAt time.Time `json:"expires_at,omitzero"`
+	MaxUses   int       `json:"max_uses,omitempty"`
+	Uses      int       `json:"uses,omitempty"`
+}
+
+// DefaultListenPorts HTTP 服务默认依次尝试的监听端口
+var DefaultListenPorts = []string{":8088", ":8089", ":8090", ":8080"}
+
+// BlockedClient 一个被封禁的来源 IP。Lockouts 为连续封禁次数，每次封禁时长翻倍。
+type BlockedClient struct {
+	IP           string    `json:"ip"`
+	Reason       string    `json:"reason"`
+	Lockouts     int       `json:"lockouts"`
+	BlockedAt    time.Time `json:"blocked_at"`
+	BlockedUntil time.Time `json:"blocked_until"`
+}
+
 var globalConfig *BarkConfig
 var once sync.Once
 var configFilePath string
@@ -82,6 +177,15 @@ func createDefaultConfig() *BarkConfig {
 		EncryptionKey: "", EncryptionIV: "",
 		RetryDelaySec: defaultRetryDelay, MaxRetries: defaultMaxRetries,
 		NotifyOnSystemReady: true, // 默认启用系统就绪通知
+		RequireAuth:         true, // 默认要求局域网设备使用令牌访问
+		TLSEnabled:          true,
+		TLSPort:             defaultTLSPort,
+		HTTPEnabled:         true, // 默认保留 HTTP，兼容旧客户端
+		ListenPorts:         append([]string(nil), DefaultListenPorts...),
+		ConfirmTimeoutSec:   DefaultConfirmTimeoutSec,
+		CountdownMinSec:     DefaultCountdownMinSec,
+		CountdownMaxSec:     DefaultCountdownMaxSec,
+		ScheduleReminderMin: DefaultScheduleReminderMin,
 	}
 }
 
@@ -126,6 +230,28 @@ func GetConfig() *BarkConfig {
 		RetryDelaySec:       globalConfig.RetryDelaySec,
 		MaxRetries:          globalConfig.MaxRetries,
 		NotifyOnSystemReady: globalConfig.NotifyOnSystemReady,
+		RequireAuth:         globalConfig.RequireAuth,
+		LegacyAllowNoToken:  globalConfig.LegacyAllowNoToken,
+		TLSEnabled:          globalConfig.TLSEnabled,
+		TLSPort:             globalConfig.TLSPort,
+		HTTPEnabled:         globalConfig.HTTPEnabled,
+		ListenAddresses:     append([]string(nil), globalConfig.ListenAddresses...),
+		ListenPorts:         append([]string(nil), globalConfig.ListenPorts...),
+		AllowCIDRs:          append([]string(nil), globalConfig.AllowCIDRs...),
+		DenyCIDRs:           append([]string(nil), globalConfig.DenyCIDRs...),
+		NotifyOnRejected:    globalConfig.NotifyOnRejected,
+		AllowLegacyGET:      globalConfig.AllowLegacyGET,
+		AllowedOrigins:      append([]string(nil), globalConfig.AllowedOrigins...),
+		ActionPolicies:      maps.Clone(globalConfig.ActionPolicies),
+		ConfirmTimeoutSec:   globalConfig.ConfirmTimeoutSec,
+		CountdownSeconds:    maps.Clone(globalConfig.CountdownSeconds),
+		CountdownMinSec:     globalConfig.CountdownMinSec,
+		CountdownMaxSec:     globalConfig.CountdownMaxSec,
+		ScheduleReminderMin: globalConfig.ScheduleReminderMin,
+		APITokens:           append([]APIToken(nil), globalConfig.APITokens...),
+		BlockedClients:      append([]BlockedClient(nil), globalConfig.BlockedClients...),
+		LinkSecret:          globalConfig.LinkSecret,
+		UsedLinkNonces:      maps.Clone(globalConfig.UsedLinkNonces),
 	}
 	return cfg
 }
@@ -175,6 +301,16 @@ func LoadConfig() error {
 		log.Printf("警告: 从配置文件加载的 MaxRetries (%d) 无效，已修正为默认值 %d。", globalConfig.MaxRetries, defaultMaxRetries)
 		globalConfig.MaxRetries = defaultMaxRetries
 	}
+	if len(globalConfig.ListenPorts) == 0 {
+		globalConfig.ListenPorts = append([]string(nil), DefaultListenPorts...)
+	}
+	if globalConfig.ConfirmTimeoutSec <= 0 {
+		globalConfig.ConfirmTimeoutSec = DefaultConfirmTimeoutSec
+	}
+	if globalConfig.CountdownMinSec <= 0 || globalConfig.CountdownMaxSec < globalConfig.CountdownMinSec {
+		log.Printf("警告: 配置文件中的倒计时范围 (%d-%d 秒) 无效，已恢复默认值。", globalConfig.CountdownMinSec, globalConfig.CountdownMaxSec)
+		globalConfig.CountdownMinSec, globalConfig.CountdownMaxSec = DefaultCountdownMinSec, DefaultCountdownMaxSec
+	}
 	return nil
 }
 
@@ -0,0 +1,195 @@
+// Package netacl 处理网络访问控制：把配置中的监听地址（IP、网卡名称、IPv6）解析为实际监听地址，
+// 以及按 CIDR 允许/拒绝列表判断请求来源。
+package netacl
+
+import (
+	"fmt"
+	"log"
+	"net"
+	"strings"
+)
+
+// ResolveListenHosts 将配置的监听地址解析为可传给 net.Listen 的主机部分。
+// 每项可以是 IPv4 / IPv6 地址（IPv6 链路本地地址可带 %网卡 后缀），也可以是网卡名称（展开为该网卡的全部地址）。
+// specs 为空时返回 [""]，表示监听所有网卡。无法解析的项会被跳过并记录日志，全部无法解析时返回错误。
+func ResolveListenHosts(specs []string) ([]string, error) {
+	if len(specs) == 0 {
+		return []string{""}, nil
+	}
+	var hosts []string
+	seen := make(map[string]bool)
+	add := func(h string) {
+		if !seen[h] {
+			seen[h] = true
+			hosts = append(hosts, h)
+		}
+	}
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		if ip, _, ok := parseIPZone(spec); ok {
+			add(formatHost(ip, zoneOf(spec)))
+			continue
+		}
+		iface, err := net.InterfaceByName(spec)
+		if err != nil {
+			log.Printf("警告: 监听地址 %q 既不是 IP 也不是已知网卡，已忽略: %v", spec, err)
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil || len(addrs) == 0 {
+			log.Printf("警告: 网卡 %q 没有可用地址，已忽略 (%v)", spec, err)
+			continue
+		}
+		for _, addr := range addrs {
+			ipnet, ok := addr.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			zone := ""
+			if ipnet.IP.To4() == nil && ipnet.IP.IsLinkLocalUnicast() {
+				zone = iface.Name
+			}
+			add(formatHost(ipnet.IP, zone))
+		}
+	}
+	if len(hosts) == 0 {
+		return nil, fmt.Errorf("配置的监听地址 %v 均无法解析", specs)
+	}
+	return hosts, nil
+}
+
+// parseIPZone 解析可能带 %zone 后缀的 IP。
+func parseIPZone(s string) (net.IP, string, bool) {
+	host, zone, _ := strings.Cut(strings.Trim(s, "[]"), "%")
+	ip := net.ParseIP(host)
+	return ip, zone, ip != nil
+}
+
+func zoneOf(s string) string {
+	_, zone, _ := parseIPZone(s)
+	return zone
+}
+
+func formatHost(ip net.IP, zone string) string {
+	if zone != "" {
+		return ip.String() + "%" + zone
+	}
+	return ip.String()
+}
+
+// Interfaces 返回 hosts 所在的网卡，用于限制 mDNS 只在这些网卡上广播。
+// hosts 包含 ""（所有网卡）时返回 nil，表示不限制。
+func Interfaces(hosts []string) []net.Interface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var result []net.Interface
+	for _, h := range hosts {
+		if h == "" {
+			return nil
+		}
+		ip, _, ok := parseIPZone(h)
+		if !ok {
+			continue
+		}
+		for _, iface := range ifaces {
+			if containsInterface(result, iface) {
+				continue
+			}
+			addrs, _ := iface.Addrs()
+			for _, addr := range addrs {
+				if ipnet, ok := addr.(*net.IPNet); ok && ipnet.IP.Equal(ip) {
+					result = append(result, iface)
+					break
+				}
+			}
+		}
+	}
+	return result
+}
+
+func containsInterface(list []net.Interface, iface net.Interface) bool {
+	for _, i := range list {
+		if i.Index == iface.Index {
+			return true
+		}
+	}
+	return false
+}
+
+// ACL 按来源 IP 判断是否允许访问。拒绝列表优先；允许列表为空时允许所有未被拒绝的地址。
+// 本机环回地址始终允许，避免把本机设置页面也挡在外面。
+type ACL struct {
+	allow []*net.IPNet
+	deny  []*net.IPNet
+}
+
+// ParseCIDR 解析一条 CIDR，也接受单个 IP（视为 /32 或 /128）。
+func ParseCIDR(s string) (*net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, fmt.Errorf("无效的 IP 或 CIDR: %q", s)
+		}
+		bits := 128
+		if ip4 := ip.To4(); ip4 != nil {
+			ip, bits = ip4, 32
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)}, nil
+	}
+	_, ipnet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, fmt.Errorf("无效的 IP 或 CIDR: %q", s)
+	}
+	return ipnet, nil
+}
+
+// NewACL 由允许和拒绝列表创建 ACL，遇到无效项时返回错误。
+func NewACL(allow, deny []string) (*ACL, error) {
+	a := &ACL{}
+	for _, s := range allow {
+		ipnet, err := ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		a.allow = append(a.allow, ipnet)
+	}
+	for _, s := range deny {
+		ipnet, err := ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		a.deny = append(a.deny, ipnet)
+	}
+	return a, nil
+}
+
+// Allowed 判断来源 ip（可带 %zone）是否允许访问。
+func (a *ACL) Allowed(host string) bool {
+	ip, _, ok := parseIPZone(host)
+	if !ok {
+		return false
+	}
+	if ip.IsLoopback() {
+		return true
+	}
+	for _, n := range a.deny {
+		if n.Contains(ip) {
+			return false
+		}
+	}
+	if len(a.allow) == 0 {
+		return true
+	}
+	for _, n := range a.allow {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,158 @@
+package netacl
+
+import (
+	"net"
+	"slices"
+	"testing"
+)
+
+func TestParseCIDR(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{"192.168.1.0/24", "192.168.1.0/24", false},
+		{"192.168.1.77/24", "192.168.1.0/24", false},
+		{" 10.0.0.5 ", "10.0.0.5/32", false},
+		{"fd00::/8", "fd00::/8", false},
+		{"fe80::1", "fe80::1/128", false},
+		{"::ffff:192.168.1.5", "192.168.1.5/32", false},
+		{"0.0.0.0/0", "0.0.0.0/0", false},
+		{"", "", true},
+		{"192.168.1.0/33", "", true},
+		{"256.1.1.1", "", true},
+		{"example.com", "", true},
+		{"fd00::/129", "", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := ParseCIDR(tt.in)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("ParseCIDR(%q) = %v, 期望返回错误", tt.in, got)
+				}
+				return
+			}
+			if err != nil || got.String() != tt.want {
+				t.Errorf("ParseCIDR(%q) = %v, %v, 期望 %s", tt.in, got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewACLRejectsInvalidEntries(t *testing.T) {
+	if _, err := NewACL([]string{"192.168.1.0/24", "bogus"}, nil); err == nil {
+		t.Error("允许列表含无效项时应返回错误")
+	}
+	if _, err := NewACL(nil, []string{"10.0.0.0/40"}); err == nil {
+		t.Error("拒绝列表含无效项时应返回错误")
+	}
+}
+
+func TestACLAllowed(t *testing.T) {
+	tests := []struct {
+		name  string
+		allow []string
+		deny  []string
+		host  string
+		want  bool
+	}{
+		{"空列表允许所有", nil, nil, "203.0.113.9", true},
+		{"允许列表内", []string{"192.168.1.0/24"}, nil, "192.168.1.20", true},
+		{"允许列表外", []string{"192.168.1.0/24"}, nil, "192.168.2.20", false},
+		{"拒绝优先于允许", []string{"192.168.1.0/24"}, []string{"192.168.1.66"}, "192.168.1.66", false},
+		{"拒绝优先于允许的同网段其他地址", []string{"192.168.1.0/24"}, []string{"192.168.1.66"}, "192.168.1.67", true},
+		{"只有拒绝列表", nil, []string{"10.0.0.0/8"}, "10.1.2.3", false},
+		{"只有拒绝列表放行其他", nil, []string{"10.0.0.0/8"}, "172.16.0.1", true},
+		{"环回地址始终允许", []string{"192.168.1.0/24"}, []string{"0.0.0.0/0", "::/0"}, "127.0.0.1", true},
+		{"IPv6 环回地址始终允许", nil, []string{"::/0"}, "::1", true},
+		{"IPv6 允许列表内", []string{"fd00::/8"}, nil, "fd12:3456::1", true},
+		{"IPv6 允许列表外", []string{"fd00::/8"}, nil, "2001:db8::1", false},
+		{"IPv6 拒绝优先", []string{"fd00::/8"}, []string{"fd12::/16"}, "fd12::5", false},
+		{"IPv6 链路本地地址带网卡", []string{"fe80::/10"}, nil, "fe80::1%eth0", true},
+		{"IPv4 映射地址匹配 IPv4 允许列表", []string{"192.168.1.0/24"}, nil, "::ffff:192.168.1.20", true},
+		{"IPv4 映射地址匹配 IPv4 拒绝列表", nil, []string{"192.168.1.0/24"}, "::ffff:192.168.1.20", false},
+		{"IPv6 拒绝列表不影响 IPv4", nil, []string{"::/0"}, "192.168.1.20", true},
+		{"无效来源", nil, nil, "not-an-ip", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			acl, err := NewACL(tt.allow, tt.deny)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := acl.Allowed(tt.host); got != tt.want {
+				t.Errorf("allow=%v deny=%v Allowed(%q) = %v, 期望 %v", tt.allow, tt.deny, tt.host, got, tt.want)
+			}
+		})
+	}
+}
+
+// loopbackInterface 返回本机环回网卡，没有时跳过测试。
+func loopbackInterface(t *testing.T) net.Interface {
+	t.Helper()
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		t.Skipf("无法列出网卡: %v", err)
+	}
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 {
+			if addrs, _ := iface.Addrs(); len(addrs) > 0 {
+				return iface
+			}
+		}
+	}
+	t.Skip("没有可用的环回网卡")
+	return net.Interface{}
+}
+
+func TestResolveListenHosts(t *testing.T) {
+	tests := []struct {
+		name    string
+		specs   []string
+		want    []string
+		wantErr bool
+	}{
+		{"未配置时监听所有网卡", nil, []string{""}, false},
+		{"IPv4", []string{"192.168.1.10"}, []string{"192.168.1.10"}, false},
+		{"IPv6 字面量", []string{"::1"}, []string{"::1"}, false},
+		{"带方括号的 IPv6", []string{"[fd00::1]"}, []string{"fd00::1"}, false},
+		{"链路本地地址带网卡", []string{"fe80::1%eth0"}, []string{"fe80::1%eth0"}, false},
+		{"去除空白和重复项", []string{" 127.0.0.1 ", "127.0.0.1", ""}, []string{"127.0.0.1"}, false},
+		{"跳过无法解析的项", []string{"no-such-iface0", "127.0.0.1"}, []string{"127.0.0.1"}, false},
+		{"全部无法解析", []string{"no-such-iface0"}, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveListenHosts(tt.specs)
+			if (err != nil) != tt.wantErr || !slices.Equal(got, tt.want) {
+				t.Errorf("ResolveListenHosts(%q) = %q, %v, 期望 %q (错误: %v)", tt.specs, got, err, tt.want, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolveListenHostsInterfaceName(t *testing.T) {
+	iface := loopbackInterface(t)
+	hosts, err := ResolveListenHosts([]string{iface.Name})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Contains(hosts, "127.0.0.1") && !slices.Contains(hosts, "::1") {
+		t.Errorf("网卡 %s 应展开为环回地址，实际: %q", iface.Name, hosts)
+	}
+	for _, h := range hosts {
+		if ln, err := net.Listen("tcp", net.JoinHostPort(h, "0")); err != nil {
+			t.Errorf("展开的地址 %q 无法监听: %v", h, err)
+		} else {
+			ln.Close()
+		}
+	}
+	if got := Interfaces(hosts); len(got) != 1 || got[0].Index != iface.Index {
+		t.Errorf("Interfaces(%q) = %v, 期望只有 %s", hosts, got, iface.Name)
+	}
+	if got := Interfaces([]string{""}); got != nil {
+		t.Errorf("监听所有网卡时 Interfaces 应返回 nil，实际: %v", got)
+	}
+}
@@ -0,0 +1,99 @@
+package platform
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// pulseDefaultSink pactl 中表示默认输出设备的特殊名称
+const pulseDefaultSink = "@DEFAULT_SINK@"
+
+// pulseAudio 通过 pactl 控制 PulseAudio（或 PipeWire 的 pipewire-pulse 兼容层）默认输出设备的音量。
+// 需要 pactl 15+ 提供的 get-sink-volume / get-sink-mute 子命令。
+type pulseAudio struct {
+	pactl string
+}
+
+func newPulseAudio() *pulseAudio {
+	return &pulseAudio{pactl: "pactl"}
+}
+
+func (a *pulseAudio) GetVolume() (int, error) {
+	out, err := a.run("get-sink-volume", pulseDefaultSink)
+	if err != nil {
+		return 0, fmt.Errorf("获取音量失败: %w", err)
+	}
+	return parsePactlVolume(out)
+}
+
+func (a *pulseAudio) SetVolume(vol int) error {
+	if _, err := a.run("set-sink-volume", pulseDefaultSink, fmt.Sprintf("%d%%", clampVolume(vol))); err != nil {
+		return fmt.Errorf("设置音量失败: %w", err)
+	}
+	return nil
+}
+
+func (a *pulseAudio) IsMuted() (bool, error) {
+	out, err := a.run("get-sink-mute", pulseDefaultSink)
+	if err != nil {
+		return false, fmt.Errorf("获取静音状态失败: %w", err)
+	}
+	return parsePactlMute(out)
+}
+
+func (a *pulseAudio) ToggleMute() error {
+	if _, err := a.run("set-sink-mute", pulseDefaultSink, "toggle"); err != nil {
+		return fmt.Errorf("静音切换失败: %w", err)
+	}
+	return nil
+}
+
+// run 执行 pactl 并返回 stdout。强制 C 语言环境，保证输出格式可解析。
+func (a *pulseAudio) run(args ...string) (string, error) {
+	cmd := exec.Command(a.pactl, args...)
+	cmd.Env = append(os.Environ(), "LC_ALL=C")
+	out, err := cmd.Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok && len(exitErr.Stderr) > 0 {
+			return "", fmt.Errorf("pactl %s: %w (%s)", strings.Join(args, " "), err, strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return "", fmt.Errorf("pactl %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+var pactlPercentPattern = regexp.MustCompile(`(\d+)%`)
+
+// parsePactlVolume 解析 get-sink-volume 的输出，例如:
+// "Volume: front-left: 32768 /  50% / -18.06 dB,   front-right: 32768 /  50% / -18.06 dB"
+// 多声道时取各声道百分比的平均值。
+func parsePactlVolume(out string) (int, error) {
+	line := strings.SplitN(out, "\n", 2)[0]
+	matches := pactlPercentPattern.FindAllStringSubmatch(line, -1)
+	if len(matches) == 0 {
+		return 0, fmt.Errorf("解析音量值失败 (原始值: %q)", strings.TrimSpace(out))
+	}
+	sum := 0
+	for _, m := range matches {
+		n, _ := strconv.Atoi(m[1])
+		sum += n
+	}
+	return clampVolume((sum + len(matches)/2) / len(matches)), nil
+}
+
+// parsePactlMute 解析 get-sink-mute 的输出，例如 "Mute: no"。
+func parsePactlMute(out string) (bool, error) {
+	value := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(out), "Mute:"))
+	switch value {
+	case "yes":
+		return true, nil
+	case "no":
+		return false, nil
+	default:
+		return false, fmt.Errorf("解析静音状态失败 (原始值: %q)", strings.TrimSpace(out))
+	}
+}
@@ -0,0 +1,94 @@
+package platform
+
+import "testing"
+
+func TestParsePactlVolume(t *testing.T) {
+	tests := []struct {
+		name    string
+		out     string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "stereo",
+			out:  "Volume: front-left: 32768 /  50% / -18.06 dB,   front-right: 32768 /  50% / -18.06 dB\n        balance 0.00\n",
+			want: 50,
+		},
+		{
+			name: "multi-channel average",
+			out:  "Volume: front-left: 21627 /  33% / -28.88 dB,   front-right: 22282 /  34% / -28.11 dB\n        balance 0.03\n",
+			want: 34,
+		},
+		{
+			name: "5.1 average",
+			out:  "Volume: front-left: 65536 / 100% / 0.00 dB,   front-right: 65536 / 100% / 0.00 dB,   rear-left: 0 /   0% / -inf dB,   rear-right: 0 /   0% / -inf dB,   front-center: 32768 /  50% / -18.06 dB,   lfe: 32768 /  50% / -18.06 dB\n",
+			want: 50,
+		},
+		{
+			name: "mono",
+			out:  "Volume: mono: 45875 /  70% / -9.29 dB\n        balance 0.00\n",
+			want: 70,
+		},
+		{
+			name: "over-amplified is clamped",
+			out:  "Volume: front-left: 98304 / 150% / 10.57 dB,   front-right: 98304 / 150% / 10.57 dB\n",
+			want: 100,
+		},
+		{
+			name:    "percent only on later line is ignored",
+			out:     "Volume: n/a\n        balance 50%\n",
+			wantErr: true,
+		},
+		{name: "empty", out: "", wantErr: true},
+		{name: "garbage", out: "Failed to get sink volume: No such entity\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parsePactlVolume(tt.out)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("parsePactlVolume(%q) = %d, 期望返回错误", tt.out, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("parsePactlVolume(%q) 返回错误: %v", tt.out, err)
+			}
+			if got != tt.want {
+				t.Errorf("parsePactlVolume(%q) = %d, 期望 %d", tt.out, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsePactlMute(t *testing.T) {
+	tests := []struct {
+		out     string
+		want    bool
+		wantErr bool
+	}{
+		{out: "Mute: yes\n", want: true},
+		{out: "Mute: no\n", want: false},
+		{out: "  Mute:   yes  ", want: true},
+		{out: "Mute: maybe\n", wantErr: true},
+		{out: "Mute: YES\n", wantErr: true},
+		{out: "", wantErr: true},
+		{out: "Failed to get sink mute status: No such entity\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := parsePactlMute(tt.out)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("parsePactlMute(%q) = %t, 期望返回错误", tt.out, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("parsePactlMute(%q) 返回错误: %v", tt.out, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parsePactlMute(%q) = %t, 期望 %t", tt.out, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package platform
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+
+	"bealinkserver/ahk"
+)
+
+// ahkHelperEnv 可指定一个实现辅助进程行协议的可执行文件（例如 ahk/fakehelper），
+// 用来替代 AutoHotkey.exe + helper.ahk，便于在没有 AutoHotkey 的机器上调试。
+const ahkHelperEnv = "BEALINK_AHK_HELPER"
+
+// ahkAudio 通过常驻的 AHK 辅助进程 (ahk/script/helper.ahk) 读写系统主音量，
+// 拖动音量滑块时不再为每次调用启动新的 AutoHotkey 进程。
+type ahkAudio struct {
+	helper *ahk.Helper
+}
+
+// newAhkAudio 创建音量后端，辅助进程在第一次调用时启动。
+func newAhkAudio() Audio {
+	if path := os.Getenv(ahkHelperEnv); path != "" {
+		log.Printf("信息: 音量控制将使用自定义辅助进程: %s", path)
+		return ahkAudio{helper: ahk.NewHelper(path)}
+	}
+	helper, err := ahk.NewScriptHelper()
+	if err != nil {
+		log.Printf("警告: 无法定位 AHK 辅助脚本，音量控制不可用: %v", err)
+		return unsupported{}
+	}
+	return ahkAudio{helper: helper}
+}
+
+func (a ahkAudio) GetVolume() (int, error) {
+	output, err := a.helper.Call("GET_VOLUME")
+	if err != nil {
+		return 0, fmt.Errorf("获取音量失败 (请确认 AutoHotkey.exe 存在): %w", err)
+	}
+	// AHK 输出可能是 "25" 或 "25.000000"，解析为 float 后四舍五入转 int
+	val, err := strconv.ParseFloat(output, 64)
+	if err != nil {
+		return 0, fmt.Errorf("解析音量值失败: %w (原始值: %q)", err, output)
+	}
+	return int(val + 0.5), nil
+}
+
+func (a ahkAudio) SetVolume(vol int) error {
+	if _, err := a.helper.Call("SET_VOLUME " + strconv.Itoa(vol)); err != nil {
+		return fmt.Errorf("设置音量失败: %w", err)
+	}
+	return nil
+}
+
+func (a ahkAudio) IsMuted() (bool, error) {
+	output, err := a.helper.Call("GET_MUTE")
+	if err != nil {
+		return false, fmt.Errorf("获取静音状态失败 (请确认 AutoHotkey.exe 存在): %w", err)
+	}
+	switch output {
+	case "On":
+		return true, nil
+	case "Off":
+		return false, nil
+	default:
+		return false, fmt.Errorf("解析静音状态失败 (原始值: %q)", output)
+	}
+}
+
+func (a ahkAudio) ToggleMute() error {
+	if _, err := a.helper.Call("MUTE"); err != nil {
+		return fmt.Errorf("静音切换失败: %w", err)
+	}
+	return nil
+}
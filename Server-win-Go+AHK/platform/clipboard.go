@@ -0,0 +1,12 @@
+package platform
+
+import "github.com/atotto/clipboard"
+
+// textClipboard 使用 atotto/clipboard 读写文本剪贴板（Windows 使用 Win32 API，Linux 依赖 xclip/xsel/wl-clipboard）。
+type textClipboard struct{}
+
+func (textClipboard) ReadText() (string, error) { return clipboard.ReadAll() }
+
+func (textClipboard) WriteText(text string) error { return clipboard.WriteAll(text) }
+
+func (textClipboard) SetImage(data []byte, filename string) error { return ErrUnsupported }
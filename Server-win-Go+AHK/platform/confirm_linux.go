@@ -0,0 +1,39 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// zenity --question 的退出码：0 为「是」，1 为「否」或关闭窗口，5 为超时。
+const zenityExitTimeout = 5
+
+// zenityConfirmer 通过 zenity 在桌面会话中显示确认框，需要已安装 zenity。
+type zenityConfirmer struct{}
+
+func (zenityConfirmer) Confirm(ctx context.Context, title, message string, timeout time.Duration) (ConfirmResult, error) {
+	seconds := max(int(math.Ceil(timeout.Seconds())), 1)
+	ctx, cancel := context.WithTimeout(ctx, timeout+5*time.Second)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, "zenity", "--question", "--default-cancel",
+		"--title="+title, "--text="+message, "--ok-label=允许", "--cancel-label=拒绝", "--timeout="+strconv.Itoa(seconds))
+	err := cmd.Run()
+	var exitErr *exec.ExitError
+	switch {
+	case err == nil:
+		return ConfirmApproved, nil
+	case ctx.Err() != nil:
+		return ConfirmTimeout, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == 1:
+		return ConfirmDenied, nil
+	case errors.As(err, &exitErr) && exitErr.ExitCode() == zenityExitTimeout:
+		return ConfirmTimeout, nil
+	default:
+		return "", fmt.Errorf("zenity 失败: %w", err)
+	}
+}
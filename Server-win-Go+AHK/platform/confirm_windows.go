@@ -0,0 +1,64 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"time"
+
+	"bealinkserver/ahk"
+)
+
+// confirm.ahk 的退出码。
+const (
+	confirmExitApproved = 0
+	confirmExitDenied   = 1
+	confirmExitTimeout  = 2
+)
+
+// ahkConfirmer 使用 ahk/script/confirm.ahk 显示置顶的 是/否 对话框。
+// 参数依次为内容、标题和超时秒数，默认按钮为「否」，避免误按回车批准。
+type ahkConfirmer struct{}
+
+func (ahkConfirmer) Confirm(ctx context.Context, title, message string, timeout time.Duration) (ConfirmResult, error) {
+	seconds := max(int(math.Ceil(timeout.Seconds())), 1)
+	proc, err := ahk.RunScriptAndGetProcess("confirm.ahk", message, title, strconv.Itoa(seconds))
+	if err != nil {
+		return "", err
+	}
+	exited := make(chan int, 1)
+	go func() {
+		state, err := proc.Wait()
+		if err != nil {
+			log.Printf("等待确认脚本 (PID: %d) 结束时发生错误: %v", proc.Pid, err)
+			exited <- -1
+			return
+		}
+		exited <- state.ExitCode()
+	}()
+
+	// 脚本自身也会超时退出，这里多等几秒作为兜底
+	timer := time.NewTimer(timeout + 5*time.Second)
+	defer timer.Stop()
+	select {
+	case code := <-exited:
+		switch code {
+		case confirmExitApproved:
+			return ConfirmApproved, nil
+		case confirmExitDenied:
+			return ConfirmDenied, nil
+		case confirmExitTimeout:
+			return ConfirmTimeout, nil
+		default:
+			return "", fmt.Errorf("确认脚本异常退出 (退出码 %d)", code)
+		}
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	if err := proc.Kill(); err != nil {
+		log.Printf("关闭确认脚本 (PID: %d) 失败: %v", proc.Pid, err)
+	}
+	return ConfirmTimeout, nil
+}
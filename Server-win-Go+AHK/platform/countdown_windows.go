@@ -0,0 +1,95 @@
+package platform
+
+import (
+	"fmt"
+	"log"
+	"math"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+
+	"bealinkserver/ahk"
+)
+
+// countdownExitCancelled 倒计时脚本在用户点击窗口取消时使用的退出码。
+const countdownExitCancelled = 2
+
+// countdownScript 所有电源操作共用的倒计时脚本。
+const countdownScript = "countdown.ahk"
+
+// countdownStyle 倒计时窗口的标题、操作名称和进度条颜色。
+type countdownStyle struct {
+	title string
+	label string
+	color string
+}
+
+// countdownStyles 各电源操作的倒计时窗口样式：关机红色，重启和注销橙色，其余蓝色。
+var countdownStyles = map[string]countdownStyle{
+	"sleep":     {"Bealink Sleep Countdown", "睡眠", "0078D7"},
+	"shutdown":  {"Bealink Shutdown Countdown", "关机", "FF0000"},
+	"restart":   {"Bealink Restart Countdown", "重启", "FF8C00"},
+	"hibernate": {"Bealink Hibernate Countdown", "休眠", "0078D7"},
+	"lock":      {"Bealink Lock Countdown", "锁定", "0078D7"},
+	"signout":   {"Bealink Sign Out Countdown", "注销", "FF8C00"},
+}
+
+// ahkCountdownView 使用 ahk/script/countdown.ahk 显示倒计时。
+// 脚本只负责展示剩余时间和原因（可为空），窗口标题、操作名称和颜色按 countdownStyles 传入，不执行任何电源操作；
+// 用户点击窗口取消时脚本以 countdownExitCancelled 退出。
+type ahkCountdownView struct{}
+
+func (ahkCountdownView) ShowCountdown(action string, deadline time.Time, reason string, onCancel func()) (CountdownWindow, error) {
+	style, ok := countdownStyles[action]
+	if !ok {
+		return nil, fmt.Errorf("%w: 没有%s的倒计时界面", ErrUnsupported, action)
+	}
+	seconds := int(math.Ceil(time.Until(deadline).Seconds()))
+	proc, err := ahk.RunScriptAndGetProcess(countdownScript, strconv.Itoa(seconds), reason, style.title, style.label, style.color)
+	if err != nil {
+		return nil, err
+	}
+	w := &processCountdownWindow{proc: proc}
+	go func() {
+		pid := proc.Pid
+		state, err := proc.Wait()
+		if err != nil {
+			log.Printf("等待 %s AHK 脚本 (PID: %d) 结束时发生错误: %v", countdownScript, pid, err)
+			return
+		}
+		if w.isClosed() {
+			return
+		}
+		log.Printf("%s AHK 脚本 (PID: %d) 已结束，退出状态: %s", countdownScript, pid, state.String())
+		if state.ExitCode() == countdownExitCancelled {
+			onCancel()
+		}
+	}()
+	return w, nil
+}
+
+// processCountdownWindow 对应一个正在运行的倒计时脚本进程。
+type processCountdownWindow struct {
+	proc *os.Process
+
+	mu     sync.Mutex
+	closed bool
+}
+
+func (w *processCountdownWindow) isClosed() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.closed
+}
+
+func (w *processCountdownWindow) Close() error {
+	w.mu.Lock()
+	if w.closed {
+		w.mu.Unlock()
+		return nil
+	}
+	w.closed = true
+	w.mu.Unlock()
+	return w.proc.Kill()
+}
@@ -0,0 +1,185 @@
+package platform
+
+import (
+	"encoding/binary"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/unix"
+)
+
+const (
+	uinputPath       = "/dev/uinput"
+	uinputDeviceName = "Bealink Virtual Keyboard"
+
+	// ioctl 请求号 (linux/uinput.h)
+	uiSetEvBit   = 0x40045564 // _IOW('U', 100, int)
+	uiSetKeyBit  = 0x40045565 // _IOW('U', 101, int)
+	uiDevSetup   = 0x405c5503 // _IOW('U', 3, struct uinput_setup)
+	uiDevCreate  = 0x5501     // _IO('U', 1)
+	uiDevDestroy = 0x5502     // _IO('U', 2)
+
+	evSyn     = 0x00
+	evKey     = 0x01
+	synReport = 0
+
+	busVirtual = 0x06
+
+	// 新建的虚拟设备需要一点时间才会被桌面环境识别，过早发送的事件会丢失
+	uinputSettleDelay = 200 * time.Millisecond
+)
+
+// keyDevice 接收按键事件的设备，真实实现为 /dev/uinput 虚拟键盘，测试时可替换为记录事件的假设备。
+type keyDevice interface {
+	// Emit 发送一次按下/松开并同步 (SYN_REPORT)。
+	Emit(code uint16, pressed bool) error
+}
+
+// uinputInput 通过虚拟键盘设备模拟按键，按键词汇与 Windows 实现相同（VK_* 虚拟键码）。
+type uinputInput struct {
+	mu     sync.Mutex
+	device keyDevice
+	open   func() (keyDevice, error)
+}
+
+func newUinputInput() *uinputInput {
+	return &uinputInput{open: openUinputDevice}
+}
+
+func (in *uinputInput) SendKeyPress(vk uint16) error {
+	return in.SendKeyWithModifiers(false, false, false, vk)
+}
+
+func (in *uinputInput) SendKeyWithModifiers(ctrl, alt, shift bool, vk uint16) error {
+	events, err := keyChordEvents(ctrl, alt, shift, vk)
+	if err != nil {
+		return err
+	}
+	return in.send(events)
+}
+
+// Paste 模拟粘贴操作 (Ctrl+V)
+func (in *uinputInput) Paste() error {
+	return in.SendKeyWithModifiers(true, false, false, VK_V)
+}
+
+// send 依次发送事件序列。中途失败时尽量松开已按下的键，避免修饰键卡住。
+func (in *uinputInput) send(events []keyEvent) error {
+	in.mu.Lock()
+	defer in.mu.Unlock()
+	if in.device == nil {
+		device, err := in.open()
+		if err != nil {
+			return err
+		}
+		in.device = device
+	}
+	var pressed []uint16
+	for _, ev := range events {
+		if err := in.device.Emit(ev.Code, ev.Pressed); err != nil {
+			for i := len(pressed) - 1; i >= 0; i-- {
+				in.device.Emit(pressed[i], false)
+			}
+			return fmt.Errorf("发送按键事件失败 (code %d): %w", ev.Code, err)
+		}
+		if ev.Pressed {
+			pressed = append(pressed, ev.Code)
+		} else {
+			for i, code := range pressed {
+				if code == ev.Code {
+					pressed = append(pressed[:i], pressed[i+1:]...)
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// uinputSetup 对应内核的 struct uinput_setup
+type uinputSetup struct {
+	BusType      uint16
+	Vendor       uint16
+	Product      uint16
+	Version      uint16
+	Name         [80]byte
+	FFEffectsMax uint32
+}
+
+// inputEvent 对应内核的 struct input_event
+type inputEvent struct {
+	Time  unix.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// uinputDevice 是通过 /dev/uinput 创建的虚拟键盘。
+type uinputDevice struct {
+	f *os.File
+}
+
+// openUinputDevice 创建虚拟键盘，并声明翻译表中的全部按键。需要对 /dev/uinput 有写权限。
+func openUinputDevice() (keyDevice, error) {
+	f, err := os.OpenFile(uinputPath, os.O_WRONLY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, fmt.Errorf("打开 %s 失败 (需要 uinput 权限): %w", uinputPath, err)
+	}
+	fd := f.Fd()
+	if err := ioctlInt(fd, uiSetEvBit, evKey); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("UI_SET_EVBIT 失败: %w", err)
+	}
+	for _, code := range vkToLinuxKey {
+		if err := ioctlInt(fd, uiSetKeyBit, uintptr(code)); err != nil {
+			f.Close()
+			return nil, fmt.Errorf("UI_SET_KEYBIT (%d) 失败: %w", code, err)
+		}
+	}
+	setup := uinputSetup{BusType: busVirtual, Vendor: 0x1209, Product: 0xbea1, Version: 1}
+	copy(setup.Name[:], uinputDeviceName)
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, uiDevSetup, uintptr(unsafe.Pointer(&setup))); errno != 0 {
+		f.Close()
+		return nil, fmt.Errorf("UI_DEV_SETUP 失败: %w", errno)
+	}
+	if err := ioctlInt(fd, uiDevCreate, 0); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("UI_DEV_CREATE 失败: %w", err)
+	}
+	log.Printf("已创建虚拟键盘设备: %s", uinputDeviceName)
+	time.Sleep(uinputSettleDelay)
+	return &uinputDevice{f: f}, nil
+}
+
+func (d *uinputDevice) Emit(code uint16, pressed bool) error {
+	value := int32(0)
+	if pressed {
+		value = 1
+	}
+	if err := d.write(evKey, code, value); err != nil {
+		return err
+	}
+	return d.write(evSyn, synReport, 0)
+}
+
+func (d *uinputDevice) write(evType, code uint16, value int32) error {
+	tv := unix.NsecToTimeval(time.Now().UnixNano())
+	return binary.Write(d.f, binary.NativeEndian, inputEvent{Time: tv, Type: evType, Code: code, Value: value})
+}
+
+// Close 销毁虚拟键盘设备。
+func (d *uinputDevice) Close() error {
+	ioctlInt(d.f.Fd(), uiDevDestroy, 0)
+	return d.f.Close()
+}
+
+func ioctlInt(fd uintptr, req uint, value uintptr) error {
+	if _, _, errno := unix.Syscall(unix.SYS_IOCTL, fd, uintptr(req), value); errno != 0 {
+		return errno
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package platform
+
+import (
+	"encoding/binary"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// openFakeUinput 用普通文件代替 /dev/uinput 创建 uinputDevice，返回 uinputInput 和文件路径。
+func openFakeUinput(t *testing.T) (*uinputInput, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "uinput")
+	in := &uinputInput{open: func() (keyDevice, error) {
+		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0600)
+		if err != nil {
+			return nil, err
+		}
+		t.Cleanup(func() { f.Close() })
+		return &uinputDevice{f: f}, nil
+	}}
+	return in, path
+}
+
+// readFakeUinput 解析写入假设备文件的 input_event 序列。
+func readFakeUinput(t *testing.T, path string) []inputEvent {
+	t.Helper()
+	f, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	var events []inputEvent
+	for {
+		var ev inputEvent
+		if err := binary.Read(f, binary.NativeEndian, &ev); err != nil {
+			if err != io.EOF {
+				t.Fatalf("解析事件失败: %v", err)
+			}
+			return events
+		}
+		events = append(events, ev)
+	}
+}
+
+// keyDown/keyUp 生成一次按键事件及其后的 SYN_REPORT。
+func keyDown(code uint16) []inputEvent {
+	return []inputEvent{{Type: evKey, Code: code, Value: 1}, {Type: evSyn, Code: synReport}}
+}
+
+func keyUp(code uint16) []inputEvent {
+	return []inputEvent{{Type: evKey, Code: code, Value: 0}, {Type: evSyn, Code: synReport}}
+}
+
+func concatEvents(parts ...[]inputEvent) []inputEvent {
+	var all []inputEvent
+	for _, p := range parts {
+		all = append(all, p...)
+	}
+	return all
+}
+
+func TestUinputKeySequences(t *testing.T) {
+	tests := []struct {
+		name string
+		send func(in *uinputInput) error
+		want []inputEvent
+	}{
+		{
+			name: "volume up",
+			send: func(in *uinputInput) error { return in.SendKeyPress(VK_VOLUME_UP) },
+			want: concatEvents(keyDown(linuxKeyVolumeUp), keyUp(linuxKeyVolumeUp)),
+		},
+		{
+			name: "media play/pause",
+			send: func(in *uinputInput) error { return in.SendKeyPress(VK_MEDIA_PLAY_PAUSE) },
+			want: concatEvents(keyDown(linuxKeyPlayPause), keyUp(linuxKeyPlayPause)),
+		},
+		{
+			name: "paste",
+			send: func(in *uinputInput) error { return in.Paste() },
+			want: concatEvents(keyDown(linuxKeyLeftCtrl), keyDown(47), keyUp(47), keyUp(linuxKeyLeftCtrl)),
+		},
+		{
+			name: "ctrl+alt+p",
+			send: func(in *uinputInput) error { return in.SendKeyWithModifiers(true, true, false, VK_P) },
+			want: concatEvents(
+				keyDown(linuxKeyLeftCtrl), keyDown(linuxKeyLeftAlt),
+				keyDown(25), keyUp(25),
+				keyUp(linuxKeyLeftAlt), keyUp(linuxKeyLeftCtrl),
+			),
+		},
+		{
+			name: "ctrl+alt+shift+right",
+			send: func(in *uinputInput) error { return in.SendKeyWithModifiers(true, true, true, VK_RIGHT) },
+			want: concatEvents(
+				keyDown(linuxKeyLeftCtrl), keyDown(linuxKeyLeftAlt), keyDown(linuxKeyLeftShift),
+				keyDown(linuxKeyRight), keyUp(linuxKeyRight),
+				keyUp(linuxKeyLeftShift), keyUp(linuxKeyLeftAlt), keyUp(linuxKeyLeftCtrl),
+			),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			in, path := openFakeUinput(t)
+			if err := tt.send(in); err != nil {
+				t.Fatalf("发送失败: %v", err)
+			}
+			got := readFakeUinput(t, path)
+			if len(got) != len(tt.want) {
+				t.Fatalf("事件数 = %d, 期望 %d: %+v", len(got), len(tt.want), got)
+			}
+			for i := range got {
+				g, w := got[i], tt.want[i]
+				if g.Type != w.Type || g.Code != w.Code || g.Value != w.Value {
+					t.Errorf("事件[%d] = {type %d code %d value %d}, 期望 {type %d code %d value %d}",
+						i, g.Type, g.Code, g.Value, w.Type, w.Code, w.Value)
+				}
+			}
+		})
+	}
+}
+
+func TestUinputUnsupportedKey(t *testing.T) {
+	opened := false
+	in := &uinputInput{open: func() (keyDevice, error) {
+		opened = true
+		return nil, errors.New("不应打开设备")
+	}}
+	if err := in.SendKeyPress(0xFF); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("未翻译的按键应返回 ErrUnsupported，实际: %v", err)
+	}
+	if opened {
+		t.Error("未翻译的按键不应打开 uinput 设备")
+	}
+}
+
+// failingKeyDevice 在发送第 failAt 个事件时失败，记录收到的所有事件。
+type failingKeyDevice struct {
+	failAt int
+	events []keyEvent
+}
+
+func (d *failingKeyDevice) Emit(code uint16, pressed bool) error {
+	if len(d.events) == d.failAt {
+		d.failAt = -1
+		return errors.New("写入失败")
+	}
+	d.events = append(d.events, keyEvent{Code: code, Pressed: pressed})
+	return nil
+}
+
+// TestUinputReleasesModifiersOnError 中途写入失败时应松开已按下的修饰键，避免卡键。
+func TestUinputReleasesModifiersOnError(t *testing.T) {
+	dev := &failingKeyDevice{failAt: 2}
+	in := &uinputInput{open: func() (keyDevice, error) { return dev, nil }}
+	if err := in.SendKeyWithModifiers(true, true, false, VK_P); err == nil {
+		t.Fatal("写入失败时应返回错误")
+	}
+	want := []keyEvent{
+		{linuxKeyLeftCtrl, true}, {linuxKeyLeftAlt, true},
+		{linuxKeyLeftAlt, false}, {linuxKeyLeftCtrl, false},
+	}
+	if len(dev.events) != len(want) {
+		t.Fatalf("事件 = %+v, 期望 %+v", dev.events, want)
+	}
+	for i := range want {
+		if dev.events[i] != want[i] {
+			t.Fatalf("事件 = %+v, 期望 %+v", dev.events, want)
+		}
+	}
+}
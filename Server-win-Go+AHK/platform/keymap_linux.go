@@ -0,0 +1,99 @@
+package platform
+
+import "fmt"
+
+// Linux 输入事件键码 (linux/input-event-codes.h)
+const (
+	linuxKeyEsc          = 1
+	linuxKeyBackspace    = 14
+	linuxKeyTab          = 15
+	linuxKeyEnter        = 28
+	linuxKeyLeftCtrl     = 29
+	linuxKeyLeftShift    = 42
+	linuxKeyLeftAlt      = 56
+	linuxKeySpace        = 57
+	linuxKeyUp           = 103
+	linuxKeyLeft         = 105
+	linuxKeyRight        = 106
+	linuxKeyDown         = 108
+	linuxKeyMute         = 113
+	linuxKeyVolumeDown   = 114
+	linuxKeyVolumeUp     = 115
+	linuxKeyNextSong     = 163
+	linuxKeyPlayPause    = 164
+	linuxKeyPreviousSong = 165
+)
+
+// vkToLinuxKey 将 Windows 虚拟键码翻译为 Linux 键码，未列出的按键视为不支持。
+var vkToLinuxKey = map[uint16]uint16{
+	0x08:                linuxKeyBackspace, // VK_BACK
+	0x09:                linuxKeyTab,       // VK_TAB
+	0x0D:                linuxKeyEnter,     // VK_RETURN
+	0x1B:                linuxKeyEsc,       // VK_ESCAPE
+	0x20:                linuxKeySpace,     // VK_SPACE
+	0x26:                linuxKeyUp,        // VK_UP
+	0x28:                linuxKeyDown,      // VK_DOWN
+	VK_SHIFT:            linuxKeyLeftShift,
+	VK_CONTROL:          linuxKeyLeftCtrl,
+	VK_MENU:             linuxKeyLeftAlt,
+	VK_LEFT:             linuxKeyLeft,
+	VK_RIGHT:            linuxKeyRight,
+	VK_VOLUME_MUTE:      linuxKeyMute,
+	VK_VOLUME_DOWN:      linuxKeyVolumeDown,
+	VK_VOLUME_UP:        linuxKeyVolumeUp,
+	VK_MEDIA_NEXT_TRACK: linuxKeyNextSong,
+	VK_MEDIA_PREV_TRACK: linuxKeyPreviousSong,
+	VK_MEDIA_PLAY_PAUSE: linuxKeyPlayPause,
+
+	// 数字键 '0'-'9'
+	'1': 2, '2': 3, '3': 4, '4': 5, '5': 6, '6': 7, '7': 8, '8': 9, '9': 10, '0': 11,
+
+	// 字母键 'A'-'Z'
+	'Q': 16, 'W': 17, 'E': 18, 'R': 19, 'T': 20, 'Y': 21, 'U': 22, 'I': 23, 'O': 24, 'P': 25,
+	'A': 30, 'S': 31, 'D': 32, 'F': 33, 'G': 34, 'H': 35, 'J': 36, 'K': 37, 'L': 38,
+	'Z': 44, 'X': 45, 'C': 46, 'V': 47, 'B': 48, 'N': 49, 'M': 50,
+}
+
+// translateVK 返回虚拟键码对应的 Linux 键码。
+func translateVK(vk uint16) (uint16, error) {
+	code, ok := vkToLinuxKey[vk]
+	if !ok {
+		return 0, fmt.Errorf("虚拟键码 0x%02X 没有对应的 Linux 键码: %w", vk, ErrUnsupported)
+	}
+	return code, nil
+}
+
+// keyEvent 一次按下或松开。
+type keyEvent struct {
+	Code    uint16
+	Pressed bool
+}
+
+// keyChordEvents 生成组合键的事件序列：依次按下 Ctrl/Alt/Shift，按下并松开主键，再按相反顺序松开修饰键。
+// 与 winapi.SendKeyWithModifiers 的顺序保持一致。
+func keyChordEvents(ctrl, alt, shift bool, vk uint16) ([]keyEvent, error) {
+	key, err := translateVK(vk)
+	if err != nil {
+		return nil, err
+	}
+	var modifiers []uint16
+	if ctrl {
+		modifiers = append(modifiers, linuxKeyLeftCtrl)
+	}
+	if alt {
+		modifiers = append(modifiers, linuxKeyLeftAlt)
+	}
+	if shift {
+		modifiers = append(modifiers, linuxKeyLeftShift)
+	}
+
+	events := make([]keyEvent, 0, len(modifiers)*2+2)
+	for _, m := range modifiers {
+		events = append(events, keyEvent{Code: m, Pressed: true})
+	}
+	events = append(events, keyEvent{Code: key, Pressed: true}, keyEvent{Code: key, Pressed: false})
+	for i := len(modifiers) - 1; i >= 0; i-- {
+		events = append(events, keyEvent{Code: modifiers[i], Pressed: false})
+	}
+	return events, nil
+}
@@ -0,0 +1,18 @@
+package platform
+
+// 虚拟键码，与 Windows VK_* 取值保持一致，其他平台的实现负责自行转换。
+const (
+	VK_SHIFT            = 0x10
+	VK_CONTROL          = 0x11
+	VK_MENU             = 0x12
+	VK_LEFT             = 0x25
+	VK_RIGHT            = 0x27
+	VK_P                = 0x50
+	VK_V                = 0x56
+	VK_VOLUME_MUTE      = 0xAD
+	VK_VOLUME_DOWN      = 0xAE
+	VK_VOLUME_UP        = 0xAF
+	VK_MEDIA_NEXT_TRACK = 0xB0
+	VK_MEDIA_PREV_TRACK = 0xB1
+	VK_MEDIA_PLAY_PAUSE = 0xB3
+)
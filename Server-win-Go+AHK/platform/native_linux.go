@@ -0,0 +1,32 @@
+package platform
+
+import (
+	"log"
+	"os"
+)
+
+// logindBusAddressEnv 可指定连接 logind 使用的 D-Bus 地址（例如私有会话总线上的桩对象），
+// 留空时使用系统总线。
+const logindBusAddressEnv = "BEALINK_LOGIND_BUS_ADDRESS"
+
+// NewNative 返回 Linux 原生后端：电源操作通过 systemd-logind (D-Bus) 完成，
+// 音量通过 pactl 控制 PulseAudio / PipeWire-pulse 的默认输出设备，按键通过 /dev/uinput 虚拟键盘模拟，
+// 通知通过 notify-send 显示。
+func NewNative() *Backend {
+	busAddress := os.Getenv(logindBusAddressEnv)
+	if busAddress != "" {
+		log.Printf("信息: logind 将使用自定义 D-Bus 地址: %s", busAddress)
+	}
+	return &Backend{
+		Name:      "linux",
+		Power:     newLogindPower(busAddress),
+		Audio:     newPulseAudio(),
+		Display:   unsupported{},
+		Input:     newUinputInput(),
+		Clipboard: textClipboard{},
+		AutoStart: unsupported{},
+		Countdown: unsupported{},
+		Notifier:  notifySend{},
+		Confirmer: zenityConfirmer{},
+	}
+}
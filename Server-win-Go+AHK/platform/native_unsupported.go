@@ -0,0 +1,21 @@
+//go:build !windows && !linux
+
+package platform
+
+import "runtime"
+
+// NewNative 返回当前平台的原生后端。此平台尚无原生实现，除文本剪贴板外均返回 ErrUnsupported。
+func NewNative() *Backend {
+	return &Backend{
+		Name:      runtime.GOOS,
+		Power:     unsupported{},
+		Audio:     unsupported{},
+		Display:   unsupported{},
+		Input:     unsupported{},
+		Clipboard: textClipboard{},
+		AutoStart: unsupported{},
+		Countdown: unsupported{},
+		Notifier:  unsupported{},
+		Confirmer: unsupported{},
+	}
+}
@@ -0,0 +1,90 @@
+package platform
+
+import (
+	"errors"
+	"fmt"
+	"os/exec"
+
+	"bealinkserver/ahk"
+	"bealinkserver/winapi"
+)
+
+// NewNative 返回 Windows 原生后端：Win32 API + AutoHotkey。
+func NewNative() *Backend {
+	return &Backend{
+		Name:      "windows",
+		Power:     windowsPower{},
+		Audio:     newAhkAudio(),
+		Display:   windowsDisplay{},
+		Input:     windowsInput{},
+		Clipboard: windowsClipboard{},
+		AutoStart: windowsAutoStart{},
+		Countdown: ahkCountdownView{},
+		Notifier:  ahkNotifier{},
+		Confirmer: ahkConfirmer{},
+	}
+}
+
+// errNoShutdownInProgress shutdown /a 在没有计划中的关机时的退出码 (ERROR_NO_SHUTDOWN_IN_PROGRESS)。
+const errNoShutdownInProgress = 1116
+
+// windowsPower 直接调用系统电源操作（倒计时界面由上层的 AHK 脚本负责）。
+type windowsPower struct{}
+
+func (windowsPower) Sleep() error {
+	if _, err := ahk.RunAhkCode(`Run, rundll32.exe powrprof.dll,SetSuspendState 0,1,0`); err != nil {
+		return fmt.Errorf("睡眠指令失败: %w", err)
+	}
+	return nil
+}
+
+func (windowsPower) Shutdown() error  { return runShutdown("关机", "/s", "/t", "0") }
+func (windowsPower) Restart() error   { return runShutdown("重启", "/r", "/t", "0") }
+func (windowsPower) Hibernate() error { return runShutdown("休眠", "/h") }
+func (windowsPower) SignOut() error   { return runShutdown("注销", "/l") }
+func (windowsPower) Lock() error      { return winapi.LockWorkStation() }
+
+func (windowsPower) AbortShutdown() error {
+	err := runShutdown("取消关机", "/a")
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && exitErr.ExitCode() == errNoShutdownInProgress {
+		return nil
+	}
+	return err
+}
+
+// runShutdown 运行 shutdown.exe，desc 用于错误信息。
+func runShutdown(desc string, args ...string) error {
+	if err := exec.Command("shutdown", args...).Run(); err != nil {
+		return fmt.Errorf("%s指令失败: %w", desc, err)
+	}
+	return nil
+}
+
+type windowsDisplay struct{}
+
+func (windowsDisplay) ToggleMonitorPower() (bool, error)      { return winapi.ToggleMonitorPower() }
+func (windowsDisplay) SetMonitorPower(off bool) (bool, error) { return winapi.SetMonitorPower(off) }
+func (windowsDisplay) IsMonitorOff() bool                     { return winapi.GetCurrentMonitorState() }
+func (windowsDisplay) OnMonitorStateChange(fn func(bool))     { winapi.OnMonitorStateChange(fn) }
+
+type windowsInput struct{}
+
+func (windowsInput) SendKeyPress(vk uint16) error { return winapi.SendKeyPress(vk) }
+func (windowsInput) SendKeyWithModifiers(ctrl, alt, shift bool, vk uint16) error {
+	return winapi.SendKeyWithModifiers(ctrl, alt, shift, vk)
+}
+func (windowsInput) Paste() error { return winapi.Paste() }
+
+// windowsClipboard 文本走 atotto/clipboard，图片通过 PowerShell 以文件形式放入剪贴板。
+type windowsClipboard struct{ textClipboard }
+
+func (windowsClipboard) SetImage(data []byte, filename string) error {
+	return winapi.SetClipboardImage(data, filename)
+}
+
+type windowsAutoStart struct{}
+
+func (windowsAutoStart) IsEnabled() (bool, error) { return winapi.IsAutoStartEnabled() }
+func (windowsAutoStart) Enable() error            { return winapi.EnableAutoStart() }
+func (windowsAutoStart) Disable() error           { return winapi.DisableAutoStart() }
@@ -0,0 +1,19 @@
+package platform
+
+import (
+	"fmt"
+	"os/exec"
+	"strconv"
+	"time"
+)
+
+// notifySend 通过 notify-send (libnotify) 在桌面会话中显示通知，需要可用的通知服务。
+type notifySend struct{}
+
+func (notifySend) Notify(title, message string, duration time.Duration) error {
+	cmd := exec.Command("notify-send", "--app-name=Bealink", "--expire-time="+strconv.FormatInt(duration.Milliseconds(), 10), title, message)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("notify-send 失败: %w (%s)", err, out)
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package platform
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"bealinkserver/ahk"
+)
+
+// ahkNotifier 使用 ahk/script/notify.ahk 在屏幕右下角显示通知窗口。
+// 参数依次为内容、标题和显示秒数。
+type ahkNotifier struct{}
+
+func (ahkNotifier) Notify(title, message string, duration time.Duration) error {
+	seconds := int(duration.Seconds())
+	if seconds < 1 {
+		seconds = 1
+	}
+	proc, err := ahk.RunScriptAndGetProcess("notify.ahk", message, title, strconv.Itoa(seconds))
+	if err != nil {
+		return err
+	}
+	go func() {
+		if _, err := proc.Wait(); err != nil {
+			log.Printf("等待通知脚本退出失败: %v", err)
+		}
+	}()
+	return nil
+}
@@ -0,0 +1,137 @@
+// Package platform 定义 Bealink 依赖的系统能力接口（电源、音频、显示器、输入、剪贴板、开机自启）。
+// 具体实现通过构建标签选择：Windows 使用 Win32 API + AutoHotkey，其余平台逐步补充。
+// server 包只依赖这里的接口，因此可以在任意平台编译和测试。
+package platform
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnsupported 表示当前后端不支持该操作。
+var ErrUnsupported = errors.New("当前平台不支持该操作")
+
+// Power 电源控制（立即执行，不含倒计时）。
+type Power interface {
+	Sleep() error
+	Shutdown() error
+	Restart() error
+	Hibernate() error
+	// Lock 锁定工作站，回到登录界面但不结束会话。
+	Lock() error
+	// SignOut 注销当前用户的会话。
+	SignOut() error
+	// AbortShutdown 取消系统中已计划的关机或重启（Windows 的 shutdown /a），没有计划中的关机时不返回错误。
+	AbortShutdown() error
+}
+
+// Audio 系统主音量控制，音量范围 0-100。
+type Audio interface {
+	GetVolume() (int, error)
+	SetVolume(vol int) error
+	// IsMuted 返回主音量当前是否静音。
+	IsMuted() (bool, error)
+	ToggleMute() error
+}
+
+// Display 显示器电源控制。
+type Display interface {
+	// ToggleMonitorPower 切换显示器电源，返回切换后显示器是否为关闭状态。
+	ToggleMonitorPower() (newStateIsOff bool, err error)
+	// SetMonitorPower 打开 (off=false) 或关闭 (off=true) 显示器，已处于该状态时不做任何操作并返回 changed=false。
+	SetMonitorPower(off bool) (changed bool, err error)
+	// IsMonitorOff 返回显示器当前是否关闭。Windows 上为系统通知的真实状态，
+	// 包括用户移动鼠标或系统自行息屏引起的变化。
+	IsMonitorOff() bool
+	// OnMonitorStateChange 注册显示器状态变化的回调，回调不应阻塞。
+	OnMonitorStateChange(fn func(off bool))
+}
+
+// Input 键盘输入模拟，按键使用 Windows 虚拟键码 (VK_*) 表示。
+type Input interface {
+	SendKeyPress(vk uint16) error
+	SendKeyWithModifiers(ctrl, alt, shift bool, vk uint16) error
+	// Paste 模拟粘贴操作 (Ctrl+V)
+	Paste() error
+}
+
+// Clipboard 系统剪贴板。
+type Clipboard interface {
+	ReadText() (string, error)
+	WriteText(text string) error
+	// SetImage 将图片数据放入剪贴板，filename 为保存临时文件时使用的文件名。
+	SetImage(data []byte, filename string) error
+}
+
+// AutoStart 开机自启设置。
+type AutoStart interface {
+	IsEnabled() (bool, error)
+	Enable() error
+	Disable() error
+}
+
+// Notifier 在本机弹出一条通知（如配对码），不等待用户操作。
+type Notifier interface {
+	// Notify 显示标题为 title 的通知，持续 duration 后自动关闭。
+	Notify(title, message string, duration time.Duration) error
+}
+
+// ConfirmResult 本机确认框的结果。
+type ConfirmResult string
+
+const (
+	ConfirmApproved ConfirmResult = "approved"
+	ConfirmDenied   ConfirmResult = "denied"
+	ConfirmTimeout  ConfirmResult = "timeout"
+)
+
+// Confirmer 在本机弹出允许/拒绝确认框，供需要电脑前的人批准的远程操作使用。
+type Confirmer interface {
+	// Confirm 显示确认框并等待用户选择。超过 timeout 或 ctx 结束时关闭确认框并返回 ConfirmTimeout。
+	Confirm(ctx context.Context, title, message string, timeout time.Duration) (ConfirmResult, error)
+}
+
+// CountdownView 在本机显示电源操作倒计时界面。界面只负责展示，
+// 倒计时的截止时间和到点后的电源操作都由调用方（power.Manager）掌控。
+type CountdownView interface {
+	// ShowCountdown 显示倒计时到 deadline 的界面，reason 非空时一并显示（如发起方填写的原因），
+	// 用户在界面上取消时调用 onCancel，onCancel 可以在 ShowCountdown 返回前同步调用。
+	ShowCountdown(action string, deadline time.Time, reason string, onCancel func()) (CountdownWindow, error)
+}
+
+// CountdownWindow 表示一个正在显示的倒计时界面。
+type CountdownWindow interface {
+	// Close 关闭界面，不会触发 onCancel。
+	Close() error
+}
+
+// Backend 聚合一组平台能力实现，由 main 创建后注入 server。
+type Backend struct {
+	Name      string
+	Power     Power
+	Audio     Audio
+	Display   Display
+	Input     Input
+	Clipboard Clipboard
+	AutoStart AutoStart
+	Countdown CountdownView
+	Notifier  Notifier
+	Confirmer Confirmer
+
+	// Simulator 仅在模拟后端下非 nil，用于查询模拟状态和操作日志。
+	Simulator *Simulator
+}
+
+// New 按名称创建后端："native" 为当前平台的原生实现，"simulated" 为只记录不执行的模拟实现。
+func New(name string) (*Backend, error) {
+	switch name {
+	case "", "native":
+		return NewNative(), nil
+	case "simulated":
+		return NewSimulator().Backend(), nil
+	default:
+		return nil, fmt.Errorf("未知的平台后端: %s (可选: native, simulated)", name)
+	}
+}
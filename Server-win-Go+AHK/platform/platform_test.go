@@ -0,0 +1,66 @@
+package platform
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestNewSelectsBackend(t *testing.T) {
+	tests := []struct {
+		name     string
+		wantName string
+		wantSim  bool
+	}{
+		{name: "", wantName: NewNative().Name},
+		{name: "native", wantName: NewNative().Name},
+		{name: "simulated", wantName: "simulated", wantSim: true},
+	}
+	for _, tt := range tests {
+		b, err := New(tt.name)
+		if err != nil {
+			t.Fatalf("New(%q) 返回错误: %v", tt.name, err)
+		}
+		if b.Name != tt.wantName {
+			t.Errorf("New(%q).Name = %q, 期望 %q", tt.name, b.Name, tt.wantName)
+		}
+		if (b.Simulator != nil) != tt.wantSim {
+			t.Errorf("New(%q).Simulator 非 nil = %t, 期望 %t", tt.name, b.Simulator != nil, tt.wantSim)
+		}
+	}
+}
+
+func TestNewUnknownBackend(t *testing.T) {
+	if _, err := New("bogus"); err == nil {
+		t.Fatal("New(\"bogus\") 应返回错误")
+	}
+}
+
+// TestNativeBackendComplete 确保原生后端的每项能力都有实现（哪怕是 unsupported），server 可以放心调用。
+func TestNativeBackendComplete(t *testing.T) {
+	b := NewNative()
+	if b.Name == "" {
+		t.Error("原生后端缺少名称")
+	}
+	if runtime.GOOS != "windows" && runtime.GOOS != "linux" && b.Name != runtime.GOOS {
+		t.Errorf("Name = %q, 期望 %q", b.Name, runtime.GOOS)
+	}
+	caps := map[string]interface{}{
+		"Power":     b.Power,
+		"Audio":     b.Audio,
+		"Display":   b.Display,
+		"Input":     b.Input,
+		"Clipboard": b.Clipboard,
+		"AutoStart": b.AutoStart,
+		"Countdown": b.Countdown,
+		"Notifier":  b.Notifier,
+		"Confirmer": b.Confirmer,
+	}
+	for name, c := range caps {
+		if c == nil {
+			t.Errorf("原生后端的 %s 为 nil", name)
+		}
+	}
+	if b.Simulator != nil {
+		t.Error("原生后端不应带有 Simulator")
+	}
+}
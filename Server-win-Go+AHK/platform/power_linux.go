@@ -0,0 +1,103 @@
+package platform
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	logindService    = "org.freedesktop.login1"
+	logindObjectPath = dbus.ObjectPath("/org/freedesktop/login1")
+	logindManager    = "org.freedesktop.login1.Manager"
+)
+
+// logindPower 通过 systemd-logind 的 org.freedesktop.login1.Manager 接口执行电源操作。
+// 每次调用前先查询对应的 Can* 方法，只有返回 "yes" 时才真正执行。
+type logindPower struct {
+	busAddress string // 为空时连接系统总线
+}
+
+func newLogindPower(busAddress string) *logindPower {
+	return &logindPower{busAddress: busAddress}
+}
+
+func (p *logindPower) Sleep() error     { return p.call("Suspend", "CanSuspend") }
+func (p *logindPower) Shutdown() error  { return p.call("PowerOff", "CanPowerOff") }
+func (p *logindPower) Restart() error   { return p.call("Reboot", "CanReboot") }
+func (p *logindPower) Hibernate() error { return p.call("Hibernate", "CanHibernate") }
+
+// Lock 锁定所有会话，logind 没有对应的 Can* 方法，权限不足时由 polkit 拒绝。
+func (p *logindPower) Lock() error { return p.invoke("LockSessions") }
+
+// SignOut 结束运行本服务的用户的所有会话。
+func (p *logindPower) SignOut() error { return p.invoke("TerminateUser", uint32(os.Getuid())) }
+
+// AbortShutdown 取消通过 logind 计划的关机（如 shutdown +5）。
+func (p *logindPower) AbortShutdown() error {
+	conn, err := p.connect()
+	if err != nil {
+		return fmt.Errorf("连接 D-Bus 失败: %w", err)
+	}
+	defer conn.Close()
+	var cancelled bool
+	if err := conn.Object(logindService, logindObjectPath).Call(logindManager+".CancelScheduledShutdown", 0).Store(&cancelled); err != nil {
+		return fmt.Errorf("调用 logind CancelScheduledShutdown 失败: %w", err)
+	}
+	if cancelled {
+		log.Printf("已取消 logind 计划的关机")
+	}
+	return nil
+}
+
+// connect 建立到 logind 所在总线的连接，调用方负责关闭。
+func (p *logindPower) connect() (*dbus.Conn, error) {
+	if p.busAddress == "" {
+		return dbus.ConnectSystemBus()
+	}
+	return dbus.Connect(p.busAddress)
+}
+
+func (p *logindPower) call(method, canMethod string) error {
+	conn, err := p.connect()
+	if err != nil {
+		return fmt.Errorf("连接 D-Bus 失败: %w", err)
+	}
+	defer conn.Close()
+	obj := conn.Object(logindService, logindObjectPath)
+
+	var answer string
+	if err := obj.Call(logindManager+"."+canMethod, 0).Store(&answer); err != nil {
+		return fmt.Errorf("查询 logind %s 失败: %w", canMethod, err)
+	}
+	// 可能的返回值: yes / no / challenge (需要交互授权) / na (不支持)
+	if answer != "yes" {
+		if answer == "na" {
+			return fmt.Errorf("logind %s 返回 %q: %w", canMethod, answer, ErrUnsupported)
+		}
+		return fmt.Errorf("logind %s 返回 %q，当前用户无权执行 %s", canMethod, answer, method)
+	}
+
+	// 参数 interactive=false：后台服务不应弹出授权对话框
+	return p.invokeOn(conn, method, false)
+}
+
+// invoke 不经 Can* 检查直接调用 logind 的 method。
+func (p *logindPower) invoke(method string, args ...interface{}) error {
+	conn, err := p.connect()
+	if err != nil {
+		return fmt.Errorf("连接 D-Bus 失败: %w", err)
+	}
+	defer conn.Close()
+	return p.invokeOn(conn, method, args...)
+}
+
+func (p *logindPower) invokeOn(conn *dbus.Conn, method string, args ...interface{}) error {
+	log.Printf("调用 logind %s...", method)
+	if err := conn.Object(logindService, logindObjectPath).Call(logindManager+"."+method, 0, args...).Err; err != nil {
+		return fmt.Errorf("调用 logind %s 失败: %w", method, err)
+	}
+	return nil
+}
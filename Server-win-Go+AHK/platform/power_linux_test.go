@@ -0,0 +1,193 @@
+package platform
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/godbus/dbus/v5"
+)
+
+// stubLogin1 是挂在私有总线上的 org.freedesktop.login1.Manager 桩对象，
+// Can* 方法返回 answers 中配置的值，其余方法只记录调用。
+type stubLogin1 struct {
+	mu        sync.Mutex
+	answers   map[string]string
+	calls     []string
+	cancelled bool // CancelScheduledShutdown 的返回值
+}
+
+func (s *stubLogin1) can(method string) (string, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, method)
+	return s.answers[method], nil
+}
+
+func (s *stubLogin1) record(method string, interactive bool) *dbus.Error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, fmt.Sprintf("%s(%t)", method, interactive))
+	return nil
+}
+
+func (s *stubLogin1) CanSuspend() (string, *dbus.Error)   { return s.can("CanSuspend") }
+func (s *stubLogin1) CanPowerOff() (string, *dbus.Error)  { return s.can("CanPowerOff") }
+func (s *stubLogin1) CanReboot() (string, *dbus.Error)    { return s.can("CanReboot") }
+func (s *stubLogin1) CanHibernate() (string, *dbus.Error) { return s.can("CanHibernate") }
+
+func (s *stubLogin1) Suspend(interactive bool) *dbus.Error  { return s.record("Suspend", interactive) }
+func (s *stubLogin1) PowerOff(interactive bool) *dbus.Error { return s.record("PowerOff", interactive) }
+func (s *stubLogin1) Reboot(interactive bool) *dbus.Error   { return s.record("Reboot", interactive) }
+func (s *stubLogin1) Hibernate(interactive bool) *dbus.Error {
+	return s.record("Hibernate", interactive)
+}
+
+func (s *stubLogin1) CancelScheduledShutdown() (bool, *dbus.Error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls = append(s.calls, "CancelScheduledShutdown")
+	return s.cancelled, nil
+}
+
+func (s *stubLogin1) Calls() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.calls...)
+}
+
+const privateBusConfig = `<!DOCTYPE busconfig PUBLIC "-//freedesktop//DTD D-Bus Bus Configuration 1.0//EN"
+ "http://www.freedesktop.org/standards/dbus/1.0/busconfig.dtd">
+<busconfig>
+  <type>session</type>
+  <listen>unix:path=%s</listen>
+  <auth>EXTERNAL</auth>
+  <policy context="default">
+    <allow send_destination="*" eavesdrop="true"/>
+    <allow eavesdrop="true"/>
+    <allow own="*"/>
+  </policy>
+</busconfig>
+`
+
+// startPrivateBus 启动一个私有 dbus-daemon 并返回其地址，测试结束时关闭。没有 dbus-daemon 时跳过测试。
+func startPrivateBus(t *testing.T) string {
+	t.Helper()
+	daemon, err := exec.LookPath("dbus-daemon")
+	if err != nil {
+		t.Skip("未找到 dbus-daemon，跳过 logind 桩测试")
+	}
+	dir := t.TempDir()
+	config := filepath.Join(dir, "bus.conf")
+	if err := os.WriteFile(config, []byte(fmt.Sprintf(privateBusConfig, filepath.Join(dir, "bus"))), 0600); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(daemon, "--config-file="+config, "--nofork", "--print-address")
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("启动 dbus-daemon 失败: %v", err)
+	}
+	t.Cleanup(func() {
+		cmd.Process.Kill()
+		cmd.Wait()
+	})
+	address, err := bufio.NewReader(stdout).ReadString('\n')
+	if err != nil {
+		t.Fatalf("读取 dbus-daemon 地址失败: %v", err)
+	}
+	return strings.TrimSpace(address)
+}
+
+// startStubLogin1 在私有总线上注册桩 login1 服务，返回桩对象和指向该总线的 logindPower。
+func startStubLogin1(t *testing.T, answers map[string]string) (*stubLogin1, *logindPower) {
+	t.Helper()
+	address := startPrivateBus(t)
+	conn, err := dbus.Connect(address)
+	if err != nil {
+		t.Fatalf("连接私有总线失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	stub := &stubLogin1{answers: answers}
+	if err := conn.Export(stub, logindObjectPath, logindManager); err != nil {
+		t.Fatal(err)
+	}
+	reply, err := conn.RequestName(logindService, dbus.NameFlagDoNotQueue)
+	if err != nil || reply != dbus.RequestNameReplyPrimaryOwner {
+		t.Fatalf("注册 %s 失败: reply=%v err=%v", logindService, reply, err)
+	}
+	return stub, newLogindPower(address)
+}
+
+func TestLogindPowerCanAnswers(t *testing.T) {
+	stub, p := startStubLogin1(t, map[string]string{
+		"CanSuspend":   "yes",
+		"CanPowerOff":  "no",
+		"CanReboot":    "challenge",
+		"CanHibernate": "na",
+	})
+
+	if err := p.Sleep(); err != nil {
+		t.Errorf("CanSuspend=yes 时 Sleep 返回错误: %v", err)
+	}
+	if err := p.Shutdown(); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("CanPowerOff=no 时应返回权限错误，实际: %v", err)
+	}
+	if err := p.Restart(); err == nil || errors.Is(err, ErrUnsupported) {
+		t.Errorf("CanReboot=challenge 时应返回权限错误，实际: %v", err)
+	}
+	if err := p.Hibernate(); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("CanHibernate=na 时应返回 ErrUnsupported，实际: %v", err)
+	}
+
+	// 只有 yes 才真正执行，且不允许弹出交互授权
+	want := []string{"CanSuspend", "Suspend(false)", "CanPowerOff", "CanReboot", "CanHibernate"}
+	if got := stub.Calls(); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("logind 调用序列 = %v, 期望 %v", got, want)
+	}
+}
+
+func TestLogindPowerAbortShutdown(t *testing.T) {
+	for _, cancelled := range []bool{true, false} {
+		stub, p := startStubLogin1(t, nil)
+		stub.mu.Lock()
+		stub.cancelled = cancelled
+		stub.mu.Unlock()
+		if err := p.AbortShutdown(); err != nil {
+			t.Errorf("CancelScheduledShutdown=%t 时 AbortShutdown 返回错误: %v", cancelled, err)
+		}
+		if got := stub.Calls(); len(got) != 1 || got[0] != "CancelScheduledShutdown" {
+			t.Errorf("logind 调用序列 = %v", got)
+		}
+	}
+}
+
+func TestLogindPowerNoService(t *testing.T) {
+	p := newLogindPower(startPrivateBus(t))
+	if err := p.Sleep(); err == nil {
+		t.Error("总线上没有 login1 时 Sleep 应返回错误")
+	}
+	if err := p.AbortShutdown(); err == nil {
+		t.Error("总线上没有 login1 时 AbortShutdown 应返回错误")
+	}
+}
+
+func TestNativeUsesLogindBusAddressEnv(t *testing.T) {
+	t.Setenv(logindBusAddressEnv, "unix:path=/tmp/bealink-test-bus")
+	p, ok := NewNative().Power.(*logindPower)
+	if !ok {
+		t.Fatalf("Linux 原生后端的 Power 应为 *logindPower")
+	}
+	if p.busAddress != "unix:path=/tmp/bealink-test-bus" {
+		t.Errorf("busAddress = %q", p.busAddress)
+	}
+}
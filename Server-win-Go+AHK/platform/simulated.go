@@ -0,0 +1,410 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+)
+
+const (
+	simulatedJournalSize       = 500 // 操作日志最多保留的条数
+	simulatedVolumeStep        = 2   // 音量键每次调整的幅度，与 Windows 默认步进一致
+	simulatedInitialVolume     = 50
+	simulatedJournalTimeFormat = "2006-01-02 15:04:05.000"
+)
+
+// JournalEntry 模拟后端记录的一次调用。
+type JournalEntry struct {
+	Time       time.Time `json:"time"`
+	Capability string    `json:"capability"` // power / audio / display / input / clipboard / autostart / countdown / notify / confirm
+	Action     string    `json:"action"`
+	Detail     string    `json:"detail,omitempty"`
+}
+
+func (e JournalEntry) String() string {
+	s := fmt.Sprintf("%s %s.%s", e.Time.Format(simulatedJournalTimeFormat), e.Capability, e.Action)
+	if e.Detail != "" {
+		s += " " + e.Detail
+	}
+	return s
+}
+
+// SimulatorState 模拟后端的当前状态快照。
+type SimulatorState struct {
+	Volume          int       `json:"volume"`
+	Muted           bool      `json:"muted"`
+	MonitorOff      bool      `json:"monitorOff"`
+	Clipboard       string    `json:"clipboard"`
+	AutoStart       bool      `json:"autoStart"`
+	LastPowerAction string    `json:"lastPowerAction,omitempty"`
+	LastPowerTime   time.Time `json:"lastPowerTime"`
+}
+
+// Simulator 是一个只记录、不执行的内存后端，实现 platform 包的全部能力接口。
+// 演示和开发时使用，避免误操作真实机器；也可在任何平台上跑通移动端 UI。
+type Simulator struct {
+	mu       sync.Mutex
+	state    SimulatorState
+	journal  []JournalEntry
+	windows  map[string]*simulatedCountdownWindow // 正在显示的倒计时窗口，按 action 索引
+	confirms []chan ConfirmResult                 // 正在显示的确认框，按弹出顺序排列
+	// monitorListeners 显示器状态变化的回调，见 OnMonitorStateChange。
+	monitorListeners []func(off bool)
+}
+
+// NewSimulator 创建一个处于初始状态的模拟后端。
+func NewSimulator() *Simulator {
+	return &Simulator{state: SimulatorState{Volume: simulatedInitialVolume}}
+}
+
+// Backend 返回所有能力都指向该模拟器的 Backend。
+func (s *Simulator) Backend() *Backend {
+	return &Backend{
+		Name:      "simulated",
+		Power:     s,
+		Audio:     s,
+		Display:   s,
+		Input:     s,
+		Clipboard: s,
+		AutoStart: s,
+		Countdown: s,
+		Notifier:  s,
+		Confirmer: s,
+		Simulator: s,
+	}
+}
+
+// State 返回当前模拟状态。
+func (s *Simulator) State() SimulatorState {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state
+}
+
+// Journal 返回操作日志（旧 -> 新）。
+func (s *Simulator) Journal() []JournalEntry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := make([]JournalEntry, len(s.journal))
+	copy(result, s.journal)
+	return result
+}
+
+// ClearJournal 清空操作日志，状态保持不变。
+func (s *Simulator) ClearJournal() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.journal = nil
+}
+
+// record 追加一条操作日志，调用方需持有 s.mu。
+func (s *Simulator) record(capability, action, detail string) {
+	entry := JournalEntry{Time: time.Now(), Capability: capability, Action: action, Detail: detail}
+	if len(s.journal) >= simulatedJournalSize {
+		s.journal = s.journal[1:]
+	}
+	s.journal = append(s.journal, entry)
+	log.Printf("[Simulator] %s", entry)
+}
+
+// ---- Power ----
+
+func (s *Simulator) Sleep() error     { return s.powerAction("sleep") }
+func (s *Simulator) Shutdown() error  { return s.powerAction("shutdown") }
+func (s *Simulator) Restart() error   { return s.powerAction("restart") }
+func (s *Simulator) Hibernate() error { return s.powerAction("hibernate") }
+func (s *Simulator) Lock() error      { return s.powerAction("lock") }
+func (s *Simulator) SignOut() error   { return s.powerAction("signout") }
+
+// AbortShutdown 模拟器中没有系统计划的关机，只记录调用。
+func (s *Simulator) AbortShutdown() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("power", "abort", "")
+	return nil
+}
+
+func (s *Simulator) powerAction(action string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.LastPowerAction = action
+	s.state.LastPowerTime = time.Now()
+	s.record("power", action, "")
+	return nil
+}
+
+// ---- Audio ----
+
+func (s *Simulator) GetVolume() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Volume, nil
+}
+
+func (s *Simulator) SetVolume(vol int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Volume = clampVolume(vol)
+	s.record("audio", "set_volume", fmt.Sprintf("volume=%d", s.state.Volume))
+	return nil
+}
+
+func (s *Simulator) IsMuted() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Muted, nil
+}
+
+func (s *Simulator) ToggleMute() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Muted = !s.state.Muted
+	s.record("audio", "toggle_mute", fmt.Sprintf("muted=%t", s.state.Muted))
+	return nil
+}
+
+// ---- Display ----
+
+func (s *Simulator) ToggleMonitorPower() (bool, error) {
+	s.mu.Lock()
+	off := !s.state.MonitorOff
+	s.record("display", "toggle_monitor", fmt.Sprintf("off=%t", off))
+	s.mu.Unlock()
+	s.setMonitorState(off)
+	return off, nil
+}
+
+func (s *Simulator) SetMonitorPower(off bool) (bool, error) {
+	s.mu.Lock()
+	changed := s.state.MonitorOff != off
+	s.record("display", "set_monitor", fmt.Sprintf("off=%t changed=%t", off, changed))
+	s.mu.Unlock()
+	s.setMonitorState(off)
+	return changed, nil
+}
+
+func (s *Simulator) OnMonitorStateChange(fn func(off bool)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.monitorListeners = append(s.monitorListeners, fn)
+}
+
+// SimulateMonitorState 模拟系统自行改变显示器状态（如用户移动鼠标唤醒、超时自动息屏），
+// 对应 Windows 上的 GUID_CONSOLE_DISPLAY_STATE 通知。
+func (s *Simulator) SimulateMonitorState(off bool) {
+	s.mu.Lock()
+	s.record("display", "system_change", fmt.Sprintf("off=%t", off))
+	s.mu.Unlock()
+	s.setMonitorState(off)
+}
+
+// setMonitorState 更新显示器状态，状态变化时在锁外调用回调。
+func (s *Simulator) setMonitorState(off bool) {
+	s.mu.Lock()
+	if s.state.MonitorOff == off {
+		s.mu.Unlock()
+		return
+	}
+	s.state.MonitorOff = off
+	listeners := append([]func(bool){}, s.monitorListeners...)
+	s.mu.Unlock()
+	for _, fn := range listeners {
+		fn(off)
+	}
+}
+
+func (s *Simulator) IsMonitorOff() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.MonitorOff
+}
+
+// ---- Input ----
+
+func (s *Simulator) SendKeyPress(vk uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// 音量键同时影响模拟音量状态
+	switch vk {
+	case VK_VOLUME_UP:
+		s.state.Volume = clampVolume(s.state.Volume + simulatedVolumeStep)
+	case VK_VOLUME_DOWN:
+		s.state.Volume = clampVolume(s.state.Volume - simulatedVolumeStep)
+	case VK_VOLUME_MUTE:
+		s.state.Muted = !s.state.Muted
+	}
+	s.record("input", "key_press", fmt.Sprintf("vk=0x%02X", vk))
+	return nil
+}
+
+func (s *Simulator) SendKeyWithModifiers(ctrl, alt, shift bool, vk uint16) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("input", "key_chord", fmt.Sprintf("ctrl=%t alt=%t shift=%t vk=0x%02X", ctrl, alt, shift, vk))
+	return nil
+}
+
+func (s *Simulator) Paste() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("input", "paste", fmt.Sprintf("clipboard=%d bytes", len(s.state.Clipboard)))
+	return nil
+}
+
+// ---- Clipboard ----
+
+func (s *Simulator) ReadText() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.Clipboard, nil
+}
+
+func (s *Simulator) WriteText(text string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.Clipboard = text
+	s.record("clipboard", "write_text", fmt.Sprintf("%d bytes", len(text)))
+	return nil
+}
+
+func (s *Simulator) SetImage(data []byte, filename string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("clipboard", "set_image", fmt.Sprintf("%s (%d bytes)", filename, len(data)))
+	return nil
+}
+
+// ---- AutoStart ----
+
+func (s *Simulator) IsEnabled() (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.state.AutoStart, nil
+}
+
+func (s *Simulator) Enable() error  { return s.setAutoStart(true) }
+func (s *Simulator) Disable() error { return s.setAutoStart(false) }
+
+func (s *Simulator) setAutoStart(enabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state.AutoStart = enabled
+	s.record("autostart", "set", fmt.Sprintf("enabled=%t", enabled))
+	return nil
+}
+
+// ---- Countdown ----
+
+// ShowCountdown 只记录界面的显示与关闭，倒计时本身由调用方负责。
+func (s *Simulator) ShowCountdown(action string, deadline time.Time, reason string, onCancel func()) (CountdownWindow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	w := &simulatedCountdownWindow{sim: s, action: action, onCancel: onCancel}
+	if s.windows == nil {
+		s.windows = make(map[string]*simulatedCountdownWindow)
+	}
+	s.windows[action] = w
+	s.record("countdown", "show", fmt.Sprintf("action=%s deadline=%s reason=%q", action, deadline.Format(simulatedJournalTimeFormat), reason))
+	return w, nil
+}
+
+// ClickCancel 模拟用户点击本机倒计时窗口取消 action 的倒计时，没有对应窗口时返回 false。
+func (s *Simulator) ClickCancel(action string) bool {
+	s.mu.Lock()
+	w, ok := s.windows[action]
+	if ok {
+		delete(s.windows, action)
+		s.record("countdown", "click_cancel", "action="+action)
+	}
+	s.mu.Unlock()
+	if !ok {
+		return false
+	}
+	w.onCancel()
+	return true
+}
+
+// simulatedCountdownWindow 是模拟的倒计时窗口。
+type simulatedCountdownWindow struct {
+	sim      *Simulator
+	action   string
+	onCancel func()
+}
+
+func (w *simulatedCountdownWindow) Close() error {
+	w.sim.mu.Lock()
+	defer w.sim.mu.Unlock()
+	if w.sim.windows[w.action] != w {
+		return nil
+	}
+	delete(w.sim.windows, w.action)
+	w.sim.record("countdown", "close", "action="+w.action)
+	return nil
+}
+
+// ---- Notifier ----
+
+// Notify 只记录通知内容，便于在操作日志中查看（例如配对码）。
+func (s *Simulator) Notify(title, message string, duration time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.record("notify", "show", fmt.Sprintf("title=%q message=%q duration=%s", title, message, duration))
+	return nil
+}
+
+// ---- Confirmer ----
+
+// Confirm 记录确认框并等待 AnswerConfirm 作答，超时或 ctx 结束时返回 ConfirmTimeout。
+func (s *Simulator) Confirm(ctx context.Context, title, message string, timeout time.Duration) (ConfirmResult, error) {
+	answer := make(chan ConfirmResult, 1)
+	s.mu.Lock()
+	s.confirms = append(s.confirms, answer)
+	s.record("confirm", "show", fmt.Sprintf("title=%q message=%q timeout=%s", title, message, timeout))
+	s.mu.Unlock()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case result := <-answer:
+		return result, nil
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.confirms {
+		if c == answer {
+			s.confirms = append(s.confirms[:i], s.confirms[i+1:]...)
+			s.record("confirm", "timeout", "")
+			return ConfirmTimeout, nil
+		}
+	}
+	// 超时的同时已被作答
+	return <-answer, nil
+}
+
+// AnswerConfirm 模拟本机用户在最早弹出的确认框上作答，没有确认框时返回 false。
+func (s *Simulator) AnswerConfirm(result ConfirmResult) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.confirms) == 0 {
+		return false
+	}
+	answer := s.confirms[0]
+	s.confirms = s.confirms[1:]
+	s.record("confirm", "answer", "result="+string(result))
+	answer <- result
+	return true
+}
+
+func clampVolume(vol int) int {
+	if vol < 0 {
+		return 0
+	}
+	if vol > 100 {
+		return 100
+	}
+	return vol
+}
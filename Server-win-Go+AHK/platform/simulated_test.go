@@ -0,0 +1,197 @@
+package platform
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestSimulatorInitialState(t *testing.T) {
+	s := NewSimulator()
+	st := s.State()
+	if st.Volume != simulatedInitialVolume || st.Muted || st.MonitorOff || st.AutoStart || st.LastPowerAction != "" {
+		t.Errorf("初始状态异常: %+v", st)
+	}
+	if len(s.Journal()) != 0 {
+		t.Errorf("初始操作日志应为空，实际 %d 条", len(s.Journal()))
+	}
+}
+
+func TestSimulatorAudio(t *testing.T) {
+	s := NewSimulator()
+	for _, tt := range []struct{ set, want int }{{30, 30}, {-5, 0}, {150, 100}} {
+		if err := s.SetVolume(tt.set); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := s.GetVolume(); got != tt.want {
+			t.Errorf("SetVolume(%d) 后音量 = %d, 期望 %d", tt.set, got, tt.want)
+		}
+	}
+
+	s.SetVolume(50)
+	s.SendKeyPress(VK_VOLUME_UP)
+	if got, _ := s.GetVolume(); got != 50+simulatedVolumeStep {
+		t.Errorf("音量加键后音量 = %d", got)
+	}
+	s.SendKeyPress(VK_VOLUME_DOWN)
+	s.SendKeyPress(VK_VOLUME_DOWN)
+	if got, _ := s.GetVolume(); got != 50-simulatedVolumeStep {
+		t.Errorf("音量减键后音量 = %d", got)
+	}
+
+	s.ToggleMute()
+	if muted, _ := s.IsMuted(); !muted {
+		t.Error("ToggleMute 后应为静音")
+	}
+	s.SendKeyPress(VK_VOLUME_MUTE)
+	if muted, _ := s.IsMuted(); muted {
+		t.Error("静音键后应取消静音")
+	}
+}
+
+func TestSimulatorPowerAndJournal(t *testing.T) {
+	s := NewSimulator()
+	actions := []struct {
+		name string
+		fn   func() error
+	}{
+		{"sleep", s.Sleep}, {"shutdown", s.Shutdown}, {"restart", s.Restart},
+		{"hibernate", s.Hibernate}, {"lock", s.Lock}, {"signout", s.SignOut},
+	}
+	for _, a := range actions {
+		if err := a.fn(); err != nil {
+			t.Fatalf("%s 返回错误: %v", a.name, err)
+		}
+		if got := s.State().LastPowerAction; got != a.name {
+			t.Errorf("LastPowerAction = %q, 期望 %q", got, a.name)
+		}
+	}
+	journal := s.Journal()
+	if len(journal) != len(actions) {
+		t.Fatalf("操作日志 %d 条, 期望 %d", len(journal), len(actions))
+	}
+	for i, a := range actions {
+		if journal[i].Capability != "power" || journal[i].Action != a.name {
+			t.Errorf("日志[%d] = %s.%s, 期望 power.%s", i, journal[i].Capability, journal[i].Action, a.name)
+		}
+	}
+
+	s.ClearJournal()
+	if len(s.Journal()) != 0 {
+		t.Error("ClearJournal 后日志应为空")
+	}
+	if s.State().LastPowerAction != "signout" {
+		t.Error("ClearJournal 不应改变状态")
+	}
+}
+
+func TestSimulatorJournalBounded(t *testing.T) {
+	s := NewSimulator()
+	for i := 0; i < simulatedJournalSize+10; i++ {
+		s.Lock()
+	}
+	if n := len(s.Journal()); n != simulatedJournalSize {
+		t.Errorf("日志条数 = %d, 期望上限 %d", n, simulatedJournalSize)
+	}
+}
+
+func TestSimulatorClipboardAndAutoStart(t *testing.T) {
+	s := NewSimulator()
+	s.WriteText("你好")
+	if text, _ := s.ReadText(); text != "你好" {
+		t.Errorf("ReadText = %q", text)
+	}
+	s.Enable()
+	if on, _ := s.IsEnabled(); !on {
+		t.Error("Enable 后应已开启自启")
+	}
+	s.Disable()
+	if on, _ := s.IsEnabled(); on {
+		t.Error("Disable 后应已关闭自启")
+	}
+}
+
+func TestSimulatorMonitorState(t *testing.T) {
+	s := NewSimulator()
+	var changes []bool
+	s.OnMonitorStateChange(func(off bool) { changes = append(changes, off) })
+
+	if changed, _ := s.SetMonitorPower(false); changed {
+		t.Error("显示器已开启时 SetMonitorPower(false) 应返回 changed=false")
+	}
+	if changed, _ := s.SetMonitorPower(true); !changed || !s.IsMonitorOff() {
+		t.Error("SetMonitorPower(true) 应关闭显示器")
+	}
+	if changed, _ := s.SetMonitorPower(true); changed {
+		t.Error("重复关闭应返回 changed=false")
+	}
+	if off, _ := s.ToggleMonitorPower(); off {
+		t.Error("ToggleMonitorPower 应重新打开显示器")
+	}
+	s.SimulateMonitorState(true)
+	if !s.IsMonitorOff() {
+		t.Error("SimulateMonitorState(true) 后显示器应为关闭")
+	}
+
+	want := []bool{true, false, true}
+	if len(changes) != len(want) {
+		t.Fatalf("回调 %v, 期望 %v", changes, want)
+	}
+	for i := range want {
+		if changes[i] != want[i] {
+			t.Fatalf("回调 %v, 期望 %v", changes, want)
+		}
+	}
+}
+
+func TestSimulatorCountdownWindow(t *testing.T) {
+	s := NewSimulator()
+	cancelled := 0
+	w, err := s.ShowCountdown("sleep", time.Now().Add(time.Minute), "", func() { cancelled++ })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !s.ClickCancel("sleep") || cancelled != 1 {
+		t.Errorf("ClickCancel 应触发 onCancel，实际 %d 次", cancelled)
+	}
+	if s.ClickCancel("sleep") {
+		t.Error("窗口已取消，不应再次触发")
+	}
+	w.Close()
+
+	w, _ = s.ShowCountdown("shutdown", time.Now().Add(time.Minute), "", func() { cancelled++ })
+	w.Close()
+	if s.ClickCancel("shutdown") || cancelled != 1 {
+		t.Error("Close 后的窗口不应再触发 onCancel")
+	}
+}
+
+func TestSimulatorConfirm(t *testing.T) {
+	s := NewSimulator()
+	if s.AnswerConfirm(ConfirmApproved) {
+		t.Error("没有确认框时 AnswerConfirm 应返回 false")
+	}
+
+	done := make(chan ConfirmResult)
+	go func() {
+		result, _ := s.Confirm(context.Background(), "t", "m", time.Minute)
+		done <- result
+	}()
+	deadline := time.Now().Add(time.Second)
+	for !s.AnswerConfirm(ConfirmDenied) {
+		if time.Now().After(deadline) {
+			t.Fatal("确认框没有弹出")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	if result := <-done; result != ConfirmDenied {
+		t.Errorf("Confirm = %s, 期望 %s", result, ConfirmDenied)
+	}
+
+	if result, _ := s.Confirm(context.Background(), "t", "m", 10*time.Millisecond); result != ConfirmTimeout {
+		t.Errorf("超时后 Confirm = %s, 期望 %s", result, ConfirmTimeout)
+	}
+	if s.AnswerConfirm(ConfirmApproved) {
+		t.Error("超时的确认框应已移除")
+	}
+}
@@ -0,0 +1,48 @@
+package platform
+
+import (
+	"context"
+	"time"
+)
+
+// unsupported 是所有能力接口的空实现，每个操作都返回 ErrUnsupported。
+// 用于尚未实现对应能力的平台，保证 server 仍可正常启动。
+type unsupported struct{}
+
+func (unsupported) Sleep() error         { return ErrUnsupported }
+func (unsupported) Shutdown() error      { return ErrUnsupported }
+func (unsupported) Restart() error       { return ErrUnsupported }
+func (unsupported) Hibernate() error     { return ErrUnsupported }
+func (unsupported) Lock() error          { return ErrUnsupported }
+func (unsupported) SignOut() error       { return ErrUnsupported }
+func (unsupported) AbortShutdown() error { return ErrUnsupported }
+
+func (unsupported) GetVolume() (int, error) { return 0, ErrUnsupported }
+func (unsupported) SetVolume(vol int) error { return ErrUnsupported }
+func (unsupported) IsMuted() (bool, error)  { return false, ErrUnsupported }
+func (unsupported) ToggleMute() error       { return ErrUnsupported }
+
+func (unsupported) ToggleMonitorPower() (bool, error)      { return false, ErrUnsupported }
+func (unsupported) SetMonitorPower(off bool) (bool, error) { return false, ErrUnsupported }
+func (unsupported) IsMonitorOff() bool                     { return false }
+func (unsupported) OnMonitorStateChange(fn func(bool))     {}
+
+func (unsupported) SendKeyPress(vk uint16) error { return ErrUnsupported }
+func (unsupported) SendKeyWithModifiers(ctrl, alt, shift bool, vk uint16) error {
+	return ErrUnsupported
+}
+func (unsupported) Paste() error { return ErrUnsupported }
+
+func (unsupported) IsEnabled() (bool, error) { return false, ErrUnsupported }
+func (unsupported) Enable() error            { return ErrUnsupported }
+func (unsupported) Disable() error           { return ErrUnsupported }
+
+func (unsupported) ShowCountdown(action string, deadline time.Time, reason string, onCancel func()) (CountdownWindow, error) {
+	return nil, ErrUnsupported
+}
+
+func (unsupported) Notify(title, message string, duration time.Duration) error { return ErrUnsupported }
+
+func (unsupported) Confirm(ctx context.Context, title, message string, timeout time.Duration) (ConfirmResult, error) {
+	return "", ErrUnsupported
+}
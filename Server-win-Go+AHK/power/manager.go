@@ -0,0 +1,429 @@
+// Package power 管理睡眠/关机/重启/锁定等电源操作。
+// 截止时间和到点后的执行都由 Go 掌控，本机倒计时窗口（platform.CountdownView）只是状态的展示，
+// 因此无论从手机、另一台手机还是本机窗口取消，看到的都是同一个倒计时。
+package power
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"bealinkserver/platform"
+)
+
+// 电源操作的状态。Manager 同时只处理一个操作：idle 表示没有操作，
+// cancelled / failed 表示上一个操作被取消或执行失败，此时可以启动新的操作。
+const (
+	StateIdle         = "idle"
+	StateCountingDown = "counting_down"
+	StateExecuting    = "executing"
+	StateCancelled    = "cancelled"
+	StateFailed       = "failed"
+)
+
+// Actions 支持倒计时的电源操作。
+var Actions = []string{"sleep", "shutdown", "restart", "hibernate", "lock", "signout"}
+
+var (
+	// ErrUnknownAction 不支持的电源操作。
+	ErrUnknownAction = errors.New("未知的电源操作")
+	// ErrBusy 已有其他电源操作在倒计时或执行中。
+	ErrBusy = errors.New("已有进行中的电源操作")
+	// ErrNotPending 指定 ID 的操作不存在或已不在倒计时中。
+	ErrNotPending = errors.New("没有该 ID 的待执行电源操作")
+)
+
+// Action 一个电源操作的状态快照。
+type Action struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"` // Actions 之一
+	State     string    `json:"state"`
+	Source    string    `json:"source,omitempty"` // 发起方
+	Reason    string    `json:"reason,omitempty"` // 发起方填写的原因，显示在本机倒计时界面上
+	Deadline  time.Time `json:"deadline"`
+	Duration  int       `json:"duration"`  // 倒计时总时长（秒）
+	Remaining float64   `json:"remaining"` // 剩余时间（秒），倒计时结束后为 0
+	Error     string    `json:"error,omitempty"`
+}
+
+// Request 启动电源操作的请求。Seconds 为 0 时使用该操作的默认倒计时时长，取值范围由调用方校验。
+type Request struct {
+	Action  string
+	Seconds int
+	Reason  string
+	Source  string
+}
+
+// Status 电源操作管理器的状态：State 为 idle 时 Action 为空，cancelled / failed 时 Action 为上一个操作。
+type Status struct {
+	State  string  `json:"state"`
+	Action *Action `json:"action,omitempty"`
+}
+
+// action 管理器内部的操作记录。
+type action struct {
+	id       string
+	name     string
+	source   string
+	reason   string
+	state    string
+	deadline time.Time
+	duration int
+	err      error
+	timer    *time.Timer
+	window   platform.CountdownWindow // 本机倒计时界面，不可用时为 nil
+}
+
+func (a *action) snapshot(now time.Time) Action {
+	remaining := a.deadline.Sub(now).Seconds()
+	if remaining < 0 || a.state != StateCountingDown {
+		remaining = 0
+	}
+	snap := Action{ID: a.id, Action: a.name, State: a.state, Source: a.source, Reason: a.reason,
+		Deadline: a.deadline, Duration: a.duration, Remaining: remaining}
+	if a.err != nil {
+		snap.Error = a.err.Error()
+	}
+	return snap
+}
+
+// Manager 电源操作管理器 (PowerActionManager)。同时只允许一个电源操作处于倒计时或执行中，
+// 每个操作有唯一 ID，启动和取消都是显式的，重复发送的启动请求不会取消已有的倒计时。
+// 调用 view 显示或关闭倒计时界面时不持有 mu，界面实现可以同步调用 onCancel。
+type Manager struct {
+	power platform.Power
+	view  platform.CountdownView
+	// defaultSeconds 返回操作的默认倒计时时长（秒）。
+	defaultSeconds func(action string) int
+
+	mu      sync.Mutex
+	current *action // 倒计时或执行中的操作
+	last    *action // 最近一个已结束的操作
+}
+
+// NewManager 创建管理器，到点后通过 power 执行操作，并通过 view 显示本机倒计时界面。
+// 请求未指定倒计时时长时使用 defaultSeconds 的返回值。
+func NewManager(power platform.Power, view platform.CountdownView, defaultSeconds func(action string) int) *Manager {
+	return &Manager{power: power, view: view, defaultSeconds: defaultSeconds}
+}
+
+// actionName 返回 action 的中文名称，用于日志。
+func actionName(action string) string {
+	switch action {
+	case "sleep":
+		return "睡眠"
+	case "shutdown":
+		return "关机"
+	case "restart":
+		return "重启"
+	case "hibernate":
+		return "休眠"
+	case "lock":
+		return "锁定"
+	case "signout":
+		return "注销"
+	}
+	return action
+}
+
+// run 返回 action 对应的电源操作。
+func (m *Manager) run(action string) (func() error, error) {
+	switch action {
+	case "sleep":
+		return m.power.Sleep, nil
+	case "shutdown":
+		return m.power.Shutdown, nil
+	case "restart":
+		return m.power.Restart, nil
+	case "hibernate":
+		return m.power.Hibernate, nil
+	case "lock":
+		return m.power.Lock, nil
+	case "signout":
+		return m.power.SignOut, nil
+	}
+	return nil, fmt.Errorf("%w: %s", ErrUnknownAction, action)
+}
+
+// actionSeq 系统随机数不可用时用于生成操作 ID 的序号。
+var actionSeq atomic.Uint64
+
+// newActionID 生成随机的操作 ID；系统随机数不可用时退回到时间和序号，仍保证在本进程内唯一。
+func newActionID() string {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		log.Printf("[Power] 读取系统随机数失败，使用时间生成操作 ID: %v", err)
+		return fmt.Sprintf("%x-%d", time.Now().UnixNano(), actionSeq.Add(1))
+	}
+	return hex.EncodeToString(b)
+}
+
+// Start 启动 req.Action 的倒计时。同一操作已在倒计时中时直接返回它；其他操作在倒计时或执行中时返回 ErrBusy。
+func (m *Manager) Start(req Request) (Action, error) {
+	if _, err := m.run(req.Action); err != nil {
+		return Action{}, err
+	}
+	m.mu.Lock()
+	if cur := m.current; cur != nil {
+		defer m.mu.Unlock()
+		if cur.name == req.Action && cur.state == StateCountingDown {
+			return cur.snapshot(time.Now()), nil
+		}
+		return Action{}, fmt.Errorf("%w: %s%s", ErrBusy, actionName(cur.name), stateName(cur.state))
+	}
+	a, snap, err := m.startLocked(req)
+	m.mu.Unlock()
+	if err != nil {
+		return Action{}, err
+	}
+	m.showCountdown(a)
+	return snap, nil
+}
+
+// Toggle 兼容旧接口的切换语义：action 正在倒计时则取消它并返回 started=false，
+// 没有进行中的操作则启动并返回 started=true，其他操作进行中时返回 ErrBusy。
+func (m *Manager) Toggle(req Request) (a Action, started bool, err error) {
+	if _, err := m.run(req.Action); err != nil {
+		return Action{}, false, err
+	}
+	m.mu.Lock()
+	if cur := m.current; cur != nil {
+		if cur.name == req.Action && cur.state == StateCountingDown {
+			window := m.cancelLocked(cur, req.Source)
+			a = cur.snapshot(time.Now())
+			m.mu.Unlock()
+			closeWindow(cur.name, window)
+			return a, false, nil
+		}
+		m.mu.Unlock()
+		return Action{}, false, fmt.Errorf("%w: %s%s", ErrBusy, actionName(cur.name), stateName(cur.state))
+	}
+	next, a, err := m.startLocked(req)
+	m.mu.Unlock()
+	if err != nil {
+		return Action{}, false, err
+	}
+	m.showCountdown(next)
+	return a, true, nil
+}
+
+func stateName(state string) string {
+	if state == StateExecuting {
+		return "执行中"
+	}
+	return "倒计时中"
+}
+
+// startLocked 开始倒计时并返回新操作及其快照，调用方需持有 m.mu，释放后调用 showCountdown 显示界面。
+func (m *Manager) startLocked(req Request) (*action, Action, error) {
+	name := req.Action
+	run, err := m.run(name)
+	if err != nil {
+		return nil, Action{}, err
+	}
+	seconds := req.Seconds
+	if seconds <= 0 {
+		seconds = m.defaultSeconds(name)
+	}
+	a := &action{
+		id:       newActionID(),
+		name:     name,
+		source:   req.Source,
+		reason:   req.Reason,
+		state:    StateCountingDown,
+		deadline: time.Now().Add(time.Duration(seconds) * time.Second),
+		duration: seconds,
+	}
+	a.timer = time.AfterFunc(time.Duration(seconds)*time.Second, func() { m.fire(a, run) })
+	m.current = a
+	log.Printf("[Power] %s倒计时已开始 (ID: %s，来源: %s)，%d 秒后执行。", actionName(name), a.id, a.source, seconds)
+	if a.reason != "" {
+		log.Printf("[Power] %s原因: %s", actionName(name), a.reason)
+	}
+	return a, a.snapshot(time.Now()), nil
+}
+
+// showCountdown 显示 a 的本机倒计时界面，调用方不能持有 m.mu。
+// 显示期间操作可能已被取消或开始执行，此时立即关闭刚显示的界面。
+func (m *Manager) showCountdown(a *action) {
+	window, err := m.view.ShowCountdown(a.name, a.deadline, a.reason, func() {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		if m.current == a && a.state == StateCountingDown {
+			a.window = nil // 窗口已由用户关闭
+			m.cancelLocked(a, "本机倒计时窗口")
+		}
+	})
+	if err != nil {
+		// 没有倒计时界面（例如未安装 AutoHotkey）时照常倒计时
+		log.Printf("[Power] 无法显示%s倒计时界面，继续后台倒计时: %v", actionName(a.name), err)
+		return
+	}
+	m.mu.Lock()
+	if m.current == a && a.state == StateCountingDown {
+		a.window, window = window, nil
+	}
+	m.mu.Unlock()
+	closeWindow(a.name, window)
+}
+
+// fire 倒计时到点：关闭界面并执行电源操作。执行期间状态为 executing，不能取消。
+func (m *Manager) fire(a *action, run func() error) {
+	m.mu.Lock()
+	if m.current != a || a.state != StateCountingDown {
+		m.mu.Unlock()
+		return
+	}
+	a.state = StateExecuting
+	window := a.window
+	a.window = nil
+	m.mu.Unlock()
+	closeWindow(a.name, window)
+
+	log.Printf("[Power] %s倒计时结束，开始执行 (ID: %s)。", actionName(a.name), a.id)
+	err := run()
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if err != nil {
+		log.Printf("[Power] 错误: 执行%s失败 (ID: %s): %v", actionName(a.name), a.id, err)
+		a.state, a.err = StateFailed, err
+		m.last = a
+	} else {
+		// 睡眠唤醒后回到空闲状态
+		a.state = StateIdle
+		m.last = nil
+	}
+	m.current = nil
+}
+
+// Cancel 取消 ID 为 id 的倒计时。id 不存在时返回 ErrNotPending，已在执行中时返回 ErrBusy。
+func (m *Manager) Cancel(id, source string) (Action, error) {
+	m.mu.Lock()
+	cur := m.current
+	if cur == nil || cur.id != id {
+		m.mu.Unlock()
+		return Action{}, fmt.Errorf("%w: %s", ErrNotPending, id)
+	}
+	if cur.state != StateCountingDown {
+		m.mu.Unlock()
+		return Action{}, fmt.Errorf("%w: %s已在执行，无法取消", ErrBusy, actionName(cur.name))
+	}
+	window := m.cancelLocked(cur, source)
+	a := cur.snapshot(time.Now())
+	m.mu.Unlock()
+	closeWindow(cur.name, window)
+	return a, nil
+}
+
+// CancelAction 取消 action 的倒计时，没有该操作的倒计时时返回 false。
+func (m *Manager) CancelAction(action, source string) bool {
+	m.mu.Lock()
+	cur := m.current
+	if cur == nil || cur.name != action || cur.state != StateCountingDown {
+		m.mu.Unlock()
+		return false
+	}
+	window := m.cancelLocked(cur, source)
+	m.mu.Unlock()
+	closeWindow(action, window)
+	return true
+}
+
+// CancelAll 取消倒计时中的操作，用于服务退出。
+func (m *Manager) CancelAll(source string) {
+	m.mu.Lock()
+	cur := m.current
+	if cur == nil || cur.state != StateCountingDown {
+		m.mu.Unlock()
+		return
+	}
+	window := m.cancelLocked(cur, source)
+	m.mu.Unlock()
+	closeWindow(cur.name, window)
+}
+
+// Abort 取消倒计时中的操作（如有），并取消系统中已计划的关机或重启（例如其他程序执行的 shutdown /s /t 60）。
+// cancelled 为被取消的倒计时，没有倒计时时为 nil。
+func (m *Manager) Abort(source string) (cancelled *Action, err error) {
+	m.mu.Lock()
+	cur := m.current
+	var window platform.CountdownWindow
+	if cur != nil && cur.state == StateCountingDown {
+		window = m.cancelLocked(cur, source)
+		a := cur.snapshot(time.Now())
+		cancelled = &a
+	}
+	m.mu.Unlock()
+	if cancelled != nil {
+		closeWindow(cur.name, window)
+	}
+	if err := m.power.AbortShutdown(); err != nil {
+		return cancelled, fmt.Errorf("取消系统计划的关机失败: %w", err)
+	}
+	log.Printf("[Power] 已取消系统计划的关机 (来源: %s)", source)
+	return cancelled, nil
+}
+
+// cancelLocked 停止计时，返回需要关闭的倒计时界面（可能为 nil），调用方需持有 m.mu，
+// 释放后再用 closeWindow 关闭界面。
+func (m *Manager) cancelLocked(a *action, source string) platform.CountdownWindow {
+	a.timer.Stop()
+	a.state = StateCancelled
+	m.current, m.last = nil, a
+	window := a.window
+	a.window = nil
+	log.Printf("[Power] %s倒计时已取消 (ID: %s，来源: %s)", actionName(a.name), a.id, source)
+	return window
+}
+
+// closeWindow 关闭 action 的倒计时界面，调用方不能持有 m.mu。
+func closeWindow(action string, window platform.CountdownWindow) {
+	if window == nil {
+		return
+	}
+	if err := window.Close(); err != nil {
+		log.Printf("[Power] 关闭%s倒计时界面失败: %v", actionName(action), err)
+	}
+}
+
+// Pending 返回 action 倒计时中的操作。
+func (m *Manager) Pending(action string) (Action, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil || m.current.name != action || m.current.state != StateCountingDown {
+		return Action{}, false
+	}
+	return m.current.snapshot(time.Now()), true
+}
+
+// List 返回倒计时或执行中的操作（最多一个）。
+func (m *Manager) List() []Action {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.current == nil {
+		return []Action{}
+	}
+	return []Action{m.current.snapshot(time.Now())}
+}
+
+// Status 返回管理器当前的状态。
+func (m *Manager) Status() Status {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := time.Now()
+	switch {
+	case m.current != nil:
+		a := m.current.snapshot(now)
+		return Status{State: a.State, Action: &a}
+	case m.last != nil:
+		a := m.last.snapshot(now)
+		return Status{State: a.State, Action: &a}
+	}
+	return Status{State: StateIdle}
+}
@@ -0,0 +1,204 @@
+package power
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"bealinkserver/platform"
+)
+
+// syncCancelView 在 ShowCountdown 返回前同步调用 onCancel，模拟窗口打开后立即被关闭；
+// cancelOnClose 为 true 时窗口的 Close 也同步调用 onCancel。
+type syncCancelView struct {
+	cancelOnShow  bool
+	cancelOnClose bool
+	closed        int
+}
+
+type syncCancelWindow struct {
+	view     *syncCancelView
+	onCancel func()
+}
+
+func (v *syncCancelView) ShowCountdown(action string, deadline time.Time, reason string, onCancel func()) (platform.CountdownWindow, error) {
+	if v.cancelOnShow {
+		onCancel()
+	}
+	return &syncCancelWindow{view: v, onCancel: onCancel}, nil
+}
+
+func (w *syncCancelWindow) Close() error {
+	w.view.closed++
+	if w.view.cancelOnClose {
+		w.onCancel()
+	}
+	return nil
+}
+
+func newTestManager(view platform.CountdownView) *Manager {
+	return NewManager(platform.NewSimulator().Backend().Power, view, func(string) int { return 60 })
+}
+
+// runWithTimeout 在 2 秒内未返回时判定为死锁。
+func runWithTimeout(t *testing.T, name string, fn func()) {
+	t.Helper()
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		fn()
+	}()
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatalf("%s 未返回，倒计时界面回调发生死锁", name)
+	}
+}
+
+func TestManagerViewCancelDuringShow(t *testing.T) {
+	view := &syncCancelView{cancelOnShow: true}
+	m := newTestManager(view)
+	runWithTimeout(t, "Start", func() {
+		if _, err := m.Start(Request{Action: "shutdown", Source: "测试"}); err != nil {
+			t.Errorf("Start 返回错误: %v", err)
+		}
+	})
+	if st := m.Status(); st.State != StateCancelled {
+		t.Errorf("窗口被关闭后状态 = %s, 期望 %s", st.State, StateCancelled)
+	}
+	if view.closed != 1 {
+		t.Errorf("已取消操作的界面应被关闭 1 次，实际 %d 次", view.closed)
+	}
+}
+
+func TestManagerCloseCallingOnCancel(t *testing.T) {
+	view := &syncCancelView{cancelOnClose: true}
+	m := newTestManager(view)
+	runWithTimeout(t, "Toggle", func() {
+		if _, started, err := m.Toggle(Request{Action: "sleep", Source: "测试"}); err != nil || !started {
+			t.Errorf("第一次 Toggle 应启动倒计时: started=%v, err=%v", started, err)
+		}
+		if _, started, err := m.Toggle(Request{Action: "sleep", Source: "测试"}); err != nil || started {
+			t.Errorf("第二次 Toggle 应取消倒计时: started=%v, err=%v", started, err)
+		}
+	})
+	if st := m.Status(); st.State != StateCancelled {
+		t.Errorf("状态 = %s, 期望 %s", st.State, StateCancelled)
+	}
+	if view.closed != 1 {
+		t.Errorf("界面应被关闭 1 次，实际 %d 次", view.closed)
+	}
+}
+
+func TestManagerStartBusy(t *testing.T) {
+	m := newTestManager(&syncCancelView{})
+	first, err := m.Start(Request{Action: "shutdown", Source: "测试"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer m.CancelAll("测试结束")
+	again, err := m.Start(Request{Action: "shutdown", Source: "测试"})
+	if err != nil || again.ID != first.ID {
+		t.Errorf("重复启动同一操作应返回已有倒计时: %+v, %v", again, err)
+	}
+	if _, err := m.Start(Request{Action: "sleep", Source: "测试"}); !errors.Is(err, ErrBusy) {
+		t.Errorf("其他操作倒计时中时应返回 ErrBusy，实际: %v", err)
+	}
+}
+
+// newSimulatedManager 创建倒计时界面和电源操作都由同一个模拟后端处理的管理器。
+func newSimulatedManager() (*Manager, *platform.Simulator) {
+	sim := platform.NewSimulator()
+	b := sim.Backend()
+	return NewManager(b.Power, b.Countdown, func(string) int { return 60 }), sim
+}
+
+// journalHas 返回模拟后端的操作日志中是否有 capability.action 的记录。
+func journalHas(sim *platform.Simulator, capability, action string) bool {
+	for _, e := range sim.Journal() {
+		if e.Capability == capability && e.Action == action {
+			return true
+		}
+	}
+	return false
+}
+
+func TestManagerAbortCancelsPending(t *testing.T) {
+	m, sim := newSimulatedManager()
+	started, err := m.Start(Request{Action: "restart", Source: "测试"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cancelled, err := m.Abort("测试")
+	if err != nil {
+		t.Fatalf("Abort 返回错误: %v", err)
+	}
+	if cancelled == nil || cancelled.ID != started.ID || cancelled.State != StateCancelled {
+		t.Errorf("Abort 应返回被取消的倒计时 %s，实际: %+v", started.ID, cancelled)
+	}
+	if st := m.Status(); st.State != StateCancelled {
+		t.Errorf("Abort 后状态 = %s, 期望 %s", st.State, StateCancelled)
+	}
+	if !journalHas(sim, "power", "abort") {
+		t.Error("Abort 应调用 AbortShutdown")
+	}
+	if !journalHas(sim, "countdown", "close") {
+		t.Error("Abort 应关闭倒计时界面")
+	}
+	if st := sim.State(); st.LastPowerAction != "" {
+		t.Errorf("被取消的操作不应执行，实际执行了 %s", st.LastPowerAction)
+	}
+}
+
+func TestManagerAbortWithoutPending(t *testing.T) {
+	m, sim := newSimulatedManager()
+	cancelled, err := m.Abort("测试")
+	if err != nil {
+		t.Fatalf("Abort 返回错误: %v", err)
+	}
+	if cancelled != nil {
+		t.Errorf("没有倒计时时 cancelled 应为 nil，实际: %+v", cancelled)
+	}
+	if !journalHas(sim, "power", "abort") {
+		t.Error("没有倒计时时 Abort 仍应取消系统计划的关机")
+	}
+}
+
+func TestManagerRunsNewActions(t *testing.T) {
+	for _, action := range []string{"restart", "hibernate", "lock", "signout"} {
+		t.Run(action, func(t *testing.T) {
+			t.Parallel()
+			m, sim := newSimulatedManager()
+			a, err := m.Start(Request{Action: action, Seconds: 1, Source: "测试"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if a.Action != action || a.State != StateCountingDown || a.Duration != 1 {
+				t.Errorf("Start 返回 %+v, 期望 %s 倒计时 1 秒", a, action)
+			}
+			deadline := time.Now().Add(3 * time.Second)
+			for sim.State().LastPowerAction != action {
+				if time.Now().After(deadline) {
+					t.Fatalf("倒计时结束后未执行 %s，状态: %+v", action, m.Status())
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+			for m.Status().State != StateIdle {
+				if time.Now().After(deadline) {
+					t.Fatalf("执行 %s 后状态 = %s, 期望 %s", action, m.Status().State, StateIdle)
+				}
+				time.Sleep(20 * time.Millisecond)
+			}
+		})
+	}
+}
+
+func TestManagerUnknownAction(t *testing.T) {
+	m, sim := newSimulatedManager()
+	if _, err := m.Start(Request{Action: "reboot", Source: "测试"}); !errors.Is(err, ErrUnknownAction) {
+		t.Errorf("未知操作应返回 ErrUnknownAction，实际: %v", err)
+	}
+	if journalHas(sim, "countdown", "show") {
+		t.Error("未知操作不应显示倒计时界面")
+	}
+}
@@ -0,0 +1,310 @@
+package power
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// ScheduleFileName 定时电源操作的保存文件名，与配置文件放在同一目录。
+const ScheduleFileName = "bealink_schedules.json"
+
+var (
+	// ErrScheduleNotFound 指定 ID 的定时任务不存在。
+	ErrScheduleNotFound = errors.New("定时任务不存在")
+	// ErrInvalidSchedule 执行时间格式错误或已经过去。
+	ErrInvalidSchedule = errors.New("定时任务参数无效")
+)
+
+// Schedule 一个定时电源操作。到点后通过 Manager 启动倒计时，电脑前的人仍可在倒计时窗口中取消。
+// Daily 非空 (HH:MM) 时每天在该时间执行，At 为下一次执行时间；否则只在 At 执行一次。
+// 到点时未能启动（如已有其他电源操作进行中，或操作策略不再允许）时记录在 LastError 中：一次性任务保留在列表中供查看和删除，
+// 每日任务照常顺延，下一次成功启动后清空。
+type Schedule struct {
+	ID        string    `json:"id"`
+	Action    string    `json:"action"`
+	At        time.Time `json:"at"`
+	Daily     string    `json:"daily,omitempty"`
+	Source    string    `json:"source,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	LastError string    `json:"last_error,omitempty"`
+}
+
+// scheduleEntry 调度器内部记录，runTimer 到点执行（一次性任务启动失败后为 nil），remindTimer 到点发送提醒。
+type scheduleEntry struct {
+	Schedule
+	runTimer    *time.Timer
+	remindTimer *time.Timer
+}
+
+// Scheduler 定时电源操作调度器。任务保存在 path 中，重启后重新加载。
+type Scheduler struct {
+	path    string
+	manager *Manager
+	// reminderLead 返回提前提醒的时长，0 表示不提醒；remind 发送提醒；failed 在到点未能启动时发送通知；
+	// allow 在到点时检查是否仍允许执行（如操作策略已改为禁止），返回错误时按未能启动处理。
+	reminderLead func() time.Duration
+	remind       func(Schedule)
+	failed       func(Schedule)
+	allow        func(Schedule) error
+
+	mu      sync.Mutex
+	entries map[string]*scheduleEntry
+}
+
+// NewScheduler 创建调度器，到点后通过 manager 启动电源操作，并在执行前 reminderLead() 调用 remind；
+// 到点时先调用 allow（可为 nil），返回错误或未能启动时调用 failed，参数的 LastError 为失败原因。
+func NewScheduler(path string, manager *Manager, reminderLead func() time.Duration, remind, failed func(Schedule), allow func(Schedule) error) *Scheduler {
+	return &Scheduler{path: path, manager: manager, reminderLead: reminderLead, remind: remind, failed: failed, allow: allow, entries: make(map[string]*scheduleEntry)}
+}
+
+// ParseDaily 解析 HH:MM 格式的每日时间。
+func ParseDaily(s string) (hour, minute int, err error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: 时间格式必须是 HH:MM: %s", ErrInvalidSchedule, s)
+	}
+	return t.Hour(), t.Minute(), nil
+}
+
+// NextDaily 返回 now 之后第一个 HH:MM（本地时间）。
+func NextDaily(daily string, now time.Time) (time.Time, error) {
+	hour, minute, err := ParseDaily(daily)
+	if err != nil {
+		return time.Time{}, err
+	}
+	next := time.Date(now.Year(), now.Month(), now.Day(), hour, minute, 0, 0, now.Location())
+	if !next.After(now) {
+		next = next.AddDate(0, 0, 1)
+	}
+	return next, nil
+}
+
+// Load 从文件加载定时任务。错过执行时间的一次性任务直接丢弃（避免开机后立即关机），每日任务顺延到下一次。
+func (s *Scheduler) Load() error {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取定时任务失败: %w", err)
+	}
+	var list []Schedule
+	if err := json.Unmarshal(data, &list); err != nil {
+		return fmt.Errorf("解析定时任务失败: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	dropped := false
+	for _, sc := range list {
+		if !sc.At.After(now) {
+			if sc.Daily == "" && sc.LastError != "" {
+				// 启动失败的一次性任务保留，供查看和删除
+				s.entries[sc.ID] = &scheduleEntry{Schedule: sc}
+				continue
+			}
+			if sc.Daily == "" {
+				log.Printf("[Schedule] 服务未运行期间错过了定时%s (%s，ID: %s)，已丢弃", actionName(sc.Action), sc.At.Format(time.DateTime), sc.ID)
+				dropped = true
+				continue
+			}
+			if sc.At, err = NextDaily(sc.Daily, now); err != nil {
+				log.Printf("[Schedule] 丢弃无效的每日任务 %s: %v", sc.ID, err)
+				dropped = true
+				continue
+			}
+		}
+		s.armLocked(&scheduleEntry{Schedule: sc})
+	}
+	if len(list) > 0 {
+		log.Printf("[Schedule] 已加载 %d 个定时电源操作", len(s.entries))
+	}
+	if dropped {
+		return s.saveLocked()
+	}
+	return nil
+}
+
+// Add 添加定时任务：daily 非空时每天执行，否则在 at 执行一次。
+func (s *Scheduler) Add(action string, at time.Time, daily, source string) (Schedule, error) {
+	if _, err := s.manager.run(action); err != nil {
+		return Schedule{}, err
+	}
+	now := time.Now()
+	if daily != "" {
+		next, err := NextDaily(daily, now)
+		if err != nil {
+			return Schedule{}, err
+		}
+		at = next
+	} else if !at.After(now) {
+		return Schedule{}, fmt.Errorf("%w: 执行时间 %s 已经过去", ErrInvalidSchedule, at.Format(time.DateTime))
+	}
+
+	sc := Schedule{ID: newActionID(), Action: action, At: at.Truncate(time.Second), Daily: daily, Source: source, CreatedAt: now}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.armLocked(&scheduleEntry{Schedule: sc})
+	if err := s.saveLocked(); err != nil {
+		s.removeLocked(sc.ID)
+		return Schedule{}, err
+	}
+	log.Printf("[Schedule] 已添加定时%s (ID: %s，来源: %s)，%s", actionName(action), sc.ID, source, describe(sc))
+	return sc, nil
+}
+
+func describe(sc Schedule) string {
+	if sc.Daily != "" {
+		return fmt.Sprintf("每天 %s 执行，下一次: %s", sc.Daily, sc.At.Format(time.DateTime))
+	}
+	return "执行时间: " + sc.At.Format(time.DateTime)
+}
+
+// Cancel 删除 ID 为 id 的定时任务。
+func (s *Scheduler) Cancel(id, source string) (Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[id]
+	if !ok {
+		return Schedule{}, fmt.Errorf("%w: %s", ErrScheduleNotFound, id)
+	}
+	s.removeLocked(id)
+	if err := s.saveLocked(); err != nil {
+		log.Printf("[Schedule] %v", err)
+	}
+	log.Printf("[Schedule] 已取消定时%s (ID: %s，来源: %s)", actionName(e.Action), id, source)
+	return e.Schedule, nil
+}
+
+// List 返回所有定时任务，按下一次执行时间排序。
+func (s *Scheduler) List() []Schedule {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	list := make([]Schedule, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e.Schedule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].At.Before(list[j].At) })
+	return list
+}
+
+// Stop 停止所有计时器（任务仍保存在文件中），用于服务退出。
+func (s *Scheduler) Stop() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, e := range s.entries {
+		e.stopTimers()
+	}
+}
+
+func (e *scheduleEntry) stopTimers() {
+	if e.runTimer != nil {
+		e.runTimer.Stop()
+	}
+	if e.remindTimer != nil {
+		e.remindTimer.Stop()
+	}
+}
+
+// armLocked 为 e 设置执行和提醒计时器，调用方需持有 s.mu。
+func (s *Scheduler) armLocked(e *scheduleEntry) {
+	s.entries[e.ID] = e
+	now := time.Now()
+	e.runTimer = time.AfterFunc(e.At.Sub(now), func() { s.fire(e) })
+	e.remindTimer = nil
+	if lead := s.reminderLead(); lead > 0 && s.remind != nil {
+		if d := e.At.Add(-lead).Sub(now); d > 0 {
+			sc := e.Schedule
+			e.remindTimer = time.AfterFunc(d, func() { s.remind(sc) })
+		}
+	}
+}
+
+func (s *Scheduler) removeLocked(id string) {
+	e, ok := s.entries[id]
+	if !ok {
+		return
+	}
+	e.stopTimers()
+	delete(s.entries, id)
+}
+
+// fire 到点启动倒计时。每日任务顺延到下一天；一次性任务启动成功后删除，失败时保留并记录原因。
+// allow 拒绝或未能启动（如 ErrBusy）时调用 failed 发送通知。
+func (s *Scheduler) fire(e *scheduleEntry) {
+	s.mu.Lock()
+	if s.entries[e.ID] != e {
+		s.mu.Unlock()
+		return
+	}
+	sc := e.Schedule
+	s.mu.Unlock()
+
+	log.Printf("[Schedule] 定时%s到点 (ID: %s)，开始倒计时", actionName(sc.Action), sc.ID)
+	var startErr error
+	if s.allow != nil {
+		startErr = s.allow(sc)
+	}
+	if startErr == nil {
+		_, startErr = s.manager.Start(Request{Action: sc.Action, Source: "定时任务 " + sc.ID})
+	}
+	sc.LastError = ""
+	if startErr != nil {
+		log.Printf("[Schedule] 错误: 定时%s未能启动: %v", actionName(sc.Action), startErr)
+		sc.LastError = startErr.Error()
+	}
+
+	s.mu.Lock()
+	if s.entries[e.ID] != e {
+		// 启动期间任务已被删除
+		s.mu.Unlock()
+		return
+	}
+	s.removeLocked(e.ID)
+	switch {
+	case sc.Daily != "":
+		next := sc
+		var err error
+		if next.At, err = NextDaily(sc.Daily, time.Now().Add(time.Second)); err == nil {
+			s.armLocked(&scheduleEntry{Schedule: next})
+		}
+	case startErr != nil:
+		s.entries[sc.ID] = &scheduleEntry{Schedule: sc}
+	}
+	if err := s.saveLocked(); err != nil {
+		log.Printf("[Schedule] %v", err)
+	}
+	s.mu.Unlock()
+
+	if startErr != nil && s.failed != nil {
+		s.failed(sc)
+	}
+}
+
+// saveLocked 将任务写入文件，调用方需持有 s.mu。
+func (s *Scheduler) saveLocked() error {
+	list := make([]Schedule, 0, len(s.entries))
+	for _, e := range s.entries {
+		list = append(list, e.Schedule)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return fmt.Errorf("序列化定时任务失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0750); err != nil {
+		return fmt.Errorf("创建定时任务目录失败: %w", err)
+	}
+	if err := os.WriteFile(s.path, data, 0640); err != nil {
+		return fmt.Errorf("保存定时任务失败: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,136 @@
+package power
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// newTestScheduler 创建使用临时文件的调度器，返回调度器、其管理器和记录失败通知的函数。
+func newTestScheduler(t *testing.T) (*Scheduler, *Manager, func() []Schedule) {
+	t.Helper()
+	m := newTestManager(&syncCancelView{})
+	t.Cleanup(func() { m.CancelAll("测试结束") })
+	var mu sync.Mutex
+	var failed []Schedule
+	s := NewScheduler(filepath.Join(t.TempDir(), ScheduleFileName), m, func() time.Duration { return 0 }, nil, func(sc Schedule) {
+		mu.Lock()
+		defer mu.Unlock()
+		failed = append(failed, sc)
+	}, nil)
+	t.Cleanup(s.Stop)
+	return s, m, func() []Schedule {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]Schedule(nil), failed...)
+	}
+}
+
+// fireNow 登记 sc 并立即触发，不等待计时器。
+func fireNow(s *Scheduler, sc Schedule) {
+	e := &scheduleEntry{Schedule: sc}
+	s.mu.Lock()
+	s.armLocked(e)
+	s.mu.Unlock()
+	s.fire(e)
+}
+
+func TestSchedulerBusyOnceKeepsFailure(t *testing.T) {
+	s, m, failed := newTestScheduler(t)
+	if _, err := m.Start(Request{Action: "sleep", Source: "测试"}); err != nil {
+		t.Fatal(err)
+	}
+	fireNow(s, Schedule{ID: "once", Action: "shutdown", At: time.Now().Add(time.Hour)})
+
+	got := failed()
+	if len(got) != 1 || got[0].ID != "once" || !strings.Contains(got[0].LastError, ErrBusy.Error()) {
+		t.Fatalf("应发送一次失败通知并带上 ErrBusy 原因，实际: %+v", got)
+	}
+	list := s.List()
+	if len(list) != 1 || list[0].LastError == "" {
+		t.Fatalf("未能启动的一次性任务应保留并记录原因，实际: %+v", list)
+	}
+
+	// 重新加载后仍保留，可以删除
+	reloaded := NewScheduler(s.path, m, func() time.Duration { return 0 }, nil, nil, nil)
+	if err := reloaded.Load(); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(reloaded.Stop)
+	if list := reloaded.List(); len(list) != 1 || list[0].LastError == "" {
+		t.Errorf("重新加载后应保留启动失败的任务，实际: %+v", list)
+	}
+	if _, err := reloaded.Cancel("once", "测试"); err != nil {
+		t.Errorf("删除启动失败的任务: %v", err)
+	}
+}
+
+func TestSchedulerBusyDailyReschedules(t *testing.T) {
+	s, m, failed := newTestScheduler(t)
+	if _, err := m.Start(Request{Action: "sleep", Source: "测试"}); err != nil {
+		t.Fatal(err)
+	}
+	fireNow(s, Schedule{ID: "daily", Action: "shutdown", Daily: "23:00", At: time.Now().Add(time.Hour)})
+	if len(failed()) != 1 {
+		t.Errorf("应发送一次失败通知，实际 %d 次", len(failed()))
+	}
+	list := s.List()
+	if len(list) != 1 || list[0].LastError == "" || !list[0].At.After(time.Now()) {
+		t.Fatalf("每日任务应顺延并记录失败原因，实际: %+v", list)
+	}
+
+	m.CancelAll("测试")
+	fireNow(s, list[0])
+	if list := s.List(); len(list) != 1 || list[0].LastError != "" {
+		t.Errorf("成功启动后应清空失败原因，实际: %+v", list)
+	}
+	if _, ok := m.Pending("shutdown"); !ok {
+		t.Error("空闲时应启动关机倒计时")
+	}
+}
+
+func TestSchedulerOnceSuccessRemoved(t *testing.T) {
+	s, m, failed := newTestScheduler(t)
+	fireNow(s, Schedule{ID: "once", Action: "lock", At: time.Now().Add(time.Hour)})
+	if list := s.List(); len(list) != 0 {
+		t.Errorf("成功启动的一次性任务应删除，实际: %+v", list)
+	}
+	if len(failed()) != 0 {
+		t.Error("成功启动时不应发送失败通知")
+	}
+	if _, ok := m.Pending("lock"); !ok {
+		t.Error("应启动锁定倒计时")
+	}
+}
+
+func TestSchedulerAllowRejects(t *testing.T) {
+	s, m, failed := newTestScheduler(t)
+	var checked []string
+	s.allow = func(sc Schedule) error {
+		checked = append(checked, sc.ID)
+		return errors.New("策略禁止远程关机")
+	}
+	fireNow(s, Schedule{ID: "once", Action: "shutdown", At: time.Now().Add(time.Hour)})
+	if _, ok := m.Pending("shutdown"); ok {
+		t.Fatal("被拒绝的定时任务不应启动倒计时")
+	}
+	if len(checked) != 1 || checked[0] != "once" {
+		t.Errorf("到点时应调用 allow，实际: %v", checked)
+	}
+	got := failed()
+	if len(got) != 1 || got[0].LastError != "策略禁止远程关机" {
+		t.Fatalf("应发送失败通知并带上拒绝原因，实际: %+v", got)
+	}
+	if list := s.List(); len(list) != 1 || list[0].LastError == "" {
+		t.Errorf("被拒绝的一次性任务应保留并记录原因，实际: %+v", list)
+	}
+
+	s.allow = func(Schedule) error { return nil }
+	fireNow(s, Schedule{ID: "allowed", Action: "lock", At: time.Now().Add(time.Hour)})
+	if _, ok := m.Pending("lock"); !ok {
+		t.Error("allow 通过时应启动倒计时")
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+	"bealinkserver/netacl"
+)
+
+// rejectNotifyInterval 同一来源被拒绝时两次 Bark 通知的最小间隔。
+const rejectNotifyInterval = 10 * time.Minute
+
+// aclCache 缓存由配置编译出的 ACL，配置中的列表变化时重新编译，使设置页面的修改无需重启即可生效。
+type aclCache struct {
+	mu         sync.Mutex
+	key        string
+	acl        *netacl.ACL
+	lastNotify map[string]time.Time // 按来源 IP 记录最近一次拒绝通知的时间
+}
+
+var accessList = &aclCache{lastNotify: make(map[string]time.Time)}
+
+// denyAll 配置无效时使用的 ACL，只放行本机请求。
+var denyAll, _ = netacl.NewACL(nil, []string{"0.0.0.0/0", "::/0"})
+
+// current 返回当前配置对应的 ACL。配置无效（例如手动编辑出错）时返回 denyAll。
+func (c *aclCache) current(cfg *bark.BarkConfig) *netacl.ACL {
+	key := strings.Join(cfg.AllowCIDRs, ",") + "|" + strings.Join(cfg.DenyCIDRs, ",")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.acl != nil && key == c.key {
+		return c.acl
+	}
+	acl, err := netacl.NewACL(cfg.AllowCIDRs, cfg.DenyCIDRs)
+	if err != nil {
+		log.Printf("[ACL] 访问控制列表无效，仅允许本机访问: %v", err)
+		acl = denyAll
+	}
+	c.key, c.acl = key, acl
+	return acl
+}
+
+// shouldNotify 判断是否需要为来源 ip 发送拒绝通知，并记录本次通知时间。
+func (c *aclCache) shouldNotify(ip string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	for k, t := range c.lastNotify {
+		if now.Sub(t) >= rejectNotifyInterval {
+			delete(c.lastNotify, k)
+		}
+	}
+	if _, ok := c.lastNotify[ip]; ok {
+		return false
+	}
+	c.lastNotify[ip] = now
+	return true
+}
+
+// accessControl 在任何处理器之前按来源 IP 检查允许/拒绝列表（拒绝时返回 403）和认证失败封禁（返回 429）。
+func (s *Server) accessControl(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		cfg := bark.GetConfig()
+		ip := auth.ClientIP(r)
+		if !accessList.current(cfg).Allowed(ip) {
+			s.rejectByACL(w, r, cfg, ip)
+			return
+		}
+		if left, blocked := auth.Blocked(ip); blocked {
+			retryAfter := strconv.Itoa(int(left.Seconds()) + 1)
+			w.Header().Set("Retry-After", retryAfter)
+			if strings.HasPrefix(r.URL.Path, "/api/") {
+				writeAPIError(w, apiErrorf(http.StatusTooManyRequests, ErrCodeBlocked, "认证失败次数过多，已被暂时封禁，请 %s 秒后再试", retryAfter))
+				return
+			}
+			http.Error(w, "认证失败次数过多，已被暂时封禁，请 "+retryAfter+" 秒后再试", http.StatusTooManyRequests)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rejectByACL 拒绝不在允许范围内的来源，记录日志并按配置发送 Bark 通知。
+func (s *Server) rejectByACL(w http.ResponseWriter, r *http.Request, cfg *bark.BarkConfig, ip string) {
+	log.Printf("[ACL] 拒绝来自 %s 的请求: %s %s", ip, r.Method, r.URL.Path)
+	if cfg.NotifyOnRejected && accessList.shouldNotify(ip) {
+		bark.NotifyMessage("access_rejected", "Bealink 拒绝访问",
+			fmt.Sprintf("来源 %s 不在允许访问的地址范围内\n请求: %s %s", ip, r.Method, r.URL.Path))
+	}
+	http.Error(w, "禁止访问: 来源地址不在允许范围内", http.StatusForbidden)
+}
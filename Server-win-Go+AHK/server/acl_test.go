@@ -0,0 +1,99 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+)
+
+func TestInvalidACLOnlyAllowsLoopback(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.AllowCIDRs, cfg.DenyCIDRs = []string{"192.168.1.0/24", "bogus"}, nil
+	}, func(cfg *bark.BarkConfig) {
+		cfg.AllowCIDRs, cfg.DenyCIDRs = nil, nil
+	})
+
+	if w := serve(h, lanRequest(http.MethodGet, "/api/v1/ping", nil)); w.Code != http.StatusForbidden {
+		t.Errorf("访问控制列表无效时局域网请求状态码 = %d, 期望 403", w.Code)
+	}
+	for _, remote := range []string{"127.0.0.1:50000", "[::1]:50000"} {
+		r := localRequest(http.MethodGet, "/api/v1/ping", nil)
+		r.RemoteAddr = remote
+		if w := serve(h, r); w.Code != http.StatusOK {
+			t.Errorf("访问控制列表无效时本机 %s 请求状态码 = %d, 期望 200", remote, w.Code)
+		}
+	}
+
+	// 修正配置后无需重启即可恢复
+	bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.AllowCIDRs = []string{"192.168.1.0/24"} })
+	if w := serve(h, lanRequest(http.MethodGet, "/api/v1/ping", nil)); w.Code != http.StatusOK {
+		t.Errorf("修正访问控制列表后局域网请求状态码 = %d, 期望 200", w.Code)
+	}
+	bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.DenyCIDRs = []string{"192.168.1.50"} })
+	if w := serve(h, lanRequest(http.MethodGet, "/api/v1/ping", nil)); w.Code != http.StatusForbidden {
+		t.Errorf("拒绝列表中的来源状态码 = %d, 期望 403", w.Code)
+	}
+}
+
+func TestRejectNotifyRateLimitedPerIP(t *testing.T) {
+	c := &aclCache{lastNotify: make(map[string]time.Time)}
+	if !c.shouldNotify("192.168.1.50") {
+		t.Error("首次被拒绝时应发送通知")
+	}
+	if c.shouldNotify("192.168.1.50") {
+		t.Error("同一来源在间隔内再次被拒绝时不应重复通知")
+	}
+	if !c.shouldNotify("192.168.1.51") {
+		t.Error("其他来源被拒绝时应单独通知")
+	}
+
+	// 模拟间隔已过去
+	c.mu.Lock()
+	c.lastNotify["192.168.1.50"] = time.Now().Add(-rejectNotifyInterval)
+	c.mu.Unlock()
+	if !c.shouldNotify("192.168.1.50") {
+		t.Error("超过通知间隔后应再次通知")
+	}
+	c.mu.Lock()
+	n := len(c.lastNotify)
+	c.mu.Unlock()
+	if n != 2 {
+		t.Errorf("通知记录应为 2 条，实际 %d 条", n)
+	}
+}
+
+func TestBlockedClientGets429(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	const ip = "192.168.1.50" // lanRequest 的来源
+	t.Cleanup(func() { auth.Unblock("") })
+	for i := 0; i < 5; i++ {
+		auth.RecordFailure(ip, "测试", fmt.Sprintf("guess-%d", i))
+	}
+
+	w := serve(h, lanRequest(http.MethodGet, "/api/v1/ping", nil))
+	if w.Code != http.StatusTooManyRequests || apiErrorCode(t, w.Body.String()) != ErrCodeBlocked {
+		t.Errorf("被封禁来源访问 /api/v1 的状态码 = %d (%s), 期望 429 blocked", w.Code, w.Body.String())
+	}
+	if retry, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || retry < 1 || retry > 61 {
+		t.Errorf("Retry-After = %q, 期望 1~61 秒", w.Header().Get("Retry-After"))
+	}
+	if w := serve(h, lanRequest(http.MethodGet, "/ping", nil)); w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") == "" {
+		t.Errorf("被封禁来源访问旧接口的状态码 = %d, 期望 429 并带 Retry-After", w.Code)
+	}
+	if w := serve(h, localRequest(http.MethodGet, "/api/v1/ping", nil)); w.Code != http.StatusOK {
+		t.Errorf("本机请求不受封禁影响，状态码 = %d", w.Code)
+	}
+
+	if w := serve(h, localRequest(http.MethodDelete, "/api/v1/security/blocked/"+ip, nil)); w.Code != http.StatusOK {
+		t.Fatalf("解除封禁的状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if w := serve(h, lanRequest(http.MethodGet, "/api/v1/ping", nil)); w.Code != http.StatusOK {
+		t.Errorf("解除封禁后状态码 = %d, 期望 200", w.Code)
+	}
+}
@@ -0,0 +1,358 @@
+package server
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"bealinkserver/bark"
+	"bealinkserver/netacl"
+	"bealinkserver/platform"
+)
+
+// 本文件中的操作同时供 /api/v1 接口和旧路由（兼容层）调用，
+// 两者只在请求解析和响应格式上不同。
+
+// errEmptyText 文本类接口收到空内容。
+var errEmptyText = apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "text 不能为空")
+
+// writeClipboardText 写入剪贴板文本。
+func (s *Server) writeClipboardText(text, remoteAddr string) error {
+	if text == "" {
+		return errEmptyText
+	}
+	if err := s.backend.Clipboard.WriteText(text); err != nil {
+		log.Printf("错误: 剪切板写入失败: %v", err)
+		return err
+	}
+	log.Printf("剪切板写入 (来自 %s): %d bytes", remoteAddr, len(text))
+	return nil
+}
+
+// typeText 将文本写入剪贴板，稍后模拟粘贴到当前焦点窗口。
+func (s *Server) typeText(text string) error {
+	if text == "" {
+		return errEmptyText
+	}
+	if err := s.backend.Clipboard.WriteText(text); err != nil {
+		log.Printf("错误: 剪切板写入失败: %v", err)
+		return err
+	}
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		if err := s.backend.Input.Paste(); err != nil {
+			log.Printf("粘贴失败: %v", err)
+		}
+	}()
+	log.Printf("已接收文本并粘贴: %s...", limitStr(text, 20))
+	return nil
+}
+
+// setClipboardImage 将上传的图片保存到临时文件后放入剪贴板。
+func (s *Server) setClipboardImage(src io.Reader) error {
+	tempFile := filepath.Join(os.TempDir(), "bealink_clip.png")
+	f, err := os.Create(tempFile)
+	if err != nil {
+		return err
+	}
+	_, err = io.Copy(f, src)
+	f.Close()
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(tempFile)
+	if err != nil {
+		return err
+	}
+	if err := s.backend.Clipboard.SetImage(data, filepath.Base(tempFile)); err != nil {
+		log.Printf("设置剪贴板图片失败: %v", err)
+		return err
+	}
+	return nil
+}
+
+// pressVolumeKey 模拟按下音量加/减键。
+func (s *Server) pressVolumeKey(up bool) error {
+	vk := uint16(platform.VK_VOLUME_DOWN)
+	if up {
+		vk = platform.VK_VOLUME_UP
+	}
+	return s.backend.Input.SendKeyPress(vk)
+}
+
+// mediaCommand 发送媒体控制快捷键：playpause / play 为 Ctrl+Alt+P，next / prev 为 Ctrl+Alt+Right / Left。
+func (s *Server) mediaCommand(command string) error {
+	var vk uint16
+	var name string
+	switch command {
+	case "playpause", "play":
+		vk, name = platform.VK_P, "播放/暂停"
+	case "next":
+		vk, name = platform.VK_RIGHT, "下一首"
+	case "prev":
+		vk, name = platform.VK_LEFT, "上一首"
+	default:
+		return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "未知的媒体命令: %s", command)
+	}
+	if err := s.backend.Input.SendKeyWithModifiers(true, true, false, vk); err != nil {
+		log.Printf("发送%s快捷键失败: %v", name, err)
+		return err
+	}
+	return nil
+}
+
+// applySettings 按字段更新 Bark 配置并保存；m 中不存在的字段保持不变，空字符串表示清空。
+// 访问令牌不在此处修改，见 /api/v1/tokens。监听相关的设置在重启后生效。
+func applySettings(m map[string]interface{}) error {
+	log.Printf("调试: handleSaveSettings - 准备更新的配置数据: %+v", m)
+
+	tlsPort, hasTLSPort := m["tls_port"].(string)
+	if hasTLSPort {
+		var ok bool
+		if tlsPort, ok = normalizePort(tlsPort); !ok {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "tls_port 必须是 1-65535 之间的端口号")
+		}
+	}
+
+	lists := make(map[string][]string)
+	for _, key := range []string{"listen_addresses", "listen_ports", "allow_cidrs", "deny_cidrs", "allowed_origins"} {
+		v, ok := m[key]
+		if !ok {
+			continue
+		}
+		list, err := stringList(v)
+		if err != nil {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%s: %v", key, err)
+		}
+		lists[key] = list
+	}
+	if ports, ok := lists["listen_ports"]; ok {
+		if len(ports) == 0 {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "listen_ports 至少需要一个端口")
+		}
+		for i, p := range ports {
+			normalized, valid := normalizePort(p)
+			if !valid {
+				return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "listen_ports 中的 %q 不是 1-65535 之间的端口号", p)
+			}
+			ports[i] = normalized
+		}
+	}
+	for i, origin := range lists["allowed_origins"] {
+		u, err := url.Parse(origin)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "allowed_origins 中的 %q 不是有效的来源，格式应为 https://主机[:端口]", origin)
+		}
+		lists["allowed_origins"][i] = u.Scheme + "://" + u.Host
+	}
+	for _, key := range []string{"allow_cidrs", "deny_cidrs"} {
+		for _, c := range lists[key] {
+			if _, err := netacl.ParseCIDR(c); err != nil {
+				return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%s: %v", key, err)
+			}
+		}
+	}
+
+	policies, hasPolicies, err := policySettings(m["action_policies"])
+	if err != nil {
+		return err
+	}
+	confirmTimeout, hasConfirmTimeout := 0, false
+	if v, ok := m["confirm_timeout_sec"]; ok {
+		if confirmTimeout, err = intSetting(v); err != nil || confirmTimeout < minConfirmTimeoutSec || confirmTimeout > maxConfirmTimeoutSec {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "confirm_timeout_sec 必须是 %d-%d 之间的整数", minConfirmTimeoutSec, maxConfirmTimeoutSec)
+		}
+		hasConfirmTimeout = true
+	}
+	reminderMin, hasReminderMin := 0, false
+	if v, ok := m["schedule_reminder_min"]; ok {
+		if reminderMin, err = intSetting(v); err != nil || reminderMin < 0 || reminderMin > maxScheduleReminderMin {
+			return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "schedule_reminder_min 必须是 0-%d 之间的整数", maxScheduleReminderMin)
+		}
+		hasReminderMin = true
+	}
+	countdownMin, countdownMax, countdownSeconds, err := countdownSettingsFrom(m)
+	if err != nil {
+		return err
+	}
+
+	err = bark.UpdateConfig(func(cfg *bark.BarkConfig) {
+		// 更新所有字段，包括空字符串（允许清空配置）
+		if v, ok := m["bark_full_url"].(string); ok {
+			log.Printf("调试: 更新 BarkFullURL 从 '%s' 到 '%s'", cfg.BarkFullURL, v)
+			cfg.BarkFullURL = v
+		}
+		if v, ok := m["sound"].(string); ok {
+			cfg.Sound = v
+		}
+		if v, ok := m["encryption_key"].(string); ok {
+			cfg.EncryptionKey = v
+		}
+		if v, ok := m["encryption_iv"].(string); ok {
+			cfg.EncryptionIV = v
+		}
+		if v, ok := m["notify_on_system_ready"].(bool); ok {
+			cfg.NotifyOnSystemReady = v
+		}
+		if v, ok := m["require_auth"].(bool); ok {
+			cfg.RequireAuth = v
+		}
+		if v, ok := m["legacy_allow_no_token"].(bool); ok {
+			cfg.LegacyAllowNoToken = v
+		}
+		if v, ok := m["tls_enabled"].(bool); ok {
+			cfg.TLSEnabled = v
+		}
+		if v, ok := m["http_enabled"].(bool); ok {
+			cfg.HTTPEnabled = v
+		}
+		if hasTLSPort {
+			cfg.TLSPort = tlsPort
+		}
+		if v, ok := lists["listen_addresses"]; ok {
+			cfg.ListenAddresses = v
+		}
+		if v, ok := lists["listen_ports"]; ok {
+			cfg.ListenPorts = v
+		}
+		if v, ok := lists["allow_cidrs"]; ok {
+			cfg.AllowCIDRs = v
+		}
+		if v, ok := lists["deny_cidrs"]; ok {
+			cfg.DenyCIDRs = v
+		}
+		if v, ok := m["notify_on_rejected"].(bool); ok {
+			cfg.NotifyOnRejected = v
+		}
+		if v, ok := m["allow_legacy_get"].(bool); ok {
+			cfg.AllowLegacyGET = v
+		}
+		if v, ok := lists["allowed_origins"]; ok {
+			cfg.AllowedOrigins = v
+		}
+		if hasPolicies {
+			cfg.ActionPolicies = policies
+		}
+		if hasConfirmTimeout {
+			cfg.ConfirmTimeoutSec = confirmTimeout
+		}
+		if hasReminderMin {
+			cfg.ScheduleReminderMin = reminderMin
+		}
+		cfg.CountdownMinSec, cfg.CountdownMaxSec = countdownMin, countdownMax
+		if countdownSeconds != nil {
+			cfg.CountdownSeconds = countdownSeconds
+		}
+	})
+	if err != nil {
+		log.Printf("错误: 保存配置失败: %v", err)
+		return fmt.Errorf("保存配置失败: %w", err)
+	}
+
+	// 验证保存后的配置
+	savedCfg := bark.GetConfig()
+	log.Printf("调试: handleSaveSettings - 保存后的配置 BarkFullURL: '%s'", savedCfg.BarkFullURL)
+	return nil
+}
+
+// sendTestBark 使用当前配置发送一条测试通知，sound 非空时覆盖配置中的铃声。
+func sendTestBark(sound string) error {
+	// 检查配置是否有效
+	cfg := bark.GetConfig()
+	sufficient, _, _, _, _, reason := bark.IsBarkConfigSufficient(cfg)
+	if !sufficient {
+		log.Printf("错误: Bark 配置不完整，无法发送测试通知: %s", reason)
+		return apiErrorf(http.StatusBadRequest, ErrCodeNotConfigured, "Bark 配置不完整: %s", reason)
+	}
+	if sound != "" {
+		cfg.Sound = sound
+	}
+	// 发送测试推送通知（使用指定或已保存的铃声）
+	bark.NotifyEventWithConfig("test", cfg)
+	return nil
+}
+
+// policySettings 校验 action_policies：键为 policyActions 中的操作名，值为 allow / confirm / deny。
+// 值为 allow 的项不保存。v 为 nil 时表示未提供该设置。
+func policySettings(v interface{}) (map[string]string, bool, error) {
+	if v == nil {
+		return nil, false, nil
+	}
+	m, ok := v.(map[string]interface{})
+	if !ok {
+		return nil, false, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "action_policies 必须是对象")
+	}
+	policies := make(map[string]string)
+	for action, p := range m {
+		policy, _ := p.(string)
+		if policyDescription(action) == action {
+			return nil, false, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "action_policies: 未知的操作 %s", action)
+		}
+		if !validPolicy(policy) {
+			return nil, false, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "action_policies.%s 只能是 allow、confirm 或 deny", action)
+		}
+		if policy != PolicyAllow {
+			policies[action] = policy
+		}
+	}
+	return policies, true, nil
+}
+
+// intSetting 读取 JSON 数字或表单字符串形式的整数设置。
+func intSetting(v interface{}) (int, error) {
+	switch n := v.(type) {
+	case float64:
+		if n != float64(int(n)) {
+			return 0, fmt.Errorf("不是整数: %v", n)
+		}
+		return int(n), nil
+	case string:
+		return strconv.Atoi(strings.TrimSpace(n))
+	default:
+		return 0, fmt.Errorf("类型无效: %T", v)
+	}
+}
+
+// normalizePort 校验端口号（可带或不带冒号前缀），返回 ":N" 形式。
+func normalizePort(s string) (string, bool) {
+	port, err := strconv.Atoi(strings.TrimPrefix(strings.TrimSpace(s), ":"))
+	if err != nil || port < 1 || port > 65535 {
+		return "", false
+	}
+	return ":" + strconv.Itoa(port), true
+}
+
+// stringList 将 JSON 字符串数组或按换行/逗号分隔的字符串（设置表单）解析为去除空白的列表。
+func stringList(v interface{}) ([]string, error) {
+	var items []string
+	switch t := v.(type) {
+	case string:
+		items = strings.FieldsFunc(t, func(r rune) bool { return r == '\n' || r == '\r' || r == ',' })
+	case []interface{}:
+		for _, item := range t {
+			str, ok := item.(string)
+			if !ok {
+				return nil, fmt.Errorf("必须是字符串数组")
+			}
+			items = append(items, str)
+		}
+	case nil:
+	default:
+		return nil, fmt.Errorf("必须是字符串数组")
+	}
+	list := []string{}
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list, nil
+}
@@ -0,0 +1,156 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sort"
+	"strings"
+
+	"bealinkserver/platform"
+)
+
+// /api/v1 错误码，供客户端按程序逻辑判断；Message 仅供人阅读。
+const (
+	ErrCodeBadRequest       = "bad_request"        // 请求格式错误（如 JSON 无法解析）
+	ErrCodeInvalidParameter = "invalid_parameter"  // 参数缺失或取值无效
+	ErrCodeNotFound         = "not_found"          // 资源或接口不存在
+	ErrCodeMethodNotAllowed = "method_not_allowed" // 接口不支持该 HTTP 方法
+	ErrCodeUnsupported      = "unsupported"        // 当前平台后端不支持该操作
+	ErrCodeNotConfigured    = "not_configured"     // 依赖的功能尚未配置（如 Bark）
+	ErrCodeBackendError     = "backend_error"      // 调用系统能力失败
+	ErrCodeUnauthorized     = "unauthorized"       // 未提供有效的访问令牌
+	ErrCodeForbidden        = "forbidden"          // 访问令牌缺少所需的权限，或配对码错误
+	ErrCodeRateLimited      = "rate_limited"       // 请求过于频繁
+	ErrCodeCSRF             = "csrf_failed"        // 浏览器写请求缺少有效的 CSRF 令牌，或来源不被允许
+	ErrCodeBlocked          = "blocked"            // 来源 IP 因多次认证失败被暂时封禁
+	ErrCodeConflict         = "conflict"           // 与进行中的操作冲突（如已有其他电源操作）
+)
+
+// APIError 是 /api/v1 接口的错误，Status 为对应的 HTTP 状态码。
+type APIError struct {
+	Status  int    `json:"-"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *APIError) Error() string { return e.Code + ": " + e.Message }
+
+// apiErrorf 构造一个 APIError。
+func apiErrorf(status int, code, format string, args ...interface{}) *APIError {
+	return &APIError{Status: status, Code: code, Message: fmt.Sprintf(format, args...)}
+}
+
+// toAPIError 将任意错误转换为 APIError：平台不支持返回 501，其余未分类错误按后端错误返回 500。
+func toAPIError(err error) *APIError {
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return apiErr
+	}
+	if errors.Is(err, platform.ErrUnsupported) {
+		return &APIError{Status: http.StatusNotImplemented, Code: ErrCodeUnsupported, Message: err.Error()}
+	}
+	return &APIError{Status: http.StatusInternalServerError, Code: ErrCodeBackendError, Message: err.Error()}
+}
+
+// apiEnvelope 是 /api/v1 所有响应的统一格式。
+type apiEnvelope struct {
+	OK    bool        `json:"ok"`
+	Data  interface{} `json:"data"`
+	Error *APIError   `json:"error,omitempty"`
+}
+
+// writeAPIData 以统一格式返回成功结果。
+func writeAPIData(w http.ResponseWriter, status int, data interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(apiEnvelope{OK: true, Data: data})
+}
+
+// writeAPIError 以统一格式返回错误。
+func writeAPIError(w http.ResponseWriter, err error) {
+	apiErr := toAPIError(err)
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(apiEnvelope{OK: false, Error: apiErr})
+}
+
+// apiFunc 处理一个 /api/v1 请求，返回的 data 会放入响应的 data 字段。
+type apiFunc func(r *http.Request) (data interface{}, err error)
+
+// policyFunc 按操作策略检查请求，返回错误时不执行操作。
+type policyFunc func(w http.ResponseWriter, r *http.Request) error
+
+// apiEndpoint 一个 /api/v1 接口方法及其所需的权限范围和操作策略。
+type apiEndpoint struct {
+	permission string
+	policy     policyFunc // 可为 nil
+	fn         apiFunc
+}
+
+// apiMethods 按 HTTP 方法分派同一路径下的接口，先检查权限，不支持的方法返回 405。
+type apiMethods map[string]apiEndpoint
+
+func (m apiMethods) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	ep, ok := m[r.Method]
+	if !ok && r.Method == http.MethodHead {
+		ep, ok = m[http.MethodGet]
+	}
+	if !ok {
+		allowed := make([]string, 0, len(m))
+		for method := range m {
+			allowed = append(allowed, method)
+		}
+		sort.Strings(allowed)
+		w.Header().Set("Allow", strings.Join(allowed, ", "))
+		writeAPIError(w, apiErrorf(http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "接口不支持 %s 方法", r.Method))
+		return
+	}
+	r, err := authorizeRequest(w, r, ep.permission)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	if ep.policy != nil {
+		if err := ep.policy(w, r); err != nil {
+			writeAPIError(w, err)
+			return
+		}
+	}
+	data, err := ep.fn(r)
+	if err != nil {
+		apiErr := toAPIError(err)
+		if apiErr.Status >= http.StatusInternalServerError {
+			log.Printf("[API] %s %s 失败: %v", r.Method, r.URL.Path, err)
+		}
+		writeAPIError(w, apiErr)
+		return
+	}
+	writeAPIData(w, http.StatusOK, data)
+}
+
+// apiNotFound 处理未注册的 /api/ 路径。
+func apiNotFound(w http.ResponseWriter, r *http.Request) {
+	writeAPIError(w, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "接口不存在: %s", r.URL.Path))
+}
+
+// maxAPIBodyBytes JSON 请求体的大小上限。
+const maxAPIBodyBytes = 1 << 20
+
+// decodeJSONBody 解析 JSON 请求体到 v，空请求体视为参数缺失。
+func decodeJSONBody(r *http.Request, v interface{}) error {
+	body, err := io.ReadAll(io.LimitReader(r.Body, maxAPIBodyBytes))
+	if err != nil {
+		return apiErrorf(http.StatusBadRequest, ErrCodeBadRequest, "读取请求体失败: %v", err)
+	}
+	if len(strings.TrimSpace(string(body))) == 0 {
+		return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "请求体不能为空")
+	}
+	if err := json.Unmarshal(body, v); err != nil {
+		return apiErrorf(http.StatusBadRequest, ErrCodeBadRequest, "无法解析 JSON 请求体: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,336 @@
+package server
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+	"bealinkserver/power"
+)
+
+// /api/v1 接口的处理函数。路由及其元数据见 routes.go，
+// 所有接口都返回统一的 {"ok": bool, "data": ..., "error": {"code", "message"}} 格式，见 api.go。
+
+func (s *Server) apiPing(r *http.Request) (interface{}, error) {
+	return pingResponse{Pong: true, TLS: s.tlsInfo()}, nil
+}
+
+// tlsInfo 返回运行中的 HTTPS 服务信息，未启用时为 nil。
+func (s *Server) tlsInfo() *tlsInfo {
+	if s.tls == nil {
+		return nil
+	}
+	return &tlsInfo{Port: GlobalTLSPort, Fingerprint: s.tls.Fingerprint}
+}
+
+// ---- 电源 ----
+
+// powerAPIError 将 power 包的错误转换为 APIError：未知操作或 ID 404，定时参数无效 400，与进行中的操作冲突 409。
+func powerAPIError(err error) *APIError {
+	switch {
+	case errors.Is(err, power.ErrUnknownAction), errors.Is(err, power.ErrNotPending), errors.Is(err, power.ErrScheduleNotFound):
+		return apiErrorf(http.StatusNotFound, ErrCodeNotFound, "%v", err)
+	case errors.Is(err, power.ErrInvalidSchedule):
+		return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%v", err)
+	case errors.Is(err, power.ErrBusy):
+		return apiErrorf(http.StatusConflict, ErrCodeConflict, "%v", err)
+	}
+	return toAPIError(err)
+}
+
+// apiPowerStart 启动 power.Actions 中操作的倒计时，请求体 (seconds、reason) 可省略；
+// 同一操作已在倒计时中时返回它，其他电源操作进行中时返回 409。
+func (s *Server) apiPowerStart(r *http.Request) (interface{}, error) {
+	var body powerStartRequest
+	if r.ContentLength != 0 {
+		if err := decodeJSONBody(r, &body); err != nil {
+			return nil, err
+		}
+	}
+	req, err := newPowerRequest(r.PathValue("action"), body, "API 请求 "+r.RemoteAddr)
+	if err != nil {
+		return nil, err
+	}
+	a, err := s.power.Start(req)
+	if err != nil {
+		return nil, powerAPIError(err)
+	}
+	return a, nil
+}
+
+// apiPowerAbort 取消倒计时中的电源操作和系统中已计划的关机，返回取消后的状态。
+func (s *Server) apiPowerAbort(r *http.Request) (interface{}, error) {
+	if _, err := s.power.Abort("API 请求 " + r.RemoteAddr); err != nil {
+		return nil, powerAPIError(err)
+	}
+	return s.power.Status(), nil
+}
+
+func (s *Server) apiPowerStatus(r *http.Request) (interface{}, error) {
+	return s.power.Status(), nil
+}
+
+// apiPowerCancel 按 ID 取消倒计时中的电源操作。
+func (s *Server) apiPowerCancel(r *http.Request) (interface{}, error) {
+	a, err := s.power.Cancel(r.PathValue("id"), "API 请求 "+r.RemoteAddr)
+	if err != nil {
+		return nil, powerAPIError(err)
+	}
+	return a, nil
+}
+
+func (s *Server) apiCountdownList(r *http.Request) (interface{}, error) {
+	return s.power.List(), nil
+}
+
+func (s *Server) apiCountdownCancel(r *http.Request) (interface{}, error) {
+	action := r.URL.Query().Get("action")
+	if action == "" {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "缺少 action 参数")
+	}
+	if !s.power.CancelAction(action, "API 请求 "+r.RemoteAddr) {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "没有进行中的%s倒计时", action)
+	}
+	return nil, nil
+}
+
+// ---- 显示器 ----
+
+type displayState struct {
+	MonitorOff bool `json:"monitor_off"`
+}
+
+func (s *Server) apiDisplayState(r *http.Request) (interface{}, error) {
+	return displayState{MonitorOff: s.backend.Display.IsMonitorOff()}, nil
+}
+
+func (s *Server) apiDisplayToggle(r *http.Request) (interface{}, error) {
+	off, err := s.backend.Display.ToggleMonitorPower()
+	if err != nil {
+		return nil, err
+	}
+	return displayState{MonitorOff: off}, nil
+}
+
+// displaySetResult 打开/关闭显示器的结果，显示器已处于目标状态时 changed 为 false。
+type displaySetResult struct {
+	MonitorOff bool `json:"monitor_off"`
+	Changed    bool `json:"changed"`
+}
+
+// setMonitorPower 将显示器设为 off 指定的状态，已经是该状态时不做任何操作。
+func (s *Server) setMonitorPower(off bool) (interface{}, error) {
+	changed, err := s.backend.Display.SetMonitorPower(off)
+	if err != nil {
+		return nil, err
+	}
+	return displaySetResult{MonitorOff: off, Changed: changed}, nil
+}
+
+func (s *Server) apiDisplayOn(r *http.Request) (interface{}, error) {
+	return s.setMonitorPower(false)
+}
+
+func (s *Server) apiDisplayOff(r *http.Request) (interface{}, error) {
+	return s.setMonitorPower(true)
+}
+
+// ---- 音量与媒体 ----
+
+func (s *Server) apiVolumeGet(r *http.Request) (interface{}, error) {
+	return s.volume.Get(), nil
+}
+
+// apiVolumeSet 请求体: {"volume": 0-100}
+func (s *Server) apiVolumeSet(r *http.Request) (interface{}, error) {
+	var req volumeRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	if req.Volume == nil || *req.Volume < 0 || *req.Volume > 100 {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "volume 必须是 0-100 之间的整数")
+	}
+	s.volume.Set(*req.Volume)
+	return s.volume.Get(), nil
+}
+
+func (s *Server) apiVolumeStep(up bool) apiFunc {
+	return func(r *http.Request) (interface{}, error) {
+		return nil, s.pressVolumeKey(up)
+	}
+}
+
+func (s *Server) apiVolumeMute(r *http.Request) (interface{}, error) {
+	if err := s.volume.ToggleMute(); err != nil {
+		return nil, err
+	}
+	return s.volume.Get(), nil
+}
+
+func apiMediaInfo(r *http.Request) (interface{}, error) {
+	return GetMediaInfo(), nil
+}
+
+func (s *Server) apiMediaCommand(r *http.Request) (interface{}, error) {
+	return nil, s.mediaCommand(r.PathValue("command"))
+}
+
+// ---- 剪贴板与输入 ----
+
+type textPayload struct {
+	Text string `json:"text"`
+}
+
+func (s *Server) apiClipboardGet(r *http.Request) (interface{}, error) {
+	text, err := s.backend.Clipboard.ReadText()
+	if err != nil {
+		return nil, err
+	}
+	return textPayload{Text: text}, nil
+}
+
+// apiClipboardSet 请求体: {"text": "..."}
+func (s *Server) apiClipboardSet(r *http.Request) (interface{}, error) {
+	var req textPayload
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return nil, s.writeClipboardText(req.Text, r.RemoteAddr)
+}
+
+// apiClipboardImage 以 multipart/form-data 的 image 字段上传图片。
+func (s *Server) apiClipboardImage(r *http.Request) (interface{}, error) {
+	file, _, err := r.FormFile("image")
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "缺少 image 文件: %v", err)
+	}
+	defer file.Close()
+	return nil, s.setClipboardImage(file)
+}
+
+// apiInputText 请求体: {"text": "..."}，写入剪贴板后粘贴到当前焦点窗口。
+func (s *Server) apiInputText(r *http.Request) (interface{}, error) {
+	var req textPayload
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	return nil, s.typeText(req.Text)
+}
+
+func (s *Server) apiInputPaste(r *http.Request) (interface{}, error) {
+	return nil, s.backend.Input.Paste()
+}
+
+// ---- 设置 ----
+
+// settingsView 返回不含令牌哈希、封禁列表和签名链接密钥的配置，
+// 前两者分别通过 /api/v1/tokens 和 /api/v1/security/blocked 管理。
+func settingsView() *bark.BarkConfig {
+	cfg := bark.GetConfig()
+	cfg.APITokens = nil
+	cfg.BlockedClients = nil
+	cfg.LinkSecret = ""
+	cfg.UsedLinkNonces = nil
+	return cfg
+}
+
+func apiSettingsGet(r *http.Request) (interface{}, error) {
+	return settingsView(), nil
+}
+
+// apiSettingsUpdate 请求体为 BarkConfig 的部分字段，未提供的字段保持不变。
+func apiSettingsUpdate(r *http.Request) (interface{}, error) {
+	var m map[string]interface{}
+	if err := decodeJSONBody(r, &m); err != nil {
+		return nil, err
+	}
+	if err := applySettings(m); err != nil {
+		return nil, err
+	}
+	return settingsView(), nil
+}
+
+// apiBarkTest 可选请求体: {"sound": "..."}
+func apiBarkTest(r *http.Request) (interface{}, error) {
+	var req barkTestRequest
+	if r.ContentLength > 0 {
+		if err := decodeJSONBody(r, &req); err != nil {
+			return nil, err
+		}
+	}
+	return nil, sendTestBark(req.Sound)
+}
+
+// ---- 模拟后端 ----
+
+func (s *Server) simulator() (*platform.Simulator, error) {
+	if s.backend.Simulator == nil {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "当前未使用模拟后端 (--backend=simulated)")
+	}
+	return s.backend.Simulator, nil
+}
+
+// apiSimulatorJournal 返回模拟后端的状态与操作日志。
+func (s *Server) apiSimulatorJournal(r *http.Request) (interface{}, error) {
+	sim, err := s.simulator()
+	if err != nil {
+		return nil, err
+	}
+	return simulatorJournal{Backend: s.backend.Name, State: sim.State(), Entries: sim.Journal()}, nil
+}
+
+// apiSimulatorClearJournal 清空操作日志，状态保持不变。
+func (s *Server) apiSimulatorClearJournal(r *http.Request) (interface{}, error) {
+	sim, err := s.simulator()
+	if err != nil {
+		return nil, err
+	}
+	sim.ClearJournal()
+	log.Printf("模拟后端操作日志已清空 (来自 %s)", r.RemoteAddr)
+	return nil, nil
+}
+
+// apiSimulatorConfirm 模拟本机用户在最早弹出的确认框上作答 (?result=approved|denied)。
+func (s *Server) apiSimulatorConfirm(r *http.Request) (interface{}, error) {
+	sim, err := s.simulator()
+	if err != nil {
+		return nil, err
+	}
+	result := platform.ConfirmResult(r.URL.Query().Get("result"))
+	if result != platform.ConfirmApproved && result != platform.ConfirmDenied {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "result 只能是 approved 或 denied")
+	}
+	if !sim.AnswerConfirm(result) {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "没有正在显示的确认框")
+	}
+	return nil, nil
+}
+
+// apiSimulatorDisplay 模拟系统自行改变显示器状态 (?off=true|false)，例如超时息屏或用户移动鼠标唤醒。
+func (s *Server) apiSimulatorDisplay(r *http.Request) (interface{}, error) {
+	sim, err := s.simulator()
+	if err != nil {
+		return nil, err
+	}
+	off, err := strconv.ParseBool(r.URL.Query().Get("off"))
+	if err != nil {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "off 只能是 true 或 false")
+	}
+	sim.SimulateMonitorState(off)
+	return displayState{MonitorOff: off}, nil
+}
+
+// apiSimulatorClickCancel 模拟用户点击本机倒计时窗口取消 (?action=sleep|shutdown)。
+func (s *Server) apiSimulatorClickCancel(r *http.Request) (interface{}, error) {
+	sim, err := s.simulator()
+	if err != nil {
+		return nil, err
+	}
+	if !sim.ClickCancel(r.URL.Query().Get("action")) {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "没有正在显示的倒计时窗口")
+	}
+	return nil, nil
+}
@@ -0,0 +1,364 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bealinkserver/audit"
+	"bealinkserver/auth"
+)
+
+const (
+	// auditBodyLimit 超过此大小的请求体不解析参数，只记录大小。
+	auditBodyLimit = 64 * 1024
+	// auditValueLimit 单个参数值和结果最多记录的字符数。
+	auditValueLimit = 200
+	// 查询接口默认和最多返回的条数。
+	auditDefaultLimit = 100
+	auditMaxLimit     = 1000
+)
+
+// auditRedactKeys 参数名包含这些词时只记录 "***"。
+var auditRedactKeys = []string{"token", "secret", "password", "key", "sig", "nonce", "code", "pin", "encryption_iv", "bark_full_url"}
+
+// auditLengthOnlyKeys 这些参数（剪贴板和输入的文本）只记录长度。
+var auditLengthOnlyKeys = map[string]bool{"text": true}
+
+type auditContextKey struct{}
+
+// auditRecord 审计中间件放在请求 context 中的记录，authorizeRequest 认证后填入请求方。
+type auditRecord struct {
+	client  string
+	tokenID string
+}
+
+// noteAuditIdentity 将认证得到的请求方记录到审计日志中。
+func noteAuditIdentity(r *http.Request, id *auth.Identity) {
+	if rec, ok := r.Context().Value(auditContextKey{}).(*auditRecord); ok && id != nil {
+		rec.client, rec.tokenID = id.Name, id.TokenID
+	}
+}
+
+// noteAuditClient 为不经过令牌认证的请求（如签名链接）记录请求方名称。
+func noteAuditClient(r *http.Request, client string) {
+	if rec, ok := r.Context().Value(auditContextKey{}).(*auditRecord); ok {
+		rec.client = client
+	}
+}
+
+// auditRecorder 记录响应状态码、响应体大小和开头部分。
+type auditRecorder struct {
+	http.ResponseWriter
+	status int
+	size   int
+	body   bytes.Buffer
+}
+
+// Unwrap 供 http.ResponseController 访问底层连接（如延长写超时）。
+func (w *auditRecorder) Unwrap() http.ResponseWriter { return w.ResponseWriter }
+
+func (w *auditRecorder) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *auditRecorder) Write(p []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	w.size += len(p)
+	if room := 4*auditValueLimit - w.body.Len(); room > 0 {
+		w.body.Write(p[:min(len(p), room)])
+	}
+	return w.ResponseWriter.Write(p)
+}
+
+// auditRequests 审计中间件：记录所有写操作，以及 auditGET 中通过 GET 执行操作的路由（旧客户端兼容和签名链接）。
+// 查询类的 GET 请求不记录。textBody 中的路由（如旧接口 /clip）请求体整体是文本，只记录长度；
+// hideResult 中的路由（剪贴板）成功读取时响应内容不写入日志，只记录大小。
+func (s *Server) auditRequests(mux *http.ServeMux, auditGET, textBody, hideResult map[string]bool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, pattern := mux.Handler(r)
+		if safeMethod(r.Method) && (r.Method != http.MethodGet || !auditGET[pattern]) {
+			mux.ServeHTTP(w, r)
+			return
+		}
+		start := time.Now()
+		params := auditParams(r, textBody[pattern])
+		rec := &auditRecord{}
+		rw := &auditRecorder{ResponseWriter: w}
+		mux.ServeHTTP(rw, r.WithContext(context.WithValue(r.Context(), auditContextKey{}, rec)))
+
+		if rw.status == 0 {
+			rw.status = http.StatusOK
+		}
+		result := auditResult(rw)
+		if hideResult[pattern] && safeMethod(r.Method) && rw.status < http.StatusBadRequest {
+			result = fmt.Sprintf("[%d 字节]", rw.size)
+		}
+		entry := audit.Entry{
+			Time:       start,
+			IP:         auth.ClientIP(r),
+			Client:     rec.client,
+			TokenID:    rec.tokenID,
+			UserAgent:  truncateRunes(r.UserAgent(), auditValueLimit),
+			Method:     r.Method,
+			Action:     r.URL.Path,
+			Params:     params,
+			Status:     rw.status,
+			Result:     result,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		if err := s.audit.Append(entry); err != nil {
+			log.Printf("[Audit] %v", err)
+		}
+	})
+}
+
+// auditParams 提取请求参数（查询参数、表单或 JSON 请求体的字段），敏感字段打码。
+// textBody 为 true 时请求体按纯文本处理。读取的请求体会放回 r.Body，不影响后续处理。
+func auditParams(r *http.Request, textBody bool) map[string]string {
+	params := make(map[string]string)
+	addFormParams(params, r.URL.Query())
+	if r.Body != nil && r.Body != http.NoBody {
+		mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+		switch {
+		case strings.HasPrefix(mediaType, "multipart/"):
+			params["body"] = fmt.Sprintf("[%s, %d 字节]", mediaType, r.ContentLength)
+		case r.ContentLength > auditBodyLimit:
+			params["body"] = fmt.Sprintf("[%d 字节]", r.ContentLength)
+		default:
+			body, err := io.ReadAll(io.LimitReader(r.Body, auditBodyLimit+1))
+			r.Body = io.NopCloser(io.MultiReader(bytes.NewReader(body), r.Body))
+			if err != nil || len(body) > auditBodyLimit {
+				params["body"] = fmt.Sprintf("[%d 字节以上]", len(body))
+				break
+			}
+			addBodyParams(params, mediaType, body, textBody)
+		}
+	}
+	for k, v := range params {
+		params[k] = redactParam(k, v)
+	}
+	if len(params) == 0 {
+		return nil
+	}
+	return params
+}
+
+// addBodyParams 按处理函数的方式解析请求体：先尝试 JSON 对象（不论 Content-Type），
+// 再按表单解析；都不成立时整个请求体按 text 参数处理（只记录长度），不会原样写入日志。
+func addBodyParams(params map[string]string, mediaType string, body []byte, textBody bool) {
+	if len(bytes.TrimSpace(body)) == 0 {
+		return
+	}
+	if textBody {
+		params["text"] = string(body)
+		return
+	}
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if dec.Decode(&m) == nil && !dec.More() {
+		addJSONParams(params, "", m)
+		return
+	}
+	if mediaType == "application/x-www-form-urlencoded" {
+		if form, err := url.ParseQuery(string(body)); err == nil && validParamNames(form) {
+			addFormParams(params, form)
+			return
+		}
+	}
+	// 纯文本请求体（如旧接口 /text）按 text 参数处理
+	params["text"] = string(body)
+}
+
+// addJSONParams 将 JSON 对象的字段展开为 a.b 形式的参数，数组按下标展开。
+func addJSONParams(params map[string]string, prefix string, v interface{}) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, child := range v {
+			if prefix != "" {
+				k = prefix + "." + k
+			}
+			addJSONParams(params, k, child)
+		}
+	case []interface{}:
+		for i, child := range v {
+			addJSONParams(params, prefix+"."+strconv.Itoa(i), child)
+		}
+	case nil:
+		params[auditParamName(prefix)] = "null"
+	default:
+		params[auditParamName(prefix)] = fmt.Sprint(v)
+	}
+}
+
+func addFormParams(params map[string]string, form url.Values) {
+	for k, v := range form {
+		params[auditParamName(k)] = strings.Join(v, ",")
+	}
+}
+
+// auditParamPattern 正常的参数名；不符合的（如被误当作表单解析的文本片段）不写入日志。
+var auditParamPattern = regexp.MustCompile(`^[A-Za-z0-9_.\-\[\]]{1,64}$`)
+
+// auditParamName 返回可写入审计日志的参数名，不正常的参数名统一替换为 "?"，其值也会被打码。
+func auditParamName(k string) string {
+	if auditParamPattern.MatchString(k) {
+		return k
+	}
+	return "?"
+}
+
+func validParamNames(form url.Values) bool {
+	for k := range form {
+		if !auditParamPattern.MatchString(k) {
+			return false
+		}
+	}
+	return true
+}
+
+func redactParam(key, value string) string {
+	lower := strings.ToLower(key)
+	// JSON 展开的参数按最后一段判断，如 items.0.text
+	if auditLengthOnlyKeys[lower[strings.LastIndex(lower, ".")+1:]] {
+		return fmt.Sprintf("[%d 字符]", utf8.RuneCountInString(value))
+	}
+	if key == "?" {
+		return "***"
+	}
+	for _, k := range auditRedactKeys {
+		if strings.Contains(lower, k) && value != "" {
+			return "***"
+		}
+	}
+	return truncateRunes(value, auditValueLimit)
+}
+
+// auditResult 从响应中提取结果：/api/v1 接口返回 ok 或错误信息，其余返回响应文本的开头部分。
+func auditResult(rw *auditRecorder) string {
+	body := bytes.TrimSpace(rw.body.Bytes())
+	var envelope struct {
+		OK    bool `json:"ok"`
+		Error *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if strings.HasPrefix(rw.Header().Get("Content-Type"), "application/json") && json.Unmarshal(body, &envelope) == nil {
+		if envelope.Error != nil {
+			return truncateRunes(envelope.Error.Message, auditValueLimit)
+		}
+		if envelope.OK {
+			return "ok"
+		}
+	}
+	if len(body) == 0 || !utf8.Valid(body) {
+		return http.StatusText(rw.status)
+	}
+	return truncateRunes(string(body), auditValueLimit)
+}
+
+func truncateRunes(s string, n int) string {
+	if utf8.RuneCountInString(s) <= n {
+		return s
+	}
+	return string([]rune(s)[:n]) + "…"
+}
+
+// auditFilter 从查询参数解析过滤条件：since / until (RFC 3339 或 Unix 秒)、ip、client、action、result (ok|error)、limit。
+func auditFilter(q url.Values, defaultLimit int) (audit.Filter, error) {
+	f := audit.Filter{IP: q.Get("ip"), Client: q.Get("client"), Action: q.Get("action"), Result: q.Get("result"), Limit: defaultLimit}
+	var err error
+	if f.Since, err = parseAuditTime(q.Get("since")); err != nil {
+		return f, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "since %v", err)
+	}
+	if f.Until, err = parseAuditTime(q.Get("until")); err != nil {
+		return f, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "until %v", err)
+	}
+	if f.Result != "" && f.Result != "ok" && f.Result != "error" {
+		return f, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "result 只能是 ok 或 error")
+	}
+	if v := q.Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > auditMaxLimit {
+			return f, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "limit 必须是 1-%d 之间的整数", auditMaxLimit)
+		}
+		f.Limit = n
+	}
+	return f, nil
+}
+
+func parseAuditTime(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if sec, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(sec, 0), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("必须是 RFC 3339 时间或 Unix 秒: %s", v)
+	}
+	return t, nil
+}
+
+// apiAuditList 按条件查询审计日志，按时间从新到旧返回。
+func (s *Server) apiAuditList(r *http.Request) (interface{}, error) {
+	f, err := auditFilter(r.URL.Query(), auditDefaultLimit)
+	if err != nil {
+		return nil, err
+	}
+	return s.audit.Query(f)
+}
+
+// handleAuditExport 导出审计日志 (?format=csv|jsonl，过滤参数同 /api/v1/audit，默认不限制条数)。
+func (s *Server) handleAuditExport(w http.ResponseWriter, r *http.Request) {
+	f, err := auditFilter(r.URL.Query(), 0)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "csv"
+	}
+	write, contentType := audit.WriteCSV, "text/csv; charset=utf-8"
+	switch format {
+	case "csv":
+	case "jsonl":
+		write, contentType = audit.WriteJSONL, "application/x-ndjson"
+	default:
+		writeAPIError(w, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "format 只能是 csv 或 jsonl"))
+		return
+	}
+	entries, err := s.audit.Query(f)
+	if err != nil {
+		writeAPIError(w, err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	if format == "csv" {
+		// 带 BOM，Excel 才能正确识别 UTF-8 编码的中文
+		w.Write([]byte("\ufeff"))
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="bealink_audit_%s.%s"`, time.Now().Format("20060102_150405"), format))
+	if err := write(w, entries); err != nil {
+		log.Printf("[Audit] 导出审计日志失败: %v", err)
+	}
+}
@@ -0,0 +1,107 @@
+package server
+
+import (
+	"net/http"
+	"os"
+	"strings"
+	"testing"
+
+	"bealinkserver/audit"
+	"bealinkserver/bark"
+)
+
+// TestAuditNeverLogsSecrets 不论请求体格式和 Content-Type 是否一致，剪贴板文本、加密密钥、
+// Bark 设备密钥和配对码都不能出现在审计日志中。
+func TestAuditNeverLogsSecrets(t *testing.T) {
+	s, h, _ := newTestServer(t)
+	os.Remove(s.audit.Path())
+	// 要求令牌，设置接口不会真正执行，但请求仍会记入审计日志
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, true
+	}, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, false
+	})
+
+	const (
+		clipText  = "CLIPSECRET-abc"
+		encKey    = "ENCKEY0123456789"
+		deviceKey = "DEVICEKEYxyz987"
+		pairCode  = "482913"
+	)
+	settingsJSON := `{"bark_full_url":"https://api.day.app/` + deviceKey + `/","encryption_key":"` + encKey + `","sound":"bell"}`
+	requests := []struct {
+		method, path, contentType, body string
+	}{
+		{http.MethodPost, "/setting", "application/x-www-form-urlencoded", settingsJSON},
+		{http.MethodPost, "/setting", "application/json", settingsJSON},
+		{http.MethodPost, "/setting", "application/x-www-form-urlencoded", "bark_full_url=https%3A%2F%2Fapi.day.app%2F" + deviceKey + "&encryption_key=" + encKey},
+		{http.MethodPost, "/api/v1/settings", "text/plain", `{"options":{"encryption_key":"` + encKey + `"}}`},
+		{http.MethodPost, "/clip", "text/plain", clipText},
+		{http.MethodPost, "/clip", "application/x-www-form-urlencoded", clipText + " with spaces"},
+		{http.MethodPost, "/clip", "application/x-www-form-urlencoded", "note=" + clipText},
+		{http.MethodPost, "/text", "application/x-www-form-urlencoded", "text=" + clipText},
+		{http.MethodPut, "/api/v1/clipboard", "application/x-www-form-urlencoded", `{"text":"` + clipText + `"}`},
+		{http.MethodPost, "/api/v1/pair/complete", "application/x-www-form-urlencoded", `{"id":"abc","code":"` + pairCode + `"}`},
+		{http.MethodPost, "/api/v1/pair/complete?" + clipText + "+as+query", "", ""},
+	}
+	for _, req := range requests {
+		r := lanRequest(req.method, req.path, strings.NewReader(req.body))
+		if req.contentType != "" {
+			r.Header.Set("Content-Type", req.contentType)
+		}
+		serve(h, r)
+	}
+
+	data, err := os.ReadFile(s.audit.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	log := string(data)
+	if n := strings.Count(log, "\n"); n != len(requests) {
+		t.Errorf("审计日志条数 = %d, 期望 %d", n, len(requests))
+	}
+	for _, secret := range []string{"CLIPSECRET", encKey, deviceKey, pairCode} {
+		if strings.Contains(log, secret) {
+			t.Errorf("审计日志中出现了敏感内容 %q:\n%s", secret, log)
+		}
+	}
+	if !strings.Contains(log, `"sound":"bell"`) {
+		t.Errorf("普通参数应照常记录:\n%s", log)
+	}
+}
+
+func TestAuditClipboardReads(t *testing.T) {
+	s, h, sim := newTestServer(t)
+	os.Remove(s.audit.Path())
+	const clipText = "CLIPSECRET-read"
+	sim.WriteText(clipText)
+
+	paths := []string{"/getclip", "/clip", "/api/v1/clipboard"}
+	for _, path := range paths {
+		if w := serve(h, localRequest(http.MethodGet, path, nil)); w.Code != http.StatusOK {
+			t.Fatalf("GET %s 状态码 = %d", path, w.Code)
+		}
+	}
+	// 其他查询类 GET 请求不记录
+	serve(h, localRequest(http.MethodGet, "/api/v1/volume", nil))
+
+	entries, err := s.audit.Query(audit.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != len(paths) {
+		t.Fatalf("审计日志条数 = %d, 期望 %d: %+v", len(entries), len(paths), entries)
+	}
+	for _, e := range entries {
+		if e.Method != http.MethodGet || !strings.HasPrefix(e.Result, "[") || !strings.HasSuffix(e.Result, "字节]") {
+			t.Errorf("%s 的审计记录 = %+v, 期望只记录响应大小", e.Action, e)
+		}
+	}
+	data, err := os.ReadFile(s.audit.Path())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "CLIPSECRET") {
+		t.Errorf("审计日志中出现了剪贴板内容:\n%s", data)
+	}
+}
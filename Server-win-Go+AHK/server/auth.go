@@ -0,0 +1,227 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"bealinkserver/auth"
+	"bealinkserver/platform"
+)
+
+// authorizeRequest 检查请求方是否拥有 permission 权限，通过时返回携带请求方信息的请求（见 auth.FromContext）。
+// 失败时返回 401 / 403 的 APIError，并为 401 设置 WWW-Authenticate 响应头。
+func authorizeRequest(w http.ResponseWriter, r *http.Request, permission string) (*http.Request, error) {
+	return authorizeRequestWith(w, r, permission, auth.Authorize)
+}
+
+// authorizeRequestWith 同 authorizeRequest，使用 authorize 识别请求方（旧接口为 auth.AuthorizeLegacy）。
+func authorizeRequestWith(w http.ResponseWriter, r *http.Request, permission string, authorize func(*http.Request, string) (*auth.Identity, error)) (*http.Request, error) {
+	id, err := authorize(r, permission)
+	noteAuditIdentity(r, id)
+	switch {
+	case errors.Is(err, auth.ErrUnauthorized):
+		log.Printf("[Auth] 拒绝未认证的请求: %s %s (来自 %s)", r.Method, r.URL.Path, r.RemoteAddr)
+		w.Header().Set("WWW-Authenticate", `Bearer realm="bealink"`)
+		return r, apiErrorf(http.StatusUnauthorized, ErrCodeUnauthorized, "%v", err)
+	case errors.Is(err, auth.ErrForbidden):
+		log.Printf("[Auth] 令牌 %q 缺少 %s 权限: %s %s (来自 %s)", id.Name, permission, r.Method, r.URL.Path, r.RemoteAddr)
+		return r, apiErrorf(http.StatusForbidden, ErrCodeForbidden, "%v: %s", err, permission)
+	case err != nil:
+		return r, err
+	}
+	if id != nil {
+		r = r.WithContext(auth.WithIdentity(r.Context(), id))
+	}
+	return r, nil
+}
+
+// withPermission 为旧路由和页面添加请求方法、权限和操作策略检查 (policy 可为 nil)。
+// 页面 (text/html) 未认证时返回令牌输入页，其余返回纯文本错误。
+// 旧接口开启 legacy_allow_no_token 时无需令牌，见 auth.AuthorizeLegacy。
+func withPermission(rt route, policy policyFunc) http.HandlerFunc {
+	authorize := auth.Authorize
+	if rt.Tag == tagLegacy {
+		authorize = auth.AuthorizeLegacy
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		if rt.LegacyGET && r.Method != http.MethodPost && !legacyGETAllowed(r) {
+			w.Header().Set("Allow", http.MethodPost)
+			http.Error(w, "此操作只接受 POST 请求（旧客户端可在设置中开启「允许旧客户端使用 GET」，跨站 GET 请求始终被拒绝）", http.StatusMethodNotAllowed)
+			return
+		}
+		r, err := authorizeRequestWith(w, r, rt.Permission, authorize)
+		if err != nil {
+			apiErr := toAPIError(err)
+			if rt.ContentType == "text/html" && apiErr.Status == http.StatusUnauthorized {
+				serveLoginPage(w)
+				return
+			}
+			http.Error(w, apiErr.Message, apiErr.Status)
+			return
+		}
+		if policy != nil {
+			if err := policy(w, r); err != nil {
+				apiErr := toAPIError(err)
+				http.Error(w, apiErr.Message, apiErr.Status)
+				return
+			}
+		}
+		rt.handler(w, r)
+	}
+}
+
+// serveLoginPage 返回令牌输入页，输入后带 access_token 重新打开当前页面。
+func serveLoginPage(w http.ResponseWriter) {
+	htmlContent, err := templateFS.ReadFile("html/login.html")
+	if err != nil {
+		log.Printf("错误: 从 embed.FS 读取 login.html 失败: %v", err)
+		http.Error(w, "未提供有效的访问令牌", http.StatusUnauthorized)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnauthorized)
+	w.Write(htmlContent)
+}
+
+// handleAuthJS 返回页面共用的 auth.js：为 fetch 和 WebSocket 附加本地保存的访问令牌。
+func handleAuthJS(w http.ResponseWriter, r *http.Request) {
+	content, err := templateFS.ReadFile("html/auth.js")
+	if err != nil {
+		log.Printf("错误: 从 embed.FS 读取 auth.js 失败: %v", err)
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "application/javascript; charset=utf-8")
+	w.Write(content)
+}
+
+// ---- 令牌管理接口 ----
+
+// tokenCreateRequest 创建令牌的请求体。
+type tokenCreateRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes"`
+}
+
+// createdToken 新建令牌的响应，Token 为明文，只返回这一次。
+type createdToken struct {
+	Token string         `json:"token"`
+	Info  auth.TokenInfo `json:"info"`
+}
+
+func apiTokenList(r *http.Request) (interface{}, error) {
+	return auth.ListTokens(), nil
+}
+
+func apiTokenScopes(r *http.Request) (interface{}, error) {
+	return auth.Scopes, nil
+}
+
+// apiTokenCreate 请求体: {"name": "...", "scopes": ["power", ...]}
+func apiTokenCreate(r *http.Request) (interface{}, error) {
+	var req tokenCreateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	plain, info, err := auth.CreateToken(req.Name, req.Scopes)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return createdToken{Token: plain, Info: info}, nil
+}
+
+func apiTokenRevoke(r *http.Request) (interface{}, error) {
+	err := auth.RevokeToken(r.PathValue("id"))
+	if errors.Is(err, auth.ErrTokenNotFound) {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "%v: %s", err, r.PathValue("id"))
+	}
+	return nil, err
+}
+
+// ---- 认证失败封禁 ----
+
+func apiBlockedList(r *http.Request) (interface{}, error) {
+	return auth.ListBlocked(), nil
+}
+
+func apiBlockedClear(r *http.Request) (interface{}, error) {
+	return nil, auth.Unblock("")
+}
+
+func apiBlockedRemove(r *http.Request) (interface{}, error) {
+	err := auth.Unblock(r.PathValue("ip"))
+	if errors.Is(err, auth.ErrBlockNotFound) {
+		return nil, apiErrorf(http.StatusNotFound, ErrCodeNotFound, "%v: %s", err, r.PathValue("ip"))
+	}
+	return nil, err
+}
+
+// ---- 设备配对 ----
+
+// pairNotifier 返回在本机弹出配对码通知的函数。
+func pairNotifier(notifier platform.Notifier) auth.PairNotifyFunc {
+	return func(req auth.PairRequest, code string) error {
+		message := fmt.Sprintf("配对码: %s\n设备: %s (%s)\n权限: %s\n如非本人操作请忽略。",
+			code, req.Name, req.Remote, strings.Join(req.Scopes, ", "))
+		return notifier.Notify("Bealink 设备配对", message, auth.PairCodeTTL)
+	}
+}
+
+// pairStartRequest 发起配对的请求体，scopes 为空时申请除 admin:settings 外的全部权限。
+type pairStartRequest struct {
+	Name   string   `json:"name"`
+	Scopes []string `json:"scopes,omitempty"`
+}
+
+// pairCompleteRequest 完成配对的请求体。
+type pairCompleteRequest struct {
+	ID   string `json:"id"`
+	Code string `json:"code"`
+}
+
+// pairError 将配对错误转换为 APIError。
+func pairError(err error) error {
+	switch {
+	case errors.Is(err, auth.ErrInvalidToken):
+		return apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%v", err)
+	case errors.Is(err, auth.ErrPairNotFound):
+		return apiErrorf(http.StatusNotFound, ErrCodeNotFound, "%v", err)
+	case errors.Is(err, auth.ErrPairCodeMismatch):
+		return apiErrorf(http.StatusForbidden, ErrCodeForbidden, "%v", err)
+	case errors.Is(err, auth.ErrPairRateLimited):
+		return apiErrorf(http.StatusTooManyRequests, ErrCodeRateLimited, "%v", err)
+	}
+	return err
+}
+
+// apiPairStart 请求体: {"name": "设备名", "scopes": [...]}，本机随即弹出 6 位配对码。
+func (s *Server) apiPairStart(r *http.Request) (interface{}, error) {
+	var req pairStartRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	pending, err := s.pairing.Start(req.Name, req.Scopes, auth.ClientIP(r))
+	if err != nil {
+		return nil, pairError(err)
+	}
+	return pending, nil
+}
+
+// apiPairComplete 请求体: {"id": "...", "code": "123456"}，成功时返回令牌明文（只返回这一次）。
+func (s *Server) apiPairComplete(r *http.Request) (interface{}, error) {
+	var req pairCompleteRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	plain, info, err := s.pairing.Complete(req.ID, req.Code, auth.ClientIP(r))
+	if err != nil {
+		return nil, pairError(err)
+	}
+	return createdToken{Token: plain, Info: info}, nil
+}
@@ -0,0 +1,134 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+)
+
+// withToken 为请求添加 Authorization: Bearer 头。
+func withToken(r *http.Request, token string) *http.Request {
+	r.Header.Set("Authorization", "Bearer "+token)
+	return r
+}
+
+// createTestToken 通过 /api/v1/tokens 创建令牌，返回明文和 ID。
+func createTestToken(t *testing.T, h http.Handler, name string, scopes ...string) (string, string) {
+	t.Helper()
+	body, _ := json.Marshal(tokenCreateRequest{Name: name, Scopes: scopes})
+	r := localRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(string(body)))
+	r.Header.Set("Content-Type", "application/json")
+	w := serve(h, r)
+	var resp struct {
+		OK   bool         `json:"ok"`
+		Data createdToken `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || !resp.OK || resp.Data.Token == "" {
+		t.Fatalf("创建令牌失败 (%d): %s", w.Code, w.Body.String())
+	}
+	return resp.Data.Token, resp.Data.Info.ID
+}
+
+// listedTokenIDs 通过 /api/v1/tokens 列出令牌 ID。
+func listedTokenIDs(t *testing.T, h http.Handler) map[string]bool {
+	t.Helper()
+	w := serve(h, localRequest(http.MethodGet, "/api/v1/tokens", nil))
+	var resp struct {
+		Data []auth.TokenInfo `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("列出令牌失败 (%d): %s", w.Code, w.Body.String())
+	}
+	ids := map[string]bool{}
+	for _, ti := range resp.Data {
+		ids[ti.ID] = true
+	}
+	return ids
+}
+
+func TestTokenAPI(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	t.Cleanup(func() { auth.Unblock("") })
+
+	r := localRequest(http.MethodPost, "/api/v1/tokens", strings.NewReader(`{"name":"手机","scopes":["root"]}`))
+	r.Header.Set("Content-Type", "application/json")
+	if w := serve(h, r); w.Code != http.StatusBadRequest || apiErrorCode(t, w.Body.String()) != ErrCodeInvalidParameter {
+		t.Errorf("未知权限范围应返回 400 invalid_parameter，实际 %d: %s", w.Code, w.Body.String())
+	}
+
+	plain, id := createTestToken(t, h, "手机", auth.ScopePower)
+	if !listedTokenIDs(t, h)[id] {
+		t.Errorf("GET /api/v1/tokens 中没有新令牌 %s", id)
+	}
+	if w := serve(h, withToken(lanRequest(http.MethodGet, "/api/v1/power", nil), plain)); w.Code != http.StatusOK {
+		t.Errorf("使用新令牌访问的状态码 = %d, 期望 200", w.Code)
+	}
+
+	// 列出和吊销令牌需要 admin:settings 权限
+	if w := serve(h, withToken(lanRequest(http.MethodGet, "/api/v1/tokens", nil), plain)); w.Code != http.StatusForbidden {
+		t.Errorf("没有管理权限的令牌列出令牌的状态码 = %d, 期望 403", w.Code)
+	}
+
+	if w := serve(h, localRequest(http.MethodDelete, "/api/v1/tokens/"+id, nil)); w.Code != http.StatusOK {
+		t.Fatalf("吊销令牌的状态码 = %d: %s", w.Code, w.Body.String())
+	}
+	if listedTokenIDs(t, h)[id] {
+		t.Error("吊销后令牌仍在列表中")
+	}
+	if w := serve(h, localRequest(http.MethodDelete, "/api/v1/tokens/"+id, nil)); w.Code != http.StatusNotFound {
+		t.Errorf("再次吊销的状态码 = %d, 期望 404", w.Code)
+	}
+	w := serve(h, withToken(lanRequest(http.MethodGet, "/api/v1/power", nil), plain))
+	if w.Code != http.StatusUnauthorized || w.Header().Get("WWW-Authenticate") == "" {
+		t.Errorf("已吊销令牌的状态码 = %d (WWW-Authenticate: %q), 期望 401", w.Code, w.Header().Get("WWW-Authenticate"))
+	}
+}
+
+func TestAuthorizeScopeEnforcement(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	t.Cleanup(func() { auth.Unblock("") })
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, false
+	}, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, false
+	})
+	power, _ := createTestToken(t, h, "电源", auth.ScopePower)
+
+	tests := []struct {
+		name   string
+		target string
+		token  string
+		status int
+		code   string
+	}{
+		{"没有令牌", "/api/v1/power", "", http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"无效令牌", "/api/v1/power", "bl_invalid", http.StatusUnauthorized, ErrCodeUnauthorized},
+		{"令牌拥有权限", "/api/v1/power", power, http.StatusOK, ""},
+		{"令牌缺少权限", "/api/v1/clipboard", power, http.StatusForbidden, ErrCodeForbidden},
+		{"旧接口没有令牌", "/getclip", "", http.StatusUnauthorized, ""},
+		{"旧接口令牌缺少权限", "/getclip", power, http.StatusForbidden, ""},
+		{"无需权限的接口", "/api/v1/ping", "", http.StatusOK, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := lanRequest(http.MethodGet, tt.target, nil)
+			if tt.token != "" {
+				withToken(r, tt.token)
+			}
+			w := serve(h, r)
+			if w.Code != tt.status {
+				t.Errorf("GET %s 状态码 = %d, 期望 %d (响应: %s)", tt.target, w.Code, tt.status, w.Body.String())
+			}
+			if tt.code != "" && apiErrorCode(t, w.Body.String()) != tt.code {
+				t.Errorf("GET %s 错误码 = %q, 期望 %q", tt.target, apiErrorCode(t, w.Body.String()), tt.code)
+			}
+			if tt.status == http.StatusUnauthorized && w.Header().Get("WWW-Authenticate") == "" {
+				t.Errorf("GET %s 返回 401 时应设置 WWW-Authenticate", tt.target)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package server
+
+import (
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bealinkserver/bark"
+	"bealinkserver/power"
+)
+
+const (
+	// maxCountdownSec 设置中允许的最长倒计时（秒）。
+	maxCountdownSec = 24 * 60 * 60
+	// maxReasonRunes 倒计时原因的最大字符数，过长会撑破本机倒计时窗口。
+	maxReasonRunes = 100
+)
+
+// powerStartRequest 启动电源倒计时的可选参数：seconds 为 0 时使用设置中该操作的默认时长，
+// reason 会显示在本机倒计时窗口上。
+type powerStartRequest struct {
+	Seconds int    `json:"seconds,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+}
+
+// countdownSetting 设置页面中一个电源操作的默认倒计时。
+type countdownSetting struct {
+	Name        string
+	Description string
+	Seconds     int
+}
+
+// defaultCountdownSeconds 返回 action 在设置中的默认倒计时时长（秒）。
+func defaultCountdownSeconds(action string) int {
+	if sec, ok := bark.GetConfig().CountdownSeconds[action]; ok && sec > 0 {
+		return sec
+	}
+	return bark.DefaultCountdownSec
+}
+
+// countdownSettings 返回各电源操作当前的默认倒计时，顺序同 actionParam。
+func countdownSettings() []countdownSetting {
+	list := make([]countdownSetting, len(actionParam.Enum))
+	for i, name := range actionParam.Enum {
+		list[i] = countdownSetting{Name: name, Description: policyDescription(name), Seconds: defaultCountdownSeconds(name)}
+	}
+	return list
+}
+
+// newPowerRequest 校验请求中的倒计时时长和原因：seconds 必须在设置的范围内，
+// reason 中的换行等控制字符替换为空格，且不能超过 maxReasonRunes 个字符。
+func newPowerRequest(action string, req powerStartRequest, source string) (power.Request, error) {
+	cfg := bark.GetConfig()
+	if req.Seconds != 0 && (req.Seconds < cfg.CountdownMinSec || req.Seconds > cfg.CountdownMaxSec) {
+		return power.Request{}, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "seconds 必须是 %d-%d 之间的整数", cfg.CountdownMinSec, cfg.CountdownMaxSec)
+	}
+	reason := strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return ' '
+		}
+		return r
+	}, req.Reason))
+	if utf8.RuneCountInString(reason) > maxReasonRunes {
+		return power.Request{}, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "reason 不能超过 %d 个字符", maxReasonRunes)
+	}
+	return power.Request{Action: action, Seconds: req.Seconds, Reason: reason, Source: source}, nil
+}
+
+// formPowerRequest 从旧接口的查询参数或表单 (seconds、reason) 读取倒计时参数。
+func formPowerRequest(r *http.Request, action, source string) (power.Request, error) {
+	var req powerStartRequest
+	if v := strings.TrimSpace(r.FormValue("seconds")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			cfg := bark.GetConfig()
+			return power.Request{}, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "seconds 必须是 %d-%d 之间的整数", cfg.CountdownMinSec, cfg.CountdownMaxSec)
+		}
+		req.Seconds = n
+	}
+	req.Reason = r.FormValue("reason")
+	return newPowerRequest(action, req, source)
+}
+
+// countdownSettingsFrom 校验设置中的 countdown_min_sec、countdown_max_sec 和 countdown_seconds（各操作的默认时长），
+// 未提供的项沿用当前配置。返回值中 seconds 为 nil 表示未修改默认时长。
+func countdownSettingsFrom(m map[string]interface{}) (minSec, maxSec int, seconds map[string]int, err error) {
+	cfg := bark.GetConfig()
+	minSec, maxSec = cfg.CountdownMinSec, cfg.CountdownMaxSec
+	if v, ok := m["countdown_min_sec"]; ok {
+		if minSec, err = intSetting(v); err != nil || minSec < 1 || minSec > maxCountdownSec {
+			return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_min_sec 必须是 1-%d 之间的整数", maxCountdownSec)
+		}
+	}
+	if v, ok := m["countdown_max_sec"]; ok {
+		if maxSec, err = intSetting(v); err != nil || maxSec < 1 || maxSec > maxCountdownSec {
+			return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_max_sec 必须是 1-%d 之间的整数", maxCountdownSec)
+		}
+	}
+	if minSec > maxSec {
+		return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_min_sec 不能大于 countdown_max_sec")
+	}
+
+	v, ok := m["countdown_seconds"]
+	if !ok || v == nil {
+		return minSec, maxSec, nil, nil
+	}
+	values, ok := v.(map[string]interface{})
+	if !ok {
+		return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_seconds 必须是对象")
+	}
+	seconds = make(map[string]int)
+	for action, v := range values {
+		if !slices.Contains(actionParam.Enum, action) {
+			return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_seconds: 未知的操作 %s", action)
+		}
+		sec, err := intSetting(v)
+		if err != nil || sec < minSec || sec > maxSec {
+			return 0, 0, nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "countdown_seconds.%s 必须是 %d-%d 之间的整数", action, minSec, maxSec)
+		}
+		seconds[action] = sec
+	}
+	return minSec, maxSec, seconds, nil
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"bealinkserver/bark"
+)
+
+// setCountdownRange 将倒计时范围设为 minSec-maxSec，各操作默认时长设为 seconds，测试结束时恢复默认值。
+func setCountdownRange(t *testing.T, minSec, maxSec int, seconds map[string]int) {
+	t.Helper()
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.CountdownMinSec, cfg.CountdownMaxSec, cfg.CountdownSeconds = minSec, maxSec, seconds
+	}, func(cfg *bark.BarkConfig) {
+		cfg.CountdownMinSec, cfg.CountdownMaxSec, cfg.CountdownSeconds = bark.DefaultCountdownMinSec, bark.DefaultCountdownMaxSec, nil
+	})
+}
+
+func TestNewPowerRequest(t *testing.T) {
+	setCountdownRange(t, 10, 600, nil)
+	tests := []struct {
+		name        string
+		req         powerStartRequest
+		wantErr     bool
+		wantSeconds int
+		wantReason  string
+	}{
+		{name: "默认时长", req: powerStartRequest{}},
+		{name: "下限", req: powerStartRequest{Seconds: 10}, wantSeconds: 10},
+		{name: "上限", req: powerStartRequest{Seconds: 600}, wantSeconds: 600},
+		{name: "低于下限", req: powerStartRequest{Seconds: 9}, wantErr: true},
+		{name: "超过上限", req: powerStartRequest{Seconds: 601}, wantErr: true},
+		{name: "负数", req: powerStartRequest{Seconds: -1}, wantErr: true},
+		{name: "原因去掉首尾空白", req: powerStartRequest{Reason: "  更新系统  "}, wantReason: "更新系统"},
+		{name: "控制字符替换为空格", req: powerStartRequest{Reason: "第一行\n第二行\t\x07结束"}, wantReason: "第一行 第二行  结束"},
+		{name: "原因 100 个字符", req: powerStartRequest{Reason: strings.Repeat("关", maxReasonRunes)}, wantReason: strings.Repeat("关", maxReasonRunes)},
+		{name: "原因超过 100 个字符", req: powerStartRequest{Reason: strings.Repeat("关", maxReasonRunes+1)}, wantErr: true},
+		{name: "去掉首尾空白后不超过 100 个字符", req: powerStartRequest{Reason: strings.Repeat("a", maxReasonRunes) + "\n"}, wantReason: strings.Repeat("a", maxReasonRunes)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := newPowerRequest("shutdown", tt.req, "测试")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望出错，实际: %+v", got)
+				}
+				if apiErr := toAPIError(err); apiErr.Status != http.StatusBadRequest || apiErr.Code != ErrCodeInvalidParameter {
+					t.Errorf("错误 = %+v, 期望 400 invalid_parameter", apiErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Action != "shutdown" || got.Source != "测试" || got.Seconds != tt.wantSeconds || got.Reason != tt.wantReason {
+				t.Errorf("请求 = %+v, 期望 seconds=%d reason=%q", got, tt.wantSeconds, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestFormPowerRequest(t *testing.T) {
+	setCountdownRange(t, 10, 600, nil)
+	tests := []struct {
+		name        string
+		query       string
+		body        string
+		wantErr     bool
+		wantSeconds int
+		wantReason  string
+	}{
+		{name: "无参数", query: ""},
+		{name: "查询参数", query: "seconds=60&reason=" + url.QueryEscape("更新系统"), wantSeconds: 60, wantReason: "更新系统"},
+		{name: "表单", body: "seconds=+30+&reason=" + url.QueryEscape("a\r\nb"), wantSeconds: 30, wantReason: "a  b"},
+		{name: "空 seconds 使用默认时长", query: "seconds=", wantSeconds: 0},
+		{name: "零", query: "seconds=0", wantErr: true},
+		{name: "负数", query: "seconds=-5", wantErr: true},
+		{name: "非数字", query: "seconds=abc", wantErr: true},
+		{name: "小数", query: "seconds=1.5", wantErr: true},
+		{name: "超出范围", query: "seconds=601", wantErr: true},
+		{name: "原因过长", query: "reason=" + strings.Repeat("x", maxReasonRunes+1), wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var r *http.Request
+			if tt.body != "" {
+				r = httptest.NewRequest(http.MethodPost, "/shutdown", strings.NewReader(tt.body))
+				r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			} else {
+				r = httptest.NewRequest(http.MethodPost, "/shutdown?"+tt.query, nil)
+			}
+			got, err := formPowerRequest(r, "shutdown", "测试")
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("期望出错，实际: %+v", got)
+				}
+				if apiErr := toAPIError(err); apiErr.Status != http.StatusBadRequest || !strings.Contains(apiErr.Message, "10-600") && !strings.Contains(apiErr.Message, "reason") {
+					t.Errorf("错误 = %+v", apiErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Seconds != tt.wantSeconds || got.Reason != tt.wantReason {
+				t.Errorf("请求 = %+v, 期望 seconds=%d reason=%q", got, tt.wantSeconds, tt.wantReason)
+			}
+		})
+	}
+}
+
+func TestDefaultCountdownSeconds(t *testing.T) {
+	setCountdownRange(t, 10, 600, map[string]int{"shutdown": 120, "sleep": 0})
+	tests := []struct {
+		action string
+		want   int
+	}{
+		{"shutdown", 120},
+		{"sleep", bark.DefaultCountdownSec}, // 0 视为未设置
+		{"restart", bark.DefaultCountdownSec},
+	}
+	for _, tt := range tests {
+		if got := defaultCountdownSeconds(tt.action); got != tt.want {
+			t.Errorf("defaultCountdownSeconds(%q) = %d, 期望 %d", tt.action, got, tt.want)
+		}
+	}
+}
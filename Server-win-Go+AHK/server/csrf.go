@@ -0,0 +1,101 @@
+package server
+
+import (
+	"crypto/subtle"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+)
+
+// 本机请求和 require_auth=false 时的局域网请求无需令牌，浏览器会替任意网页发出这类请求，
+// 因此修改状态的请求必须证明来自内置页面：携带访问令牌，或携带与 Cookie 中一致的 CSRF 令牌。
+const (
+	csrfCookieName = "bealink_csrf"
+	csrfHeaderName = "X-CSRF-Token"
+)
+
+// safeMethod 判断请求方法是否不修改状态。
+func safeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead || method == http.MethodOptions
+}
+
+// fromBrowser 判断请求是否由浏览器发出。非浏览器客户端（脚本、Android 应用）不带这些头，也不会被网页借用。
+func fromBrowser(r *http.Request) bool {
+	return r.Header.Get("Origin") != "" || r.Header.Get("Sec-Fetch-Site") != "" || r.Header.Get("Referer") != ""
+}
+
+// crossSite 判断浏览器是否标记该请求由其他站点发起（没有 Sec-Fetch-Site 的旧浏览器无法判断，返回 false）。
+func crossSite(r *http.Request) bool {
+	site := r.Header.Get("Sec-Fetch-Site")
+	return site == "cross-site" || site == "same-site"
+}
+
+// originAllowed 判断页面来源是否与请求的主机一致，或在 allowed_origins 中。origin 为空（非浏览器客户端）时允许。
+func originAllowed(r *http.Request, origin string) bool {
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range bark.GetConfig().AllowedOrigins {
+		if strings.EqualFold(strings.TrimSuffix(allowed, "/"), origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// csrfProtect 对读请求下发 CSRF Cookie（SameSite=Strict，只有同源页面的脚本能读到），
+// 对来自浏览器且未携带令牌的写请求校验来源和 X-CSRF-Token 请求头。
+func (s *Server) csrfProtect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if safeMethod(r.Method) {
+			if c, err := r.Cookie(csrfCookieName); err != nil || c.Value != s.csrfToken {
+				http.SetCookie(w, &http.Cookie{
+					Name:     csrfCookieName,
+					Value:    s.csrfToken,
+					Path:     "/",
+					SameSite: http.SameSiteStrictMode,
+					Secure:   r.TLS != nil,
+				})
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+		if auth.HasToken(r) || !fromBrowser(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		var reason string
+		if origin := r.Header.Get("Origin"); !originAllowed(r, origin) {
+			reason = "来源 " + origin + " 不被允许"
+		} else if subtle.ConstantTimeCompare([]byte(r.Header.Get(csrfHeaderName)), []byte(s.csrfToken)) != 1 {
+			reason = "缺少有效的 CSRF 令牌"
+		}
+		if reason == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		log.Printf("[Auth] 拒绝 %s 的 %s %s: %s", auth.ClientIP(r), r.Method, r.URL.Path, reason)
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			writeAPIError(w, apiErrorf(http.StatusForbidden, ErrCodeCSRF, "%s，请刷新页面后重试或使用访问令牌", reason))
+			return
+		}
+		http.Error(w, reason+"，请刷新页面后重试或使用访问令牌", http.StatusForbidden)
+	})
+}
+
+// legacyGETAllowed 判断旧接口的 GET 请求是否可以执行写操作：需开启 allow_legacy_get，且不是浏览器标记的跨站请求。
+func legacyGETAllowed(r *http.Request) bool {
+	return r.Method == http.MethodGet && bark.GetConfig().AllowLegacyGET && !crossSite(r)
+}
@@ -0,0 +1,209 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+)
+
+// csrfCookie 通过一次 GET 请求取得服务器下发的 CSRF Cookie 值。
+func csrfCookie(t *testing.T, h http.Handler) string {
+	t.Helper()
+	w := serve(h, localRequest(http.MethodGet, "/api/v1/ping", nil))
+	for _, c := range w.Result().Cookies() {
+		if c.Name == csrfCookieName {
+			if c.SameSite != http.SameSiteStrictMode {
+				t.Errorf("CSRF Cookie 的 SameSite = %v, 期望 Strict", c.SameSite)
+			}
+			return c.Value
+		}
+	}
+	t.Fatal("GET 请求未下发 CSRF Cookie")
+	return ""
+}
+
+func TestFromBrowser(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		value  string
+		want   bool
+	}{
+		{"没有浏览器请求头", "", "", false},
+		{"Origin", "Origin", "http://example.com", true},
+		{"Sec-Fetch-Site", "Sec-Fetch-Site", "same-origin", true},
+		{"Referer", "Referer", "http://example.com/setting", true},
+		{"User-Agent 不算", "User-Agent", "Mozilla/5.0", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := localRequest(http.MethodPost, "/sleep", nil)
+			if tt.header != "" {
+				r.Header.Set(tt.header, tt.value)
+			}
+			if got := fromBrowser(r); got != tt.want {
+				t.Errorf("fromBrowser = %v, 期望 %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestOriginAllowed(t *testing.T) {
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.AllowedOrigins = []string{"https://panel.example.org/"}
+	}, func(cfg *bark.BarkConfig) {
+		cfg.AllowedOrigins = nil
+	})
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{"", true},
+		{"http://pc.local:8088", true},
+		{"https://PC.local:8088", true},
+		{"http://pc.local:8089", false},
+		{"http://evil.example", false},
+		{"https://panel.example.org", true},
+		{"http://panel.example.org", false},
+		{"null", false},
+		{"://bad", false},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodPost, "http://pc.local:8088/sleep", nil)
+		if got := originAllowed(r, tt.origin); got != tt.want {
+			t.Errorf("originAllowed(Host=pc.local:8088, %q) = %v, 期望 %v", tt.origin, got, tt.want)
+		}
+	}
+}
+
+func TestCSRFProtect(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	token := csrfCookie(t, h)
+	plain, _ := createTestToken(t, h, "脚本", auth.ScopeAdminSettings)
+
+	tests := []struct {
+		name    string
+		origin  string
+		site    string
+		csrf    string
+		bearer  bool
+		wantErr bool
+	}{
+		{"非浏览器客户端", "", "", "", false, false},
+		{"跨站 POST 没有 CSRF 令牌", "http://evil.example", "cross-site", "", false, true},
+		{"跨站 POST 带上 CSRF 令牌也不行", "http://evil.example", "cross-site", token, false, true},
+		{"同源 POST 没有 CSRF 令牌", "http://example.com", "same-origin", "", false, true},
+		{"同源 POST 的 CSRF 令牌错误", "http://example.com", "same-origin", "wrong", false, true},
+		{"同源 POST 带 X-CSRF-Token", "http://example.com", "same-origin", token, false, false},
+		{"只有 Sec-Fetch-Site 的同源 POST", "", "same-origin", token, false, false},
+		{"携带访问令牌的客户端", "http://evil.example", "cross-site", "", true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := localRequest(http.MethodPost, "/api/v1/bark/test", strings.NewReader(`{}`))
+			r.Header.Set("Content-Type", "application/json")
+			if tt.origin != "" {
+				r.Header.Set("Origin", tt.origin)
+			}
+			if tt.site != "" {
+				r.Header.Set("Sec-Fetch-Site", tt.site)
+			}
+			if tt.csrf != "" {
+				r.Header.Set(csrfHeaderName, tt.csrf)
+			}
+			if tt.bearer {
+				withToken(r, plain)
+			}
+			w := serve(h, r)
+			rejected := w.Code == http.StatusForbidden && apiErrorCode(t, w.Body.String()) == ErrCodeCSRF
+			if rejected != tt.wantErr {
+				t.Errorf("CSRF 拒绝 = %v, 期望 %v (状态码 %d: %s)", rejected, tt.wantErr, w.Code, w.Body.String())
+			}
+		})
+	}
+
+	// 旧接口的纯文本错误同样返回 403
+	r := localRequest(http.MethodPost, "/monitor", nil)
+	r.Header.Set("Origin", "http://evil.example")
+	if w := serve(h, r); w.Code != http.StatusForbidden {
+		t.Errorf("跨站 POST /monitor 状态码 = %d, 期望 403", w.Code)
+	}
+}
+
+func TestCrossSiteLegacyGET(t *testing.T) {
+	_, h, sim := newTestServer(t)
+	updateConfig(t, func(cfg *bark.BarkConfig) { cfg.AllowLegacyGET = false }, func(cfg *bark.BarkConfig) { cfg.AllowLegacyGET = false })
+
+	if w := serve(h, localRequest(http.MethodGet, "/monitor", nil)); w.Code != http.StatusMethodNotAllowed {
+		t.Errorf("未开启 allow_legacy_get 时 GET /monitor 状态码 = %d, 期望 405", w.Code)
+	}
+
+	bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.AllowLegacyGET = true })
+	for _, site := range []string{"cross-site", "same-site"} {
+		r := localRequest(http.MethodGet, "/monitor", nil)
+		r.Header.Set("Sec-Fetch-Site", site)
+		if w := serve(h, r); w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("开启 allow_legacy_get 后 Sec-Fetch-Site: %s 的 GET /monitor 状态码 = %d, 期望 405", site, w.Code)
+		}
+	}
+	if sim.State().MonitorOff {
+		t.Fatal("被拒绝的跨站 GET 不应切换显示器")
+	}
+	for _, site := range []string{"", "same-origin", "none"} {
+		r := localRequest(http.MethodGet, "/monitor", nil)
+		if site != "" {
+			r.Header.Set("Sec-Fetch-Site", site)
+		}
+		if w := serve(h, r); w.Code != http.StatusOK {
+			t.Errorf("开启 allow_legacy_get 后 Sec-Fetch-Site: %q 的 GET /monitor 状态码 = %d, 期望 200", site, w.Code)
+		}
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.AllowedOrigins = []string{"https://panel.example.org"}
+	}, func(cfg *bark.BarkConfig) {
+		cfg.AllowedOrigins = nil
+	})
+	wsURL := "ws" + strings.TrimPrefix(ts.URL, "http") + "/ws/display"
+
+	tests := []struct {
+		origin string
+		want   bool
+	}{
+		{ts.URL, true},
+		{"https://panel.example.org", true},
+		{"http://evil.example", false},
+		{"", true},
+	}
+	for _, tt := range tests {
+		header := http.Header{}
+		if tt.origin != "" {
+			header.Set("Origin", tt.origin)
+		}
+		conn, resp, err := websocket.DefaultDialer.Dial(wsURL, header)
+		if conn != nil {
+			conn.Close()
+		}
+		if got := err == nil; got != tt.want {
+			status := 0
+			if resp != nil {
+				status = resp.StatusCode
+			}
+			t.Errorf("Origin %q 的 WebSocket 连接成功 = %v, 期望 %v (状态码 %d, 错误 %v)", tt.origin, got, tt.want, status, err)
+		}
+		if !tt.want && (resp == nil || resp.StatusCode != http.StatusForbidden) {
+			t.Errorf("Origin %q 的 WebSocket 握手应返回 403", tt.origin)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package server
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// displayHub 把显示器状态变化推送给 /ws/display 的订阅者。
+// 状态变化来自系统通知（Windows 上的 GUID_CONSOLE_DISPLAY_STATE）和本服务自己的开关操作。
+type displayHub struct {
+	mu   sync.Mutex
+	subs map[chan displayState]struct{}
+}
+
+func newDisplayHub() *displayHub {
+	return &displayHub{subs: make(map[chan displayState]struct{})}
+}
+
+func (h *displayHub) subscribe() chan displayState {
+	ch := make(chan displayState, 8)
+	h.mu.Lock()
+	h.subs[ch] = struct{}{}
+	h.mu.Unlock()
+	return ch
+}
+
+func (h *displayHub) unsubscribe(ch chan displayState) {
+	h.mu.Lock()
+	delete(h.subs, ch)
+	h.mu.Unlock()
+}
+
+// publish 通知所有订阅者。订阅者来不及接收时丢弃这条消息，不阻塞状态变化的回调。
+func (h *displayHub) publish(off bool) {
+	state := displayState{MonitorOff: off}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs {
+		select {
+		case ch <- state:
+		default:
+		}
+	}
+}
+
+// handleDisplayEvents 通过 WebSocket 推送显示器状态：连接后先发送当前状态，
+// 之后每次变化发送一条 {"monitor_off": true|false}。
+func (s *Server) handleDisplayEvents(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		log.Printf("升级 WebSocket 失败: %v", err)
+		return
+	}
+	defer conn.Close()
+	ch := s.display.subscribe()
+	defer s.display.unsubscribe(ch)
+
+	// 客户端不发送消息，读到错误即表示连接已关闭
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	send := func(state displayState) error {
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		return conn.WriteJSON(state)
+	}
+	if err := send(displayState{MonitorOff: s.backend.Display.IsMonitorOff()}); err != nil {
+		return
+	}
+	for {
+		select {
+		case state := <-ch:
+			if err := send(state); err != nil {
+				return
+			}
+		case <-closed:
+			return
+		case <-s.done:
+			conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseGoingAway, ""), time.Now().Add(time.Second))
+			return
+		}
+	}
+}
@@ -0,0 +1,85 @@
+package server
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/skip2/go-qrcode"
+
+	"bealinkserver/auth"
+)
+
+// guestSweepInterval 定期吊销到期访客令牌的间隔。令牌到期后即使没人清理也无法再使用，清理只是为了及时从列表中移除。
+const guestSweepInterval = time.Minute
+
+// guestCreateRequest 创建访客令牌的请求体。scopes 为空时只授予媒体控制权限，ttl_seconds 为 0 时有效期为 2 小时，
+// max_uses 为 0 表示不限制次数。
+type guestCreateRequest struct {
+	Name       string   `json:"name"`
+	Scopes     []string `json:"scopes,omitempty"`
+	TTLSeconds int      `json:"ttl_seconds,omitempty"`
+	MaxUses    int      `json:"max_uses,omitempty"`
+}
+
+// createdGuest 新建的访客令牌。URL 打开后控制台会自动保存令牌，QR 为该 URL 的二维码 (data URL)，供访客扫码。
+type createdGuest struct {
+	Token string         `json:"token"`
+	URL   string         `json:"url"`
+	QR    string         `json:"qr"`
+	Info  auth.TokenInfo `json:"info"`
+}
+
+const guestDefaultTTL = 2 * time.Hour
+
+func apiGuestList(r *http.Request) (interface{}, error) {
+	return auth.ListGuests(), nil
+}
+
+func apiGuestCreate(r *http.Request) (interface{}, error) {
+	var req guestCreateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	if len(req.Scopes) == 0 {
+		req.Scopes = []string{auth.ScopeMedia}
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if req.TTLSeconds == 0 {
+		ttl = guestDefaultTTL
+	}
+	plain, info, err := auth.CreateGuestToken(req.Name, req.Scopes, ttl, req.MaxUses)
+	if errors.Is(err, auth.ErrInvalidToken) {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "%v", err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	url := linkBaseURL(r) + "/?access_token=" + plain
+	png, err := qrcode.Encode(url, qrcode.Medium, 256)
+	if err != nil {
+		return nil, fmt.Errorf("生成二维码失败: %w", err)
+	}
+	log.Printf("[Auth] 已创建访客令牌 %q，权限: %v，有效期至 %s", info.Name, info.Scopes, info.ExpiresAt.Format(time.DateTime))
+	return createdGuest{Token: plain, URL: url, QR: "data:image/png;base64," + base64.StdEncoding.EncodeToString(png), Info: info}, nil
+}
+
+// expireGuests 启动时和之后定期吊销到期或用完的访客令牌，直到 ctx 结束。
+// 服务停止期间到期的令牌在启动时立即清理，不必等到第一次定时检查。
+func expireGuests(ctx context.Context) {
+	auth.PruneExpiredGuests()
+	ticker := time.NewTicker(guestSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			auth.PruneExpiredGuests()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+)
+
+// TestExpireGuestsPrunesAtStartup 服务停止期间到期的访客令牌在启动时立即吊销。
+func TestExpireGuestsPrunesAtStartup(t *testing.T) {
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.APITokens = []bark.APIToken{{ID: "guest-expired", Guest: true, ExpiresAt: time.Now().Add(-time.Hour)}}
+	}, func(cfg *bark.BarkConfig) { cfg.APITokens = nil })
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	expireGuests(ctx)
+	if tokens := bark.GetConfig().APITokens; len(tokens) != 0 {
+		t.Errorf("启动时应吊销已到期的访客令牌，剩余: %+v", tokens)
+	}
+}
@@ -8,20 +8,16 @@ import (
 	"log"
 	"net/http"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
-	"sync"
-	"time"
 
-	"bealinkserver/ahk"
+	"bealinkserver/auth"
 	"bealinkserver/bark"
 	"bealinkserver/logging"
-	"bealinkserver/winapi"
+	"bealinkserver/platform"
 	"html/template"
 
-	"github.com/atotto/clipboard"
 	"github.com/gorilla/websocket"
 )
 
@@ -32,45 +28,10 @@ var (
 	upgrader = websocket.Upgrader{
 		ReadBufferSize:  1024,
 		WriteBufferSize: 1024,
-		CheckOrigin:     func(r *http.Request) bool { return true },
+		CheckOrigin:     func(r *http.Request) bool { return originAllowed(r, r.Header.Get("Origin")) },
 	}
 )
 
-var (
-	activeSleepProcess    *os.Process
-	activeShutdownProcess *os.Process
-	sleepMutex            sync.Mutex
-	shutdownMutex         sync.Mutex
-)
-
-func clearProcess(p **os.Process, m *sync.Mutex, taskName string) {
-	if p == nil || *p == nil {
-		return
-	}
-	processToWait := *p
-	go func() {
-		if processToWait == nil {
-			return
-		}
-		pid := processToWait.Pid
-		log.Printf("开始等待 %s AHK 脚本 (PID: %d) 结束...", taskName, pid)
-		state, err := processToWait.Wait()
-		if err != nil {
-			log.Printf("等待 %s AHK 脚本 (PID: %d) 结束时发生错误: %v", taskName, pid, err)
-		} else {
-			log.Printf("%s AHK 脚本 (PID: %d) 已结束，退出状态: %s", taskName, pid, state.String())
-		}
-		m.Lock()
-		if *p != nil && (*p).Pid == pid {
-			*p = nil
-			log.Printf("已清除活动的 %s 进程引用 (PID: %d)。", taskName, pid)
-		} else {
-			log.Printf("等待 %s (PID: %d) 结束，但全局引用已指向其他进程或为nil。", taskName, pid)
-		}
-		m.Unlock()
-	}()
-}
-
 // handleMobileUI 处理移动端控制台请求 (GET /)
 func handleMobileUI(w http.ResponseWriter, r *http.Request) {
 	// 记录设备连接
@@ -91,7 +52,7 @@ func handleRoot(w http.ResponseWriter, r *http.Request) { handleMobileUI(w, r) }
 func handlePing(w http.ResponseWriter, r *http.Request) { w.Write([]byte("pong")) }
 
 // 剪贴板接口
-func handleClip(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleClip(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodPost:
 		body, _ := io.ReadAll(r.Body)
@@ -99,15 +60,23 @@ func handleClip(w http.ResponseWriter, r *http.Request) {
 		if text == "" {
 			text = r.FormValue("text")
 		}
-		if text != "" {
-			clipboard.WriteAll(text)
-			log.Printf("剪切板写入 (来自 %s): %d bytes", r.RemoteAddr, len(text))
-			w.Write([]byte("ok"))
+		if text == "" {
+			http.Error(w, "Empty body", http.StatusBadRequest)
+			return
+		}
+		if err := s.writeClipboardText(text, r.RemoteAddr); err != nil {
+			http.Error(w, "clipboard error", http.StatusInternalServerError)
 			return
 		}
-		http.Error(w, "Empty body", http.StatusBadRequest)
+		w.Write([]byte("ok"))
 	case http.MethodGet:
-		content, _ := clipboard.ReadAll()
+		// 路由声明的是写入权限，读取还需要 clipboard:read
+		if _, err := authorizeRequest(w, r, auth.ScopeClipboardRead); err != nil {
+			apiErr := toAPIError(err)
+			http.Error(w, apiErr.Message, apiErr.Status)
+			return
+		}
+		content, _ := s.backend.Clipboard.ReadText()
 		log.Printf("剪切板读取 (来自 %s): %d bytes", r.RemoteAddr, len(content))
 		w.Write([]byte(content))
 	default:
@@ -115,8 +84,8 @@ func handleClip(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-func handleGetClip(w http.ResponseWriter, r *http.Request) {
-	content, _ := clipboard.ReadAll()
+func (s *Server) handleGetClip(w http.ResponseWriter, r *http.Request) {
+	content, _ := s.backend.Clipboard.ReadText()
 	log.Printf("handleGetClip 请求来自 %s, 内容长度: %d", r.RemoteAddr, len(content))
 	w.Write([]byte(content))
 }
@@ -128,31 +97,35 @@ func handleMediaInfo(w http.ResponseWriter, r *http.Request) {
 	json.NewEncoder(w).Encode(info)
 }
 
-func handleVolumeInfo(w http.ResponseWriter, r *http.Request) {
-	vol := GetVolume()
+func (s *Server) handleVolumeInfo(w http.ResponseWriter, r *http.Request) {
+	info := s.volume.Get()
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]int{"volume": vol})
+	json.NewEncoder(w).Encode(info)
 }
 
-func handleVolumeSet(w http.ResponseWriter, r *http.Request) {
-	valStr := r.URL.Query().Get("val")
+func (s *Server) handleVolumeSet(w http.ResponseWriter, r *http.Request) {
+	valStr := r.FormValue("val")
 	val, err := strconv.Atoi(valStr)
 	if err != nil {
 		http.Error(w, "Invalid volume", http.StatusBadRequest)
 		return
 	}
-	SetVolume(val)
+	s.volume.Set(val)
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{"status": "ok"})
 }
 
-func handleMute(w http.ResponseWriter, r *http.Request) {
-	ToggleMute()
-	w.WriteHeader(http.StatusOK)
+func (s *Server) handleMute(w http.ResponseWriter, r *http.Request) {
+	if err := s.volume.ToggleMute(); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(s.volume.Get())
 }
 
 // 文本与粘贴
-func handleText(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleText(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -163,120 +136,115 @@ func handleText(w http.ResponseWriter, r *http.Request) {
 		text = string(bodyBytes)
 	}
 	if text != "" {
-		clipboard.WriteAll(text)
-		go func() {
-			time.Sleep(100 * time.Millisecond)
-			winapi.Paste()
-		}()
-		log.Printf("已接收文本并粘贴: %s...", limitStr(text, 20))
+		if err := s.typeText(text); err != nil {
+			http.Error(w, "clipboard error", http.StatusInternalServerError)
+			return
+		}
 	}
 	w.Write([]byte("Sent"))
 }
 
-func handlePaste(w http.ResponseWriter, r *http.Request) {
-	winapi.Paste()
+func (s *Server) handlePaste(w http.ResponseWriter, r *http.Request) {
+	if err := s.backend.Input.Paste(); err != nil {
+		log.Printf("粘贴失败: %v", err)
+	}
 	w.Write([]byte("Pasted"))
 }
 
 // 系统控制
-func handleMediaControl(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleMediaControl(w http.ResponseWriter, r *http.Request) {
 	action := strings.TrimPrefix(r.URL.Path, "/media/")
 	switch action {
 	case "play", "playpause":
-		winapi.MediaPlayPause()
+		s.backend.Input.SendKeyPress(platform.VK_MEDIA_PLAY_PAUSE)
 	case "prev":
-		winapi.MediaPrev()
+		s.backend.Input.SendKeyPress(platform.VK_MEDIA_PREV_TRACK)
 	case "next":
-		winapi.MediaNext()
+		s.backend.Input.SendKeyPress(platform.VK_MEDIA_NEXT_TRACK)
 	}
 	w.WriteHeader(http.StatusOK)
 }
 
-func handleMonitorOff(w http.ResponseWriter, r *http.Request) {
-	// 如果当前推测显示器是开启的 (GetState返回false)，则切换电源以关闭
-	if !winapi.GetCurrentMonitorState() {
-		winapi.ToggleMonitorPower()
-		w.Write([]byte("Monitor Off Sent"))
-	} else {
-		w.Write([]byte("Monitor Already Off (Assumed)"))
-	}
+func (s *Server) handleSleep(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "sleep")
 }
 
-func handleSleep(w http.ResponseWriter, r *http.Request) {
-	w.Header().Set("Content-Type", "application/json")
-	sleepMutex.Lock()
-	defer sleepMutex.Unlock()
-	if activeSleepProcess != nil {
-		log.Printf("取消睡眠任务 (PID: %d)...", activeSleepProcess.Pid)
-		if err := activeSleepProcess.Kill(); err != nil {
-			log.Printf("错误: 取消睡眠任务失败: %v", err)
-			http.Error(w, "cancel_error", http.StatusInternalServerError)
-			return
-		}
-		activeSleepProcess = nil
-		log.Println("睡眠任务已取消 (由前端请求)")
-		w.Write([]byte(`{"status":"cancelled"}`))
-		return
-	}
+func (s *Server) handleShutdown(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "shutdown")
+}
 
-	proc, err := ahk.RunScriptAndGetProcess("sleep_countdown.ahk")
+func (s *Server) handleRestart(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "restart")
+}
+
+func (s *Server) handleHibernate(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "hibernate")
+}
+
+func (s *Server) handleLock(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "lock")
+}
+
+func (s *Server) handleSignOut(w http.ResponseWriter, r *http.Request) {
+	s.togglePowerCountdown(w, r, "signout")
+}
+
+// handleAbort 取消倒计时中的电源操作和系统中已计划的关机，返回 {"status":"aborted"}，
+// 取消了倒计时时附带其 id。
+func (s *Server) handleAbort(w http.ResponseWriter, r *http.Request) {
+	cancelled, err := s.power.Abort("前端请求 " + r.RemoteAddr)
 	if err != nil {
-		// 回退：直接调用系统睡眠
-		cmdStr := `Run, rundll32.exe powrprof.dll,SetSuspendState 0,1,0`
-		_, err2 := ahk.RunAhkCode(cmdStr)
-		if err2 != nil {
-			log.Printf("睡眠指令失败: %v", err2)
-			http.Error(w, "Error", http.StatusInternalServerError)
-			return
-		}
-		w.Write([]byte(`{"status":"executed"}`))
-		log.Println("Info: sleep executed via fallback AHK code")
+		writePowerError(w, err)
 		return
 	}
-	activeSleepProcess = proc
-	go clearProcess(&activeSleepProcess, &sleepMutex, "睡眠")
-	scriptDir := filepath.Dir(os.Args[0])
-	scriptFullPath := filepath.Join(scriptDir, "ahk", "script", "sleep_countdown.ahk")
-	dur := ahk.GetScriptCountdownSeconds(scriptFullPath)
-	w.Write([]byte(fmt.Sprintf(`{"status":"started","duration":%d}`, dur)))
-
+	resp := map[string]string{"status": "aborted"}
+	if cancelled != nil {
+		resp["id"] = cancelled.ID
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
 }
 
-func handleShutdown(w http.ResponseWriter, r *http.Request) {
-	shutdownMutex.Lock()
-	defer shutdownMutex.Unlock()
-	if activeShutdownProcess != nil {
-		log.Printf("取消关机任务 (PID: %d)...", activeShutdownProcess.Pid)
-		if err := activeShutdownProcess.Kill(); err != nil {
-			log.Printf("错误: 取消关机任务失败: %v", err)
-			http.Error(w, "cancel_error", http.StatusInternalServerError)
-			return
-		}
-		activeShutdownProcess = nil
-		w.Header().Set("Content-Type", "application/json")
-		w.Write([]byte(`{"status":"cancelled"}`))
+// togglePowerCountdown 旧接口的切换语义：action 正在倒计时则取消它，没有进行中的电源操作则启动一个。
+// 可选参数 seconds、reason 指定倒计时时长和显示在本机窗口上的原因。
+// 倒计时由 power.Manager 掌控，本机没有倒计时界面时同样会按时执行；其他电源操作进行中时返回 409。
+func (s *Server) togglePowerCountdown(w http.ResponseWriter, r *http.Request, action string) {
+	w.Header().Set("Content-Type", "application/json")
+	req, err := formPowerRequest(r, action, "前端请求 "+r.RemoteAddr)
+	if err != nil {
+		writePowerError(w, err)
 		return
 	}
-
-	proc, err := ahk.RunScriptAndGetProcess("shutdown_countdown.ahk")
+	a, started, err := s.power.Toggle(req)
 	if err != nil {
-		// 回退：直接关机
-		exec.Command("shutdown", "/s", "/t", "0").Run()
-		w.Write([]byte(`{"status":"executed"}`))
-		log.Println("Info: shutdown executed via direct system call (fallback)")
+		writePowerError(w, err)
 		return
 	}
-	activeShutdownProcess = proc
-	go clearProcess(&activeShutdownProcess, &shutdownMutex, "关机")
-	scriptDir := filepath.Dir(os.Args[0])
-	scriptFullPath := filepath.Join(scriptDir, "ahk", "script", "shutdown_countdown.ahk")
-	dur := ahk.GetScriptCountdownSeconds(scriptFullPath)
+	if !started {
+		json.NewEncoder(w).Encode(struct {
+			Status string `json:"status"`
+			ID     string `json:"id"`
+		}{"cancelled", a.ID})
+		return
+	}
+	json.NewEncoder(w).Encode(struct {
+		Status   string `json:"status"`
+		ID       string `json:"id"`
+		Duration int    `json:"duration"`
+		Reason   string `json:"reason,omitempty"`
+	}{"started", a.ID, a.Duration, a.Reason})
+}
+
+// writePowerError 以与成功响应相同的 JSON 格式返回电源操作错误，状态码同 /api/v1 接口（见 powerAPIError）。
+func writePowerError(w http.ResponseWriter, err error) {
+	apiErr := powerAPIError(err)
 	w.Header().Set("Content-Type", "application/json")
-	w.Write([]byte(fmt.Sprintf(`{"status":"started","duration":%d}`, dur)))
+	w.WriteHeader(apiErr.Status)
+	json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": apiErr.Message})
 }
 
 // 图片上传
-func handleUploadImage(w http.ResponseWriter, r *http.Request) {
+func (s *Server) handleUploadImage(w http.ResponseWriter, r *http.Request) {
 	file, _, err := r.FormFile("image")
 	if err != nil {
 		http.Error(w, "File error", http.StatusBadRequest)
@@ -284,24 +252,7 @@ func handleUploadImage(w http.ResponseWriter, r *http.Request) {
 	}
 	defer file.Close()
 
-	tempDir := os.TempDir()
-	tempFile := filepath.Join(tempDir, "bealink_clip.png")
-
-	f, err := os.Create(tempFile)
-	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		return
-	}
-	defer f.Close()
-	io.Copy(f, file)
-
-	data, err := os.ReadFile(tempFile)
-	if err != nil {
-		http.Error(w, "Server error", http.StatusInternalServerError)
-		return
-	}
-	if err := winapi.SetClipboardImage(data, filepath.Base(tempFile)); err != nil {
-		log.Printf("设置剪贴板图片失败: %v", err)
+	if err := s.setClipboardImage(file); err != nil {
 		http.Error(w, "Server error", http.StatusInternalServerError)
 		return
 	}
@@ -316,7 +267,20 @@ func limitStr(s string, n int) string {
 }
 
 // 设置与调试
-func handleSettingsPage(w http.ResponseWriter, r *http.Request) {
+
+// handleSetting GET 返回设置页面，POST 保存设置表单。
+func (s *Server) handleSetting(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		s.handleSettingsPage(w, r)
+	case http.MethodPost:
+		handleSaveSettings(w, r)
+	default:
+		http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+	}
+}
+
+func (s *Server) handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 	cfg := bark.GetConfig()
 
 	// 读取模板文件
@@ -344,6 +308,26 @@ func handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 		EncryptionIV        string
 		UseEncryption       bool
 		NotifyOnSystemReady bool
+		RequireAuth         bool
+		LegacyAllowNoToken  bool
+		Scopes              []auth.ScopeInfo
+		TLSEnabled          bool
+		TLSPort             string
+		HTTPEnabled         bool
+		TLS                 *tlsInfo // 当前运行中的 HTTPS 服务，未启用时为 nil
+		ListenAddresses     string   // 以下列表在表单中每行一项
+		ListenPorts         string
+		AllowCIDRs          string
+		DenyCIDRs           string
+		NotifyOnRejected    bool
+		AllowLegacyGET      bool
+		AllowedOrigins      string
+		Policies            []policyAction
+		ConfirmTimeoutSec   int
+		ScheduleReminderMin int
+		Countdowns          []countdownSetting
+		CountdownMinSec     int
+		CountdownMaxSec     int
 	}
 
 	data := SettingsData{
@@ -354,6 +338,26 @@ func handleSettingsPage(w http.ResponseWriter, r *http.Request) {
 		EncryptionIV:        cfg.EncryptionIV,
 		UseEncryption:       cfg.EncryptionKey != "" && cfg.EncryptionIV != "" && len(cfg.EncryptionKey) == 16 && len(cfg.EncryptionIV) == 16,
 		NotifyOnSystemReady: cfg.NotifyOnSystemReady,
+		RequireAuth:         cfg.RequireAuth,
+		LegacyAllowNoToken:  cfg.LegacyAllowNoToken,
+		Scopes:              auth.Scopes,
+		TLSEnabled:          cfg.TLSEnabled,
+		TLSPort:             strings.TrimPrefix(cfg.TLSPort, ":"),
+		HTTPEnabled:         cfg.HTTPEnabled,
+		TLS:                 s.tlsInfo(),
+		ListenAddresses:     strings.Join(cfg.ListenAddresses, "\n"),
+		ListenPorts:         strings.ReplaceAll(strings.Join(cfg.ListenPorts, ", "), ":", ""),
+		AllowCIDRs:          strings.Join(cfg.AllowCIDRs, "\n"),
+		DenyCIDRs:           strings.Join(cfg.DenyCIDRs, "\n"),
+		NotifyOnRejected:    cfg.NotifyOnRejected,
+		AllowLegacyGET:      cfg.AllowLegacyGET,
+		AllowedOrigins:      strings.Join(cfg.AllowedOrigins, "\n"),
+		Policies:            actionPolicies(),
+		ConfirmTimeoutSec:   cfg.ConfirmTimeoutSec,
+		ScheduleReminderMin: cfg.ScheduleReminderMin,
+		Countdowns:          countdownSettings(),
+		CountdownMinSec:     cfg.CountdownMinSec,
+		CountdownMaxSec:     cfg.CountdownMaxSec,
 	}
 
 	// 渲染模板
@@ -414,38 +418,43 @@ func handleSaveSettings(w http.ResponseWriter, r *http.Request) {
 		} else {
 			m["notify_on_system_ready"] = false
 		}
-	}
-
-	log.Printf("调试: handleSaveSettings - 准备更新的配置数据: %+v", m)
-
-	err := bark.UpdateConfig(func(cfg *bark.BarkConfig) {
-		// 更新所有字段，包括空字符串（允许清空配置）
-		if v, ok := m["bark_full_url"].(string); ok {
-			log.Printf("调试: 更新 BarkFullURL 从 '%s' 到 '%s'", cfg.BarkFullURL, v)
-			cfg.BarkFullURL = v
-		}
-		if v, ok := m["sound"].(string); ok {
-			cfg.Sound = v
-		}
-		if v, ok := m["encryption_key"].(string); ok {
-			cfg.EncryptionKey = v
-		}
-		if v, ok := m["encryption_iv"].(string); ok {
-			cfg.EncryptionIV = v
+		m["require_auth"] = r.PostFormValue("require_auth") == "on"
+		m["legacy_allow_no_token"] = r.PostFormValue("legacy_allow_no_token") == "on"
+		m["tls_enabled"] = r.PostFormValue("tls_enabled") == "on"
+		m["http_enabled"] = r.PostFormValue("http_enabled") == "on"
+		m["tls_port"] = r.PostFormValue("tls_port")
+		m["listen_addresses"] = r.PostFormValue("listen_addresses")
+		m["listen_ports"] = r.PostFormValue("listen_ports")
+		m["allow_cidrs"] = r.PostFormValue("allow_cidrs")
+		m["deny_cidrs"] = r.PostFormValue("deny_cidrs")
+		m["notify_on_rejected"] = r.PostFormValue("notify_on_rejected") == "on"
+		m["allow_legacy_get"] = r.PostFormValue("allow_legacy_get") == "on"
+		m["allowed_origins"] = r.PostFormValue("allowed_origins")
+		policies := make(map[string]interface{})
+		for _, a := range policyActions {
+			if v := r.PostFormValue("policy_" + a.Name); v != "" {
+				policies[a.Name] = v
+			}
 		}
-		if v, ok := m["notify_on_system_ready"].(bool); ok {
-			cfg.NotifyOnSystemReady = v
+		m["action_policies"] = policies
+		m["confirm_timeout_sec"] = r.PostFormValue("confirm_timeout_sec")
+		m["schedule_reminder_min"] = r.PostFormValue("schedule_reminder_min")
+		m["countdown_min_sec"] = r.PostFormValue("countdown_min_sec")
+		m["countdown_max_sec"] = r.PostFormValue("countdown_max_sec")
+		countdowns := make(map[string]interface{})
+		for _, name := range actionParam.Enum {
+			if v := r.PostFormValue("countdown_" + name); v != "" {
+				countdowns[name] = v
+			}
 		}
-	})
-	if err != nil {
-		log.Printf("错误: 保存配置失败: %v", err)
-		http.Error(w, "保存配置失败: "+err.Error(), http.StatusInternalServerError)
-		return
+		m["countdown_seconds"] = countdowns
 	}
 
-	// 验证保存后的配置
-	savedCfg := bark.GetConfig()
-	log.Printf("调试: handleSaveSettings - 保存后的配置 BarkFullURL: '%s'", savedCfg.BarkFullURL)
+	if err := applySettings(m); err != nil {
+		apiErr := toAPIError(err)
+		http.Error(w, apiErr.Message, apiErr.Status)
+		return
+	}
 
 	w.Write([]byte("设置已成功保存！"))
 }
@@ -495,8 +504,8 @@ func handleWebSocket(w http.ResponseWriter, r *http.Request) {
 	http.Error(w, "Not implemented yet", 501)
 }
 
-func handleMonitorToggle(w http.ResponseWriter, r *http.Request) {
-	newState, err := winapi.ToggleMonitorPower()
+func (s *Server) handleMonitorToggle(w http.ResponseWriter, r *http.Request) {
+	newState, err := s.backend.Display.ToggleMonitorPower()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
@@ -507,34 +516,25 @@ func handleMonitorToggle(w http.ResponseWriter, r *http.Request) {
 		w.Write([]byte("on"))
 	}
 }
-func handleVolumeUp(w http.ResponseWriter, r *http.Request) {
-	_ = winapi.VolumeUp()
+func (s *Server) handleVolumeUp(w http.ResponseWriter, r *http.Request) {
+	_ = s.pressVolumeKey(true)
 	w.Write([]byte("ok"))
 }
-func handleVolumeDown(w http.ResponseWriter, r *http.Request) {
-	_ = winapi.VolumeDown()
+func (s *Server) handleVolumeDown(w http.ResponseWriter, r *http.Request) {
+	_ = s.pressVolumeKey(false)
 	w.Write([]byte("ok"))
 }
-func handleMediaPlayPause(w http.ResponseWriter, r *http.Request) {
-	// 使用简单快捷键: Ctrl+Alt+P 作为播放/暂停
-	if err := winapi.SendKeyWithModifiers(true, true, false, 0x50); err != nil {
-		log.Printf("发送播放/暂停快捷键失败: %v", err)
-	}
+func (s *Server) handleMediaPlayPause(w http.ResponseWriter, r *http.Request) {
+	_ = s.mediaCommand("playpause")
 	w.Write([]byte("ok"))
 }
-func handleMediaNext(w http.ResponseWriter, r *http.Request) {
-	// 使用快捷键: Ctrl+Alt+Right 作为下一首
-	if err := winapi.SendKeyWithModifiers(true, true, false, winapi.VK_RIGHT); err != nil {
-		log.Printf("发送下一首快捷键失败: %v", err)
-	}
+func (s *Server) handleMediaNext(w http.ResponseWriter, r *http.Request) {
+	_ = s.mediaCommand("next")
 	w.Write([]byte("ok"))
 }
 
-func handleMediaPrev(w http.ResponseWriter, r *http.Request) {
-	// 使用快捷键: Ctrl+Alt+Left 作为上一首
-	if err := winapi.SendKeyWithModifiers(true, true, false, winapi.VK_LEFT); err != nil {
-		log.Printf("发送上一首快捷键失败: %v", err)
-	}
+func (s *Server) handleMediaPrev(w http.ResponseWriter, r *http.Request) {
+	_ = s.mediaCommand("prev")
 	w.Write([]byte("ok"))
 }
 
@@ -558,26 +558,16 @@ func serveWs(hub *logging.Hub, w http.ResponseWriter, r *http.Request) {
 func handleTestBark(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Content-Type", "application/json")
 
-	// 检查配置是否有效
-	cfg := bark.GetConfig()
-	sufficient, _, _, _, _, reason := bark.IsBarkConfigSufficient(cfg)
-	if !sufficient {
-		log.Printf("错误: Bark 配置不完整，无法发送测试通知: %s", reason)
-		http.Error(w, fmt.Sprintf(`{"status":"error","message":"Bark 配置不完整: %s"}`, reason), http.StatusBadRequest)
-		return
-	}
-
 	// 获取当前选择的铃声（如果有提供）
-	selectedSound := r.FormValue("sound")
-	if selectedSound != "" {
-		cfg.Sound = selectedSound
+	if err := sendTestBark(r.FormValue("sound")); err != nil {
+		apiErr := toAPIError(err)
+		w.WriteHeader(apiErr.Status)
+		json.NewEncoder(w).Encode(map[string]string{"status": "error", "message": apiErr.Message})
+		return
 	}
 
-	// 发送测试推送通知（使用指定或已保存的铃声）
-	bark.NotifyEventWithConfig("test", cfg)
-
 	// 返回成功响应
-	w.Write([]byte(`{"status":"ok","message":"测试通知已发送，请检查你的 Bark App"}`))
+	json.NewEncoder(w).Encode(map[string]string{"status": "ok", "message": "测试通知已发送，请检查你的 Bark App"})
 }
 
 // getIconPath 返回深色图标文件路径
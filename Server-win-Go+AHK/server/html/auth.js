@@ -0,0 +1,107 @@
+// auth.js 页面共用的访问令牌处理，需在页面其他脚本之前引入。
+// 令牌保存在 localStorage (bealink_token)；打开带 ?access_token= 的链接时自动保存并从地址栏移除。
+// 同源的 fetch 请求自动附加 Authorization 头，写请求附加 X-CSRF-Token 头（取自 Cookie bealink_csrf），
+// 收到 401 时提示输入令牌或配对本设备（每个页面只提示一次）。
+(function () {
+    const KEY = 'bealink_token';
+
+    const params = new URLSearchParams(location.search);
+    if (params.has('access_token')) {
+        localStorage.setItem(KEY, params.get('access_token'));
+        params.delete('access_token');
+        const query = params.toString();
+        history.replaceState(null, '', location.pathname + (query ? '?' + query : '') + location.hash);
+    }
+
+    const nativeFetch = window.fetch.bind(window);
+
+    window.bealinkToken = function () {
+        return localStorage.getItem(KEY) || '';
+    };
+
+    window.bealinkSetToken = function (token) {
+        if (token) localStorage.setItem(KEY, token);
+        else localStorage.removeItem(KEY);
+    };
+
+    // bealinkWsURL 为 WebSocket 地址附加 access_token 查询参数（浏览器 WebSocket 无法设置请求头）。
+    window.bealinkWsURL = function (url) {
+        const token = window.bealinkToken();
+        if (!token) return url;
+        return url + (url.includes('?') ? '&' : '?') + 'access_token=' + encodeURIComponent(token);
+    };
+
+    function csrfToken() {
+        const match = document.cookie.match(/(?:^|;\s*)bealink_csrf=([^;]*)/);
+        return match ? decodeURIComponent(match[1]) : '';
+    }
+
+    async function postJSON(url, data) {
+        const res = await nativeFetch(url, {
+            method: 'POST',
+            headers: { 'Content-Type': 'application/json', 'X-CSRF-Token': csrfToken() },
+            body: JSON.stringify(data)
+        });
+        return res.json();
+    }
+
+    // bealinkPair 与电脑配对：电脑上会弹出 6 位配对码，输入正确后保存获得的令牌。成功时返回 true。
+    window.bealinkPair = async function (name) {
+        const start = await postJSON('/api/v1/pair/start', { name: name || defaultDeviceName() });
+        if (!start.ok) {
+            alert('配对失败: ' + start.error.message);
+            return false;
+        }
+        for (;;) {
+            const code = prompt('请输入电脑屏幕上显示的 6 位配对码：');
+            if (!code) return false;
+            const done = await postJSON('/api/v1/pair/complete', { id: start.data.id, code: code.trim() });
+            if (done.ok) {
+                window.bealinkSetToken(done.data.token);
+                return true;
+            }
+            alert('配对失败: ' + done.error.message);
+            if (done.error.code !== 'forbidden') return false;
+        }
+    };
+
+    function defaultDeviceName() {
+        const ua = navigator.userAgent;
+        const match = ua.match(/iPhone|iPad|Android|Macintosh|Windows|Linux/);
+        return (match ? match[0] : '浏览器') + ' 浏览器';
+    }
+
+    function sameOrigin(input) {
+        const url = typeof input === 'string' ? input : input.url;
+        return new URL(url, location.href).origin === location.origin;
+    }
+
+    let prompted = false;
+
+    async function authFetch(input, init) {
+        const token = window.bealinkToken();
+        let req = init || {};
+        if (sameOrigin(input)) {
+            const headers = new Headers(req.headers || {});
+            if (token && !headers.has('Authorization')) headers.set('Authorization', 'Bearer ' + token);
+            const method = (req.method || (input instanceof Request ? input.method : 'GET')).toUpperCase();
+            if (method !== 'GET' && method !== 'HEAD') headers.set('X-CSRF-Token', csrfToken());
+            req = Object.assign({}, req, { headers: headers });
+        }
+        const res = await nativeFetch(input, req);
+        if (res.status === 401 && sameOrigin(input) && !prompted) {
+            prompted = true;
+            const entered = prompt('此操作需要访问令牌。\n请输入在电脑上的 Bealink 设置页面创建的令牌，或留空直接确定以配对本设备：');
+            if (entered && entered.trim()) {
+                window.bealinkSetToken(entered.trim());
+                return authFetch(input, init);
+            }
+            if (entered === '' && await window.bealinkPair()) {
+                return authFetch(input, init);
+            }
+        }
+        return res;
+    }
+
+    window.fetch = authFetch;
+})();
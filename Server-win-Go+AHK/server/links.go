@@ -0,0 +1,240 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"time"
+
+	"bealinkserver/auth"
+	"bealinkserver/power"
+)
+
+// linkAction 可以生成签名链接的操作。每个链接只对应一个操作，Scope 为该操作对应的权限范围，
+// 通过 API 生成链接的请求方必须拥有该权限。
+type linkAction struct {
+	Name    string `json:"name"`
+	Scope   string `json:"scope"`
+	Summary string `json:"summary"`
+	Policy  string `json:"-"` // 执行前检查的操作策略名，空表示不检查（取消倒计时）
+	run     func() (string, error)
+}
+
+// linkActions 返回可通过签名链接执行的操作，run 返回给访问者看的结果。
+func (s *Server) linkActions() []linkAction {
+	startPower := func(action, label string) func() (string, error) {
+		return func() (string, error) {
+			cd, err := s.power.Start(power.Request{Action: action, Source: "签名链接"})
+			if err != nil {
+				return "", err
+			}
+			return fmt.Sprintf("%s倒计时已开始，%.0f 秒后执行", label, cd.Remaining), nil
+		}
+	}
+	cancelPower := func(action string) func() (string, error) {
+		return func() (string, error) {
+			if !s.power.CancelAction(action, "签名链接") {
+				return "没有进行中的倒计时", nil
+			}
+			return "倒计时已取消", nil
+		}
+	}
+	key := func(fn func() error, done string) func() (string, error) {
+		return func() (string, error) {
+			if err := fn(); err != nil {
+				return "", err
+			}
+			return done, nil
+		}
+	}
+	return []linkAction{
+		{Name: "sleep", Policy: "sleep", Scope: auth.ScopePower, Summary: "开始睡眠倒计时", run: startPower("sleep", "睡眠")},
+		{Name: "shutdown", Policy: "shutdown", Scope: auth.ScopePower, Summary: "开始关机倒计时", run: startPower("shutdown", "关机")},
+		{Name: "sleep-cancel", Scope: auth.ScopePower, Summary: "取消睡眠倒计时", run: cancelPower("sleep")},
+		{Name: "shutdown-cancel", Scope: auth.ScopePower, Summary: "取消关机倒计时", run: cancelPower("shutdown")},
+		{Name: "restart", Policy: "restart", Scope: auth.ScopePower, Summary: "开始重启倒计时", run: startPower("restart", "重启")},
+		{Name: "hibernate", Policy: "hibernate", Scope: auth.ScopePower, Summary: "开始休眠倒计时", run: startPower("hibernate", "休眠")},
+		{Name: "lock", Policy: "lock", Scope: auth.ScopePower, Summary: "开始锁定倒计时", run: startPower("lock", "锁定")},
+		{Name: "signout", Policy: "signout", Scope: auth.ScopePower, Summary: "开始注销倒计时", run: startPower("signout", "注销")},
+		{Name: "abort", Scope: auth.ScopePower, Summary: "取消所有电源倒计时和系统计划的关机", run: func() (string, error) {
+			cancelled, err := s.power.Abort("签名链接")
+			if err != nil {
+				return "", err
+			}
+			if cancelled == nil {
+				return "已取消系统计划的关机", nil
+			}
+			return "倒计时和系统计划的关机已取消", nil
+		}},
+		{Name: "display-toggle", Policy: "display", Scope: auth.ScopePower, Summary: "切换显示器开关", run: func() (string, error) {
+			off, err := s.backend.Display.ToggleMonitorPower()
+			if err != nil {
+				return "", err
+			}
+			if off {
+				return "显示器已关闭", nil
+			}
+			return "显示器已打开", nil
+		}},
+		{Name: "volume-up", Policy: "volume", Scope: auth.ScopeMedia, Summary: "音量加", run: key(func() error { return s.pressVolumeKey(true) }, "已调高音量")},
+		{Name: "volume-down", Policy: "volume", Scope: auth.ScopeMedia, Summary: "音量减", run: key(func() error { return s.pressVolumeKey(false) }, "已调低音量")},
+		{Name: "volume-mute", Policy: "volume", Scope: auth.ScopeMedia, Summary: "切换静音", run: key(s.volume.ToggleMute, "已切换静音")},
+		{Name: "media-playpause", Policy: "media", Scope: auth.ScopeMedia, Summary: "播放/暂停", run: key(func() error { return s.mediaCommand("playpause") }, "已发送播放/暂停")},
+		{Name: "media-next", Policy: "media", Scope: auth.ScopeMedia, Summary: "下一首", run: key(func() error { return s.mediaCommand("next") }, "已切换到下一首")},
+		{Name: "media-prev", Policy: "media", Scope: auth.ScopeMedia, Summary: "上一首", run: key(func() error { return s.mediaCommand("prev") }, "已切换到上一首")},
+	}
+}
+
+func (s *Server) linkActionNames() []string {
+	var names []string
+	for _, a := range s.linkActions() {
+		names = append(names, a.Name)
+	}
+	return names
+}
+
+func (s *Server) findLinkAction(name string) (linkAction, bool) {
+	for _, a := range s.linkActions() {
+		if a.Name == name {
+			return a, true
+		}
+	}
+	return linkAction{}, false
+}
+
+// linkCreateRequest 生成签名链接的请求体，ttl_seconds 为 0 时有效期为 24 小时。
+type linkCreateRequest struct {
+	Action     string `json:"action"`
+	TTLSeconds int    `json:"ttl_seconds,omitempty"`
+}
+
+// signedLink 生成的签名链接。URL 使用局域网地址，可直接写入快捷指令或 NFC 标签。
+type signedLink struct {
+	Action    string    `json:"action"`
+	URL       string    `json:"url"`
+	Path      string    `json:"path"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// SignedLink 为 action 生成相对路径形式的签名链接，供服务内部（如 Bark 通知）使用。
+func (s *Server) SignedLink(action string, ttl time.Duration) (string, time.Time, error) {
+	if _, ok := s.findLinkAction(action); !ok {
+		return "", time.Time{}, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "未知的操作: %s", action)
+	}
+	q, expiresAt, err := auth.SignLink(action, ttl)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return "/a/" + action + "?" + q.Encode(), expiresAt, nil
+}
+
+func (s *Server) apiLinkActions(r *http.Request) (interface{}, error) {
+	return s.linkActions(), nil
+}
+
+func (s *Server) apiLinkCreate(r *http.Request) (interface{}, error) {
+	var req linkCreateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	ttl := time.Duration(req.TTLSeconds) * time.Second
+	if req.TTLSeconds < 0 || ttl > auth.LinkMaxTTL {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "ttl_seconds 必须在 0-%d 之间", int(auth.LinkMaxTTL.Seconds()))
+	}
+	action, ok := s.findLinkAction(req.Action)
+	if !ok {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "未知的操作: %s", req.Action)
+	}
+	// 链接无需令牌即可执行，不允许生成超出自身权限的链接
+	if id := auth.FromContext(r.Context()); id == nil || !id.Has(action.Scope) {
+		return nil, apiErrorf(http.StatusForbidden, ErrCodeForbidden, "生成 %s 链接需要 %s 权限", action.Name, action.Scope)
+	}
+	path, expiresAt, err := s.SignedLink(req.Action, ttl)
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("[Auth] 已生成签名链接: %s，有效期至 %s", req.Action, expiresAt.Format(time.DateTime))
+	return signedLink{Action: req.Action, URL: linkBaseURL(r) + path, Path: path, ExpiresAt: expiresAt}, nil
+}
+
+// linkBaseURL 返回签名链接使用的地址。在本机设置页面生成时请求地址是 localhost，换成局域网 IP 以便其他设备使用。
+func linkBaseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	host, port, err := net.SplitHostPort(r.Host)
+	if err != nil {
+		host, port = r.Host, ""
+	}
+	if ip := net.ParseIP(host); host == "localhost" || (ip != nil && ip.IsLoopback()) {
+		host = getLocalIP()
+	}
+	if port != "" {
+		host = net.JoinHostPort(host, port)
+	}
+	return scheme + "://" + host
+}
+
+// handleSignedAction 执行签名链接对应的操作 (GET /a/{action})。签名本身就是授权凭据，
+// 因此无需令牌，也不受写操作只接受 POST 的限制；无效的签名按认证失败计数。
+// 只接受 GET，避免链接预览发出的 HEAD 等请求消耗链接。
+func (s *Server) handleSignedAction(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		w.Header().Set("Allow", http.MethodGet)
+		http.Error(w, "签名链接只接受 GET 请求", http.StatusMethodNotAllowed)
+		return
+	}
+	ip := auth.ClientIP(r)
+	noteAuditClient(r, "签名链接")
+	action, ok := s.findLinkAction(r.PathValue("action"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	// 先预留链接再检查策略：等待确认期间同一链接的其他请求返回 409，
+	// 被拒绝、确认超时或执行失败时释放预留，链接仍可再次使用；只有执行成功才消耗链接
+	link, err := auth.ReserveLink(action.Name, r.URL.Query())
+	if err != nil {
+		rejectSignedLink(w, r, action.Name, err)
+		return
+	}
+	defer link.Release() // Commit 之后不再生效
+	if action.Policy != "" {
+		if err := s.checkPolicy(w, r, action.Policy); err != nil {
+			apiErr := toAPIError(err)
+			http.Error(w, apiErr.Message, apiErr.Status)
+			return
+		}
+	}
+	result, err := action.run()
+	if err != nil {
+		log.Printf("签名链接 %s (来自 %s) 执行失败: %v", action.Name, ip, err)
+		http.Error(w, action.Summary+"失败: "+err.Error(), powerAPIError(err).Status)
+		return
+	}
+	link.Commit()
+	log.Printf("签名链接 %s (来自 %s) 已执行: %s", action.Name, ip, result)
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Write([]byte(result))
+}
+
+// rejectSignedLink 拒绝无效、过期、已使用或正在使用的签名链接，无效签名按认证失败计数。
+func rejectSignedLink(w http.ResponseWriter, r *http.Request, action string, err error) {
+	ip := auth.ClientIP(r)
+	log.Printf("[Auth] 拒绝 %s 的签名链接 %s: %v", ip, action, err)
+	status := http.StatusForbidden
+	switch {
+	case errors.Is(err, auth.ErrLinkInvalid):
+		auth.RecordFailure(ip, "签名链接无效", r.URL.Query().Get("sig"))
+	case errors.Is(err, auth.ErrLinkExpired), errors.Is(err, auth.ErrLinkUsed):
+		status = http.StatusGone
+	case errors.Is(err, auth.ErrLinkInUse):
+		status = http.StatusConflict
+	default:
+		status = http.StatusInternalServerError
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,163 @@
+package server
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+	"bealinkserver/power"
+)
+
+// setPolicy 将 action 的策略设为 policy，测试结束时恢复为 allow。
+func setPolicy(t *testing.T, action, policy string) {
+	t.Helper()
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		if cfg.ActionPolicies == nil {
+			cfg.ActionPolicies = make(map[string]string)
+		}
+		cfg.ActionPolicies[action] = policy
+	}, func(cfg *bark.BarkConfig) { delete(cfg.ActionPolicies, action) })
+}
+
+// waitConfirm 等待确认框弹出后以 result 作答。
+func waitConfirm(t *testing.T, sim *platform.Simulator, result platform.ConfirmResult) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !sim.AnswerConfirm(result) {
+		if time.Now().After(deadline) {
+			t.Fatal("确认框没有弹出")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func signedLinkPath(t *testing.T, s *Server, action string) string {
+	t.Helper()
+	path, _, err := s.SignedLink(action, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestSignedLinkDeniedByPolicyIsNotConsumed(t *testing.T) {
+	s, h, sim := newTestServer(t)
+	path := signedLinkPath(t, s, "volume-mute")
+
+	setPolicy(t, "volume", PolicyDeny)
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusForbidden {
+		t.Fatalf("策略为 deny 时状态码 = %d, 期望 403", w.Code)
+	}
+	setPolicy(t, "volume", PolicyConfirm)
+	done := make(chan int)
+	go func() { done <- serve(h, lanRequest(http.MethodGet, path, nil)).Code }()
+	waitConfirm(t, sim, platform.ConfirmDenied)
+	if code := <-done; code != http.StatusForbidden {
+		t.Fatalf("确认被拒绝时状态码 = %d, 期望 403", code)
+	}
+	if muted, _ := sim.IsMuted(); muted {
+		t.Fatal("被拒绝的链接不应执行操作")
+	}
+
+	setPolicy(t, "volume", PolicyAllow)
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusOK {
+		t.Fatalf("被拒绝过的链接应仍可使用，状态码 = %d (%s)", w.Code, w.Body.String())
+	}
+	if muted, _ := sim.IsMuted(); !muted {
+		t.Error("链接应已执行静音")
+	}
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusGone {
+		t.Errorf("已使用的链接状态码 = %d, 期望 410", w.Code)
+	}
+}
+
+func TestSignedLinkBusyIsConflict(t *testing.T) {
+	s, h, _ := newTestServer(t)
+	if _, err := s.power.Start(power.Request{Action: "shutdown", Seconds: 60, Source: "测试"}); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.power.CancelAll("测试结束") })
+	path := signedLinkPath(t, s, "sleep")
+	w := serve(h, lanRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusConflict {
+		t.Errorf("已有其他电源操作时状态码 = %d, 期望 409 (%s)", w.Code, w.Body.String())
+	}
+
+	s.power.CancelAll("测试")
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusOK {
+		t.Fatalf("执行失败的链接应仍可使用，状态码 = %d (%s)", w.Code, w.Body.String())
+	}
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusGone {
+		t.Errorf("执行成功后链接应已消耗，状态码 = %d, 期望 410", w.Code)
+	}
+}
+
+func TestSignedLinkInUseIsConflict(t *testing.T) {
+	s, h, sim := newTestServer(t)
+	path := signedLinkPath(t, s, "volume-mute")
+	u, err := url.Parse(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	link, err := auth.ReserveLink("volume-mute", u.Query())
+	if err != nil {
+		t.Fatal(err)
+	}
+	w := serve(h, lanRequest(http.MethodGet, path, nil))
+	if w.Code != http.StatusConflict || !strings.Contains(w.Body.String(), auth.ErrLinkInUse.Error()) {
+		t.Errorf("链接被预留时状态码 = %d (%s), 期望 409", w.Code, w.Body.String())
+	}
+	if muted, _ := sim.IsMuted(); muted {
+		t.Fatal("被预留的链接不应重复执行")
+	}
+
+	link.Release()
+	link.Release() // 重复释放应无副作用
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusOK {
+		t.Fatalf("释放后链接应可使用，状态码 = %d (%s)", w.Code, w.Body.String())
+	}
+	if _, err := auth.ReserveLink("volume-mute", u.Query()); err != auth.ErrLinkUsed {
+		t.Errorf("已使用的链接再次预留 err = %v, 期望 ErrLinkUsed", err)
+	}
+}
+
+func TestSignedLinkCreateRequiresActionScope(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	t.Cleanup(func() { auth.Unblock("") })
+	adminOnly, _ := createTestToken(t, h, "仅设置", auth.ScopeAdminSettings)
+	adminMedia, _ := createTestToken(t, h, "设置和媒体", auth.ScopeAdminSettings, auth.ScopeMedia)
+
+	create := func(token, action string) int {
+		r := lanRequest(http.MethodPost, "/api/v1/links", strings.NewReader(`{"action":"`+action+`"}`))
+		r.Header.Set("Content-Type", "application/json")
+		return serve(h, withToken(r, token)).Code
+	}
+	if code := create(adminOnly, "volume-up"); code != http.StatusForbidden {
+		t.Errorf("缺少 media 权限时生成 volume-up 链接状态码 = %d, 期望 403", code)
+	}
+	if code := create(adminMedia, "volume-up"); code != http.StatusOK {
+		t.Errorf("拥有 media 权限时生成 volume-up 链接状态码 = %d, 期望 200", code)
+	}
+	if code := create(adminMedia, "shutdown"); code != http.StatusForbidden {
+		t.Errorf("缺少 power 权限时生成 shutdown 链接状态码 = %d, 期望 403", code)
+	}
+	if code := create(adminMedia, "nope"); code != http.StatusBadRequest {
+		t.Errorf("未知操作状态码 = %d, 期望 400", code)
+	}
+}
+
+func TestSignedLinkInvalidSignature(t *testing.T) {
+	s, h, _ := newTestServer(t)
+	path := signedLinkPath(t, s, "volume-up")
+	if w := serve(h, lanRequest(http.MethodGet, path+"x", nil)); w.Code != http.StatusForbidden {
+		t.Errorf("签名错误时状态码 = %d, 期望 403", w.Code)
+	}
+	if w := serve(h, lanRequest(http.MethodGet, path, nil)); w.Code != http.StatusOK {
+		t.Errorf("伪造的请求不应消耗原链接，状态码 = %d", w.Code)
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"reflect"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// APIVersion /api/v1 接口的文档版本号。
+const APIVersion = "1.0.0"
+
+// jsonObject OpenAPI 文档中的任意 JSON 对象。
+type jsonObject = map[string]interface{}
+
+// schemaBuilder 通过反射把 Go 类型转换为 JSON Schema，具名结构体放入 components/schemas 并以 $ref 引用。
+type schemaBuilder struct {
+	components jsonObject
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+func (b *schemaBuilder) schemaOf(v interface{}) jsonObject {
+	return b.schemaFor(reflect.TypeOf(v))
+}
+
+func (b *schemaBuilder) schemaFor(t reflect.Type) jsonObject {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == timeType {
+		return jsonObject{"type": "string", "format": "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return jsonObject{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return jsonObject{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return jsonObject{"type": "number"}
+	case reflect.String:
+		return jsonObject{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return jsonObject{"type": "string", "format": "byte"}
+		}
+		return jsonObject{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return jsonObject{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		name := componentName(t)
+		if _, ok := b.components[name]; !ok {
+			b.components[name] = jsonObject{} // 先占位，避免递归类型无限展开
+			b.components[name] = b.structSchema(t)
+		}
+		return jsonObject{"$ref": "#/components/schemas/" + name}
+	}
+	return jsonObject{}
+}
+
+// structSchema 按 json 标签生成对象的属性列表，format 标签可覆盖字段格式。
+func (b *schemaBuilder) structSchema(t reflect.Type) jsonObject {
+	properties := jsonObject{}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		name := f.Name
+		if tag := f.Tag.Get("json"); tag != "" {
+			if tag == "-" {
+				continue
+			}
+			if n := strings.Split(tag, ",")[0]; n != "" {
+				name = n
+			}
+		}
+		schema := b.schemaFor(f.Type)
+		if format := f.Tag.Get("format"); format != "" {
+			schema = jsonObject{"type": "string", "format": format}
+		}
+		properties[name] = schema
+	}
+	return jsonObject{"type": "object", "properties": properties}
+}
+
+// componentName 返回类型在 components/schemas 中的名称（首字母大写）。
+func componentName(t reflect.Type) string {
+	r := []rune(t.Name())
+	r[0] = unicode.ToUpper(r[0])
+	return string(r)
+}
+
+// buildOpenAPI 由路由表生成 OpenAPI 3.0 文档。
+func buildOpenAPI(routes []route) jsonObject {
+	b := &schemaBuilder{components: jsonObject{}}
+	b.components["APIError"] = jsonObject{
+		"type": "object",
+		"properties": jsonObject{
+			"code":    jsonObject{"type": "string", "description": "机器可读的错误码"},
+			"message": jsonObject{"type": "string"},
+		},
+	}
+	errorResponse := jsonObject{
+		"description": "错误",
+		"content": jsonObject{"application/json": jsonObject{"schema": jsonObject{
+			"type": "object",
+			"properties": jsonObject{
+				"ok":    jsonObject{"type": "boolean", "enum": []bool{false}},
+				"data":  jsonObject{"nullable": true},
+				"error": jsonObject{"$ref": "#/components/schemas/APIError"},
+			},
+		}}},
+	}
+
+	paths := jsonObject{}
+	var tags []jsonObject
+	seenTags := map[string]bool{}
+	for _, rt := range routes {
+		if rt.Hidden {
+			continue
+		}
+		if !seenTags[rt.Tag] {
+			seenTags[rt.Tag] = true
+			tags = append(tags, jsonObject{"name": rt.Tag})
+		}
+
+		op := jsonObject{
+			"summary":     rt.Summary,
+			"tags":        []string{rt.Tag},
+			"operationId": operationID(rt),
+		}
+		if rt.Permission != "" {
+			op["x-permission"] = rt.Permission
+			op["security"] = []jsonObject{{"bearerAuth": []string{}}}
+		}
+		if rt.Tag == tagLegacy {
+			op["deprecated"] = true
+		}
+		if rt.LegacyGET {
+			op["description"] = "开启 allow_legacy_get 后也接受 GET。"
+		}
+
+		var params []jsonObject
+		for _, p := range rt.Params {
+			schema := jsonObject{"type": p.Type}
+			if len(p.Enum) > 0 {
+				schema["enum"] = p.Enum
+			}
+			param := jsonObject{"name": p.Name, "in": p.In, "required": p.Required, "schema": schema}
+			if p.Description != "" {
+				param["description"] = p.Description
+			}
+			params = append(params, param)
+		}
+		if len(params) > 0 {
+			op["parameters"] = params
+		}
+
+		if rt.Request != nil {
+			contentType := rt.RequestContentType
+			if contentType == "" {
+				contentType = "application/json"
+			}
+			op["requestBody"] = jsonObject{
+				"required": true,
+				"content":  jsonObject{contentType: jsonObject{"schema": b.schemaOf(rt.Request)}},
+			}
+		}
+
+		responses := jsonObject{}
+		if rt.api != nil {
+			data := jsonObject{"nullable": true}
+			if rt.Response != nil {
+				data = b.schemaOf(rt.Response)
+			}
+			responses["200"] = jsonObject{
+				"description": "成功",
+				"content": jsonObject{"application/json": jsonObject{"schema": jsonObject{
+					"type": "object",
+					"properties": jsonObject{
+						"ok":   jsonObject{"type": "boolean", "enum": []bool{true}},
+						"data": data,
+					},
+				}}},
+			}
+			responses["default"] = errorResponse
+		} else {
+			ok := jsonObject{"description": "成功"}
+			if rt.ContentType != "" {
+				ok["content"] = jsonObject{rt.ContentType: jsonObject{"schema": jsonObject{}}}
+			}
+			responses["200"] = ok
+		}
+		op["responses"] = responses
+
+		item, _ := paths[rt.Path].(jsonObject)
+		if item == nil {
+			item = jsonObject{}
+			paths[rt.Path] = item
+		}
+		item[strings.ToLower(rt.Method)] = op
+	}
+
+	return jsonObject{
+		"openapi": "3.0.3",
+		"info": jsonObject{
+			"title":   "BealinkGo Server API",
+			"version": APIVersion,
+			"description": "/api/v1 接口统一返回 {ok, data, error:{code,message}}；标记为 deprecated 的旧接口仅为兼容保留。" +
+				"x-permission 为接口所需的权限范围，局域网请求需通过 Authorization: Bearer <令牌> 或 access_token 查询参数携带具有该权限的令牌，本机请求无需令牌。" +
+				"浏览器发出的未携带令牌的写请求需附带 X-CSRF-Token 请求头，值为同源页面 Cookie bealink_csrf 的内容。",
+		},
+		"tags":  tags,
+		"paths": paths,
+		"components": jsonObject{
+			"schemas": b.components,
+			"securitySchemes": jsonObject{
+				"bearerAuth": jsonObject{"type": "http", "scheme": "bearer", "description": "在设置页面或 /api/v1/tokens 创建的访问令牌"},
+			},
+		},
+	}
+}
+
+// operationID 由方法和路径生成唯一的 operationId，例如 post_api_v1_power_action。
+func operationID(rt route) string {
+	return strings.ToLower(rt.Method) + strings.NewReplacer("/", "_", "{", "", "}", "", ".", "_").Replace(rt.Path)
+}
+
+// handleOpenAPI 返回由路由表生成的 OpenAPI 文档 (GET /api/openapi.json)。
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(buildOpenAPI(s.routes())); err != nil {
+		log.Printf("错误: 输出 OpenAPI 文档失败: %v", err)
+	}
+}
+
+// handleAPIExplorer 返回内置的 API 调试页面 (GET /api/explorer)。
+func handleAPIExplorer(w http.ResponseWriter, r *http.Request) {
+	htmlContent, err := templateFS.ReadFile("html/api_explorer.html")
+	if err != nil {
+		log.Printf("错误: 从 embed.FS 读取 api_explorer.html 失败: %v", err)
+		http.Error(w, "404 Not Found: UI File Missing", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(htmlContent)
+}
@@ -0,0 +1,131 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// pathParamPattern 匹配路径模板中的 {name} 参数。
+var pathParamPattern = regexp.MustCompile(`\{([^}.]+)(\.\.\.)?\}`)
+
+// collectRefs 递归收集 v 中所有 $ref 的值。
+func collectRefs(v interface{}, refs *[]string) {
+	switch x := v.(type) {
+	case map[string]interface{}:
+		for k, val := range x {
+			if ref, ok := val.(string); ok && k == "$ref" {
+				*refs = append(*refs, ref)
+				continue
+			}
+			collectRefs(val, refs)
+		}
+	case []interface{}:
+		for _, val := range x {
+			collectRefs(val, refs)
+		}
+	}
+}
+
+func TestOpenAPIListsEveryRoute(t *testing.T) {
+	s, h, _ := newTestServer(t)
+	w := serve(h, localRequest(http.MethodGet, "/api/openapi.json", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET /api/openapi.json 返回 %d", w.Code)
+	}
+	var doc struct {
+		OpenAPI    string                                       `json:"openapi"`
+		Info       map[string]interface{}                       `json:"info"`
+		Tags       []struct{ Name string }                      `json:"tags"`
+		Paths      map[string]map[string]map[string]interface{} `json:"paths"`
+		Components struct {
+			Schemas         map[string]interface{} `json:"schemas"`
+			SecuritySchemes map[string]interface{} `json:"securitySchemes"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatalf("OpenAPI 文档不是合法的 JSON: %v", err)
+	}
+	if doc.OpenAPI != "3.0.3" || doc.Info["title"] == nil || doc.Info["version"] != APIVersion {
+		t.Errorf("文档头部不正确: openapi=%q info=%v", doc.OpenAPI, doc.Info)
+	}
+	if doc.Components.SecuritySchemes["bearerAuth"] == nil {
+		t.Error("缺少 bearerAuth 安全方案")
+	}
+	tags := map[string]bool{}
+	for _, tag := range doc.Tags {
+		tags[tag.Name] = true
+	}
+
+	// 遍历路由表，逐条核对文档中的操作
+	operationIDs := map[string]string{}
+	documented := 0
+	for _, rt := range s.routes() {
+		name := rt.Method + " " + rt.Path
+		op := doc.Paths[rt.Path][strings.ToLower(rt.Method)]
+		if rt.Hidden {
+			if op != nil {
+				t.Errorf("%s 为 Hidden 路由，不应出现在文档中", name)
+			}
+			continue
+		}
+		documented++
+		if op == nil {
+			t.Errorf("文档中缺少 %s", name)
+			continue
+		}
+		id, _ := op["operationId"].(string)
+		if prev, dup := operationIDs[id]; dup || id == "" {
+			t.Errorf("%s 的 operationId %q 为空或与 %s 重复", name, id, prev)
+		}
+		operationIDs[id] = name
+		if !tags[rt.Tag] {
+			t.Errorf("%s 的标签 %q 未在 tags 中声明", name, rt.Tag)
+		}
+		if op["responses"] == nil {
+			t.Errorf("%s 缺少 responses", name)
+		}
+
+		perm, _ := op["x-permission"].(string)
+		if perm != rt.Permission {
+			t.Errorf("%s 的 x-permission = %q, 期望 %q", name, perm, rt.Permission)
+		}
+		if _, secured := op["security"]; secured != (rt.Permission != "") {
+			t.Errorf("%s 的 security 与权限范围 %q 不一致", name, rt.Permission)
+		}
+
+		// 路径模板中的参数都要在 parameters 中声明为 required 的 path 参数
+		declared := map[string]bool{}
+		params, _ := op["parameters"].([]interface{})
+		for _, p := range params {
+			p := p.(map[string]interface{})
+			if p["in"] == "path" && p["required"] == true {
+				declared[p["name"].(string)] = true
+			}
+		}
+		for _, m := range pathParamPattern.FindAllStringSubmatch(rt.Path, -1) {
+			if !declared[m[1]] {
+				t.Errorf("%s 未声明路径参数 %s", name, m[1])
+			}
+		}
+	}
+
+	operations := 0
+	for _, item := range doc.Paths {
+		operations += len(item)
+	}
+	if operations != documented {
+		t.Errorf("文档中有 %d 个操作，路由表中有 %d 条非 Hidden 路由", operations, documented)
+	}
+
+	var refs []string
+	collectRefs(map[string]interface{}{"paths": doc.Paths, "schemas": doc.Components.Schemas}, &refs)
+	for _, ref := range refs {
+		name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+		if !ok || doc.Components.Schemas[name] == nil {
+			t.Errorf("$ref %q 指向不存在的 schema", ref)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package server
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+)
+
+// 操作策略，见 BarkConfig.ActionPolicies。
+const (
+	PolicyAllow   = "allow"
+	PolicyConfirm = "confirm"
+	PolicyDeny    = "deny"
+)
+
+const (
+	ErrCodeActionDenied   = "action_denied"   // 策略禁止远程执行该操作
+	ErrCodeConfirmDenied  = "confirm_denied"  // 电脑前的人在确认框中拒绝了该操作
+	ErrCodeConfirmTimeout = "confirm_timeout" // 确认框超时无人作答
+	ErrCodeConfirmPending = "confirm_pending" // 同一操作已有确认框在等待
+)
+
+// confirmResultHeader 经过确认框的请求在响应中带上确认结果 (approved / denied / timeout)。
+const confirmResultHeader = "X-Bealink-Confirm"
+
+// 确认框等待时间的取值范围（秒）。
+const (
+	minConfirmTimeoutSec = 5
+	maxConfirmTimeoutSec = 300
+)
+
+// policyAction 可以设置策略的操作。
+type policyAction struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Policy      string `json:"policy"`
+}
+
+// policyActions 可设置策略的操作及其说明，顺序即设置页面中的显示顺序。
+var policyActions = []policyAction{
+	{Name: "sleep", Description: "睡眠"},
+	{Name: "shutdown", Description: "关机"},
+	{Name: "restart", Description: "重启"},
+	{Name: "hibernate", Description: "休眠"},
+	{Name: "lock", Description: "锁定电脑"},
+	{Name: "signout", Description: "注销"},
+	{Name: "display", Description: "开关显示器"},
+	{Name: "volume", Description: "调节音量"},
+	{Name: "media", Description: "媒体控制"},
+	{Name: "clipboard", Description: "读写剪贴板"},
+	{Name: "input", Description: "输入文字和粘贴"},
+}
+
+func policyDescription(action string) string {
+	for _, a := range policyActions {
+		if a.Name == action {
+			return a.Description
+		}
+	}
+	return action
+}
+
+func validPolicy(p string) bool {
+	return p == PolicyAllow || p == PolicyConfirm || p == PolicyDeny
+}
+
+// actionPolicies 返回各操作当前的策略。
+func actionPolicies() []policyAction {
+	policies := bark.GetConfig().ActionPolicies
+	list := make([]policyAction, len(policyActions))
+	for i, a := range policyActions {
+		a.Policy = PolicyAllow
+		if p, ok := policies[a.Name]; ok {
+			a.Policy = p
+		}
+		list[i] = a
+	}
+	return list
+}
+
+func apiPolicyList(r *http.Request) (interface{}, error) {
+	return actionPolicies(), nil
+}
+
+// confirmGate 保证同一操作同时只有一个确认框。
+type confirmGate struct {
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func (g *confirmGate) acquire(action string) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.pending[action] {
+		return false
+	}
+	if g.pending == nil {
+		g.pending = make(map[string]bool)
+	}
+	g.pending[action] = true
+	return true
+}
+
+func (g *confirmGate) release(action string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	delete(g.pending, action)
+}
+
+// policyName 解析路由声明的策略名："{name}" 形式取同名路径参数的值，没有该路径参数时取同名查询参数。
+func policyName(r *http.Request, policy string) string {
+	if strings.HasPrefix(policy, "{") && strings.HasSuffix(policy, "}") {
+		name := strings.Trim(policy, "{}")
+		if v := r.PathValue(name); v != "" {
+			return v
+		}
+		return r.URL.Query().Get(name)
+	}
+	return policy
+}
+
+// checkTogglePolicy 用于切换倒计时的旧电源接口：该操作已在倒计时中时请求只会取消它，
+// 除 deny 外无需确认；否则同 checkPolicy。
+func (s *Server) checkTogglePolicy(w http.ResponseWriter, r *http.Request, action string) error {
+	if bark.GetConfig().ActionPolicies[action] != PolicyDeny {
+		if _, running := s.power.Pending(action); running {
+			return nil
+		}
+	}
+	return s.checkPolicy(w, r, action)
+}
+
+// checkPolicy 按 action 的策略决定请求能否执行：deny 直接拒绝；confirm 在本机弹出确认框并等待结果，
+// 本机发起的请求无需确认。
+func (s *Server) checkPolicy(w http.ResponseWriter, r *http.Request, action string) error {
+	cfg := bark.GetConfig()
+	policy := cfg.ActionPolicies[action]
+	desc := policyDescription(action)
+	switch policy {
+	case "", PolicyAllow:
+		return nil
+	case PolicyDeny:
+		log.Printf("[Policy] 拒绝 %s 的%s请求：策略禁止远程执行", auth.ClientIP(r), desc)
+		return apiErrorf(http.StatusForbidden, ErrCodeActionDenied, "策略禁止远程%s", desc)
+	}
+
+	id := auth.FromContext(r.Context())
+	if id != nil && id.Local {
+		return nil
+	}
+	if !s.confirms.acquire(action) {
+		return apiErrorf(http.StatusConflict, ErrCodeConfirmPending, "已有%s请求在等待电脑前的人确认", desc)
+	}
+	defer s.confirms.release(action)
+
+	client := "未知设备"
+	if id != nil {
+		client = id.Name
+	} else if rec, ok := r.Context().Value(auditContextKey{}).(*auditRecord); ok && rec.client != "" {
+		client = rec.client
+	}
+	timeout := time.Duration(cfg.ConfirmTimeoutSec) * time.Second
+	// 等待确认的时间可能超过服务器的写超时，为本次请求单独延长
+	if err := http.NewResponseController(w).SetWriteDeadline(time.Now().Add(timeout + 10*time.Second)); err != nil {
+		log.Printf("[Policy] 延长写超时失败: %v", err)
+	}
+
+	log.Printf("[Policy] 等待本机确认 %s (%s) 的%s请求，最长 %s", client, auth.ClientIP(r), desc, timeout)
+	message := fmt.Sprintf("%s (%s) 请求%s。\n是否允许？%d 秒内未选择将自动拒绝。", client, auth.ClientIP(r), desc, cfg.ConfirmTimeoutSec)
+	result, err := s.backend.Confirmer.Confirm(r.Context(), "Bealink 远程操作确认", message, timeout)
+	if err != nil {
+		log.Printf("[Policy] 显示确认框失败，拒绝%s请求: %v", desc, err)
+		return apiErrorf(http.StatusServiceUnavailable, ErrCodeUnsupported, "无法在电脑上显示确认框: %v", err)
+	}
+	w.Header().Set(confirmResultHeader, string(result))
+	log.Printf("[Policy] %s (%s) 的%s请求确认结果: %s", client, auth.ClientIP(r), desc, result)
+	switch result {
+	case platform.ConfirmApproved:
+		return nil
+	case platform.ConfirmDenied:
+		return apiErrorf(http.StatusForbidden, ErrCodeConfirmDenied, "电脑前的人拒绝了%s请求", desc)
+	default:
+		return apiErrorf(http.StatusForbidden, ErrCodeConfirmTimeout, "%s请求在 %d 秒内未获确认", desc, cfg.ConfirmTimeoutSec)
+	}
+}
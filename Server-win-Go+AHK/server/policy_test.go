@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bealinkserver/bark"
+	"bealinkserver/logging"
+	"bealinkserver/platform"
+	"bealinkserver/power"
+)
+
+// fakeConfirmer 返回预设结果的确认框；block 非 nil 时等到它被关闭才返回。
+type fakeConfirmer struct {
+	mu     sync.Mutex
+	result platform.ConfirmResult
+	block  chan struct{}
+	calls  int
+	shown  chan struct{}
+}
+
+func newFakeConfirmer(result platform.ConfirmResult) *fakeConfirmer {
+	return &fakeConfirmer{result: result, shown: make(chan struct{}, 16)}
+}
+
+func (c *fakeConfirmer) Confirm(ctx context.Context, title, message string, timeout time.Duration) (platform.ConfirmResult, error) {
+	c.mu.Lock()
+	c.calls++
+	result, block := c.result, c.block
+	c.mu.Unlock()
+	c.shown <- struct{}{}
+	if block != nil {
+		<-block
+	}
+	return result, nil
+}
+
+func (c *fakeConfirmer) Calls() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.calls
+}
+
+// newPolicyTestServer 创建使用 confirmer 作为确认框的 Server，并关闭令牌认证以便模拟局域网请求。
+func newPolicyTestServer(t *testing.T, confirmer platform.Confirmer) (*Server, http.Handler) {
+	t.Helper()
+	b := platform.NewSimulator().Backend()
+	b.Confirmer = confirmer
+	s := New(b, logging.NewHub())
+	t.Cleanup(func() { s.power.CancelAll("测试结束") })
+	updateConfig(t, func(cfg *bark.BarkConfig) { cfg.RequireAuth = false }, func(cfg *bark.BarkConfig) { cfg.RequireAuth = true })
+	return s, s.Handler()
+}
+
+// apiErrorCode 返回 /api/v1 错误响应中的 error.code。
+func apiErrorCode(t *testing.T, body string) string {
+	t.Helper()
+	var env struct {
+		Error *APIError `json:"error"`
+	}
+	if err := json.Unmarshal([]byte(body), &env); err != nil || env.Error == nil {
+		return ""
+	}
+	return env.Error.Code
+}
+
+func TestCheckPolicy(t *testing.T) {
+	tests := []struct {
+		name       string
+		policy     string
+		result     platform.ConfirmResult
+		local      bool
+		wantStatus int
+		wantCode   string
+		wantCalls  int
+	}{
+		{name: "allow", policy: PolicyAllow, wantStatus: http.StatusOK},
+		{name: "confirm approved", policy: PolicyConfirm, result: platform.ConfirmApproved, wantStatus: http.StatusOK, wantCalls: 1},
+		{name: "confirm denied", policy: PolicyConfirm, result: platform.ConfirmDenied, wantStatus: http.StatusForbidden, wantCode: ErrCodeConfirmDenied, wantCalls: 1},
+		{name: "confirm timeout", policy: PolicyConfirm, result: platform.ConfirmTimeout, wantStatus: http.StatusForbidden, wantCode: ErrCodeConfirmTimeout, wantCalls: 1},
+		{name: "confirm local", policy: PolicyConfirm, local: true, wantStatus: http.StatusOK},
+		{name: "deny", policy: PolicyDeny, wantStatus: http.StatusForbidden, wantCode: ErrCodeActionDenied},
+		{name: "deny local", policy: PolicyDeny, local: true, wantStatus: http.StatusForbidden, wantCode: ErrCodeActionDenied},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmer := newFakeConfirmer(tt.result)
+			_, h := newPolicyTestServer(t, confirmer)
+			setPolicy(t, "volume", tt.policy)
+
+			newRequest := lanRequest
+			if tt.local {
+				newRequest = localRequest
+			}
+			w := serve(h, newRequest(http.MethodPost, "/api/v1/volume/up", nil))
+			if w.Code != tt.wantStatus {
+				t.Errorf("状态码 = %d, 期望 %d (%s)", w.Code, tt.wantStatus, w.Body.String())
+			}
+			if code := apiErrorCode(t, w.Body.String()); code != tt.wantCode {
+				t.Errorf("错误码 = %q, 期望 %q", code, tt.wantCode)
+			}
+			if n := confirmer.Calls(); n != tt.wantCalls {
+				t.Errorf("确认框弹出 %d 次, 期望 %d", n, tt.wantCalls)
+			}
+			if tt.wantCalls > 0 && w.Header().Get(confirmResultHeader) != string(tt.result) {
+				t.Errorf("%s = %q, 期望 %q", confirmResultHeader, w.Header().Get(confirmResultHeader), tt.result)
+			}
+		})
+	}
+}
+
+func TestCheckPolicyConfirmPending(t *testing.T) {
+	confirmer := newFakeConfirmer(platform.ConfirmApproved)
+	confirmer.block = make(chan struct{})
+	_, h := newPolicyTestServer(t, confirmer)
+	setPolicy(t, "volume", PolicyConfirm)
+
+	first := make(chan int)
+	go func() { first <- serve(h, lanRequest(http.MethodPost, "/api/v1/volume/up", nil)).Code }()
+	<-confirmer.shown
+
+	w := serve(h, lanRequest(http.MethodPost, "/api/v1/volume/down", nil))
+	if w.Code != http.StatusConflict || apiErrorCode(t, w.Body.String()) != ErrCodeConfirmPending {
+		t.Errorf("已有确认框等待时状态码 = %d (%s), 期望 409 confirm_pending", w.Code, w.Body.String())
+	}
+	close(confirmer.block)
+	if code := <-first; code != http.StatusOK {
+		t.Errorf("确认通过后状态码 = %d, 期望 200", code)
+	}
+	if n := confirmer.Calls(); n != 1 {
+		t.Errorf("确认框弹出 %d 次, 期望 1", n)
+	}
+}
+
+// TestCheckPolicyPendingOnlySkipsToggle 操作已在倒计时中时，只有旧接口的切换（取消）请求无需确认，
+// 添加定时任务等其他请求仍要确认。
+func TestCheckPolicyPendingOnlySkipsToggle(t *testing.T) {
+	confirmer := newFakeConfirmer(platform.ConfirmDenied)
+	s, h := newPolicyTestServer(t, confirmer)
+	setPolicy(t, "shutdown", PolicyConfirm)
+	if _, err := s.power.Start(power.Request{Action: "shutdown", Seconds: 60, Source: "测试"}); err != nil {
+		t.Fatal(err)
+	}
+
+	r := lanRequest(http.MethodPost, "/api/v1/power/schedules?action=shutdown", strings.NewReader(`{"delay_minutes":30}`))
+	r.Header.Set("Content-Type", "application/json")
+	if w := serve(h, r); w.Code != http.StatusForbidden {
+		t.Errorf("倒计时中添加定时关机的状态码 = %d, 期望 403 (%s)", w.Code, w.Body.String())
+	}
+	if n := confirmer.Calls(); n != 1 {
+		t.Fatalf("添加定时关机应弹出确认框，实际 %d 次", n)
+	}
+
+	if w := serve(h, lanRequest(http.MethodPost, "/shutdown", nil)); w.Code != http.StatusOK {
+		t.Errorf("取消倒计时的状态码 = %d, 期望 200 (%s)", w.Code, w.Body.String())
+	}
+	if n := confirmer.Calls(); n != 1 {
+		t.Errorf("取消倒计时不应弹出确认框，实际共 %d 次", n)
+	}
+	if _, running := s.power.Pending("shutdown"); running {
+		t.Error("关机倒计时应已取消")
+	}
+}
+
+func TestCheckSchedulePolicy(t *testing.T) {
+	tests := []struct {
+		name      string
+		policy    string
+		result    platform.ConfirmResult
+		wantErr   bool
+		wantCalls int
+	}{
+		{name: "allow", policy: PolicyAllow},
+		{name: "confirm approved", policy: PolicyConfirm, result: platform.ConfirmApproved, wantCalls: 1},
+		{name: "confirm denied", policy: PolicyConfirm, result: platform.ConfirmDenied, wantErr: true, wantCalls: 1},
+		{name: "confirm timeout", policy: PolicyConfirm, result: platform.ConfirmTimeout, wantErr: true, wantCalls: 1},
+		{name: "deny", policy: PolicyDeny, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			confirmer := newFakeConfirmer(tt.result)
+			s, _ := newPolicyTestServer(t, confirmer)
+			setPolicy(t, "shutdown", tt.policy)
+
+			err := s.checkSchedulePolicy(power.Schedule{ID: "sc1", Action: "shutdown"})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("err = %v, 期望出错: %v", err, tt.wantErr)
+			}
+			if n := confirmer.Calls(); n != tt.wantCalls {
+				t.Errorf("确认框弹出 %d 次, 期望 %d", n, tt.wantCalls)
+			}
+		})
+	}
+}
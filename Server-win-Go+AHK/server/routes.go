@@ -0,0 +1,328 @@
+package server
+
+import (
+	"net/http"
+
+	"bealinkserver/audit"
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+	"bealinkserver/power"
+)
+
+// routeParam 描述一个路径或查询参数。
+type routeParam struct {
+	Name        string
+	In          string // "path" 或 "query"
+	Type        string // OpenAPI 基本类型: string / integer / boolean
+	Required    bool
+	Description string
+	Enum        []string
+}
+
+// route 一条路由及其元数据。路由表同时用于注册 ServeMux 和生成 /api/openapi.json。
+type route struct {
+	Method     string
+	Path       string // 支持 Go 1.22 ServeMux 的 {name} 路径参数，与 OpenAPI 写法一致
+	Tag        string
+	Summary    string
+	Permission string // 访问所需的权限范围 (auth.Scope*)，空表示无需令牌即可访问
+	Params     []routeParam
+
+	// Request / Response 为请求体和响应 data 的示例值（通常是零值），用于生成 JSON Schema；nil 表示没有。
+	Request            interface{}
+	RequestContentType string // 默认为 application/json；text/plain 的请求体在审计日志中只记录长度
+	Response           interface{}
+
+	// api 为 /api/v1 接口的处理函数，响应使用统一格式。
+	api apiFunc
+	// handler 为旧路由或页面的处理函数，响应格式各不相同，不限制请求方法。
+	handler http.HandlerFunc
+	// ContentType 为 handler 的响应类型，仅用于文档。
+	ContentType string
+	// Hidden 的路由不出现在 OpenAPI 文档中（图标等静态资源）。
+	Hidden bool
+	// LegacyGET 表示旧接口的写操作：只接受 POST，开启 allow_legacy_get 后也接受 GET。
+	LegacyGET bool
+	// Policy 为需要按操作策略（允许/确认/拒绝，见 policy.go）检查的操作名，"{name}" 表示取同名路径参数；空表示不检查。
+	Policy string
+	// Toggle 表示切换倒计时的旧电源接口：该操作已在倒计时中时请求只会取消它，无需按策略确认。
+	Toggle bool
+}
+
+const (
+	tagSystem    = "系统"
+	tagPower     = "电源"
+	tagDisplay   = "显示器"
+	tagVolume    = "音量"
+	tagMedia     = "媒体"
+	tagClipboard = "剪贴板"
+	tagInput     = "输入"
+	tagSettings  = "设置"
+	tagTokens    = "访问令牌"
+	tagSecurity  = "安全"
+	tagLinks     = "签名链接"
+	tagAudit     = "审计日志"
+	tagSimulator = "模拟后端"
+	tagPages     = "页面"
+	tagLegacy    = "旧接口"
+)
+
+// auditParamsDoc 审计日志查询和导出接口共用的过滤参数。
+var auditParamsDoc = []routeParam{
+	{Name: "since", In: "query", Type: "string", Description: "起始时间，RFC 3339 或 Unix 秒"},
+	{Name: "until", In: "query", Type: "string", Description: "结束时间（不含），RFC 3339 或 Unix 秒"},
+	{Name: "ip", In: "query", Type: "string", Description: "来源 IP"},
+	{Name: "client", In: "query", Type: "string", Description: "令牌或设备名称（子串匹配）"},
+	{Name: "action", In: "query", Type: "string", Description: "请求路径（子串匹配）"},
+	{Name: "result", In: "query", Type: "string", Enum: []string{"ok", "error"}},
+	{Name: "limit", In: "query", Type: "integer"},
+}
+
+var actionParam = routeParam{Name: "action", In: "query", Type: "string", Required: true, Enum: power.Actions}
+
+// countdownParams 旧接口 /sleep、/shutdown 等的可选倒计时参数。
+var countdownParams = []routeParam{
+	{Name: "seconds", In: "query", Type: "integer", Description: "倒计时时长（秒），省略时使用设置中的默认值，必须在设置的范围内"},
+	{Name: "reason", In: "query", Type: "string", Description: "显示在本机倒计时窗口上的原因，最多 100 个字符"},
+}
+
+// routes 返回服务器的完整路由表。
+func (s *Server) routes() []route {
+	return []route{
+		// ---- /api/v1 ----
+		{Method: http.MethodGet, Path: "/api/v1/ping", Tag: tagSystem, Summary: "检查服务是否在线",
+			Response: pingResponse{}, api: s.apiPing},
+
+		{Method: http.MethodGet, Path: "/api/v1/power", Tag: tagPower, Summary: "查询电源操作状态 (idle / counting_down / executing / cancelled / failed)",
+			Permission: auth.ScopePower, Response: power.Status{}, api: s.apiPowerStatus},
+		{Method: http.MethodPost, Path: "/api/v1/power/{action}", Policy: "{action}", Tag: tagPower, Summary: "启动睡眠/关机/重启/休眠/锁定/注销倒计时，请求体可省略（同一操作已在倒计时中时返回它，其他电源操作进行中时返回 409）",
+			Permission: auth.ScopePower, Params: []routeParam{{Name: "action", In: "path", Type: "string", Required: true, Enum: power.Actions}},
+			Request: powerStartRequest{}, Response: power.Action{}, api: s.apiPowerStart},
+		{Method: http.MethodPost, Path: "/api/v1/power/abort", Tag: tagPower, Summary: "取消倒计时中的电源操作，并取消系统中已计划的关机或重启 (shutdown /a)",
+			Permission: auth.ScopePower, Response: power.Status{}, api: s.apiPowerAbort},
+		{Method: http.MethodDelete, Path: "/api/v1/power/pending/{id}", Tag: tagPower, Summary: "按 ID 取消倒计时中的电源操作（已开始执行时返回 409）",
+			Permission: auth.ScopePower, Params: []routeParam{{Name: "id", In: "path", Type: "string", Required: true}}, Response: power.Action{}, api: s.apiPowerCancel},
+		{Method: http.MethodGet, Path: "/api/v1/power/schedules", Tag: tagPower, Summary: "列出定时电源操作，按下一次执行时间排序",
+			Permission: auth.ScopePower, Response: []power.Schedule{}, api: s.apiScheduleList},
+		{Method: http.MethodPost, Path: "/api/v1/power/schedules", Policy: "{action}", Tag: tagPower, Summary: "添加定时电源操作：指定时间 (at)、延时 (delay_minutes) 或每天 (daily)，重启后保留",
+			Permission: auth.ScopePower, Params: []routeParam{actionParam}, Request: scheduleCreateRequest{}, Response: power.Schedule{}, api: s.apiScheduleCreate},
+		{Method: http.MethodDelete, Path: "/api/v1/power/schedules/{id}", Tag: tagPower, Summary: "删除定时电源操作",
+			Permission: auth.ScopePower, Params: []routeParam{{Name: "id", In: "path", Type: "string", Required: true}}, Response: power.Schedule{}, api: s.apiScheduleCancel},
+		{Method: http.MethodGet, Path: "/api/v1/countdown", Tag: tagPower, Summary: "列出倒计时或执行中的电源操作",
+			Permission: auth.ScopePower, Response: []power.Action{}, api: s.apiCountdownList},
+		{Method: http.MethodDelete, Path: "/api/v1/countdown", Tag: tagPower, Summary: "按操作类型取消电源倒计时",
+			Permission: auth.ScopePower, Params: []routeParam{actionParam}, api: s.apiCountdownCancel},
+
+		{Method: http.MethodGet, Path: "/api/v1/display", Tag: tagDisplay, Summary: "查询显示器状态",
+			Permission: auth.ScopePower, Response: displayState{}, api: s.apiDisplayState},
+		{Method: http.MethodPost, Path: "/api/v1/display/toggle", Policy: "display", Tag: tagDisplay, Summary: "切换显示器开关",
+			Permission: auth.ScopePower, Response: displayState{}, api: s.apiDisplayToggle},
+		{Method: http.MethodPost, Path: "/api/v1/display/on", Policy: "display", Tag: tagDisplay, Summary: "打开显示器（已打开时不做任何操作）",
+			Permission: auth.ScopePower, Response: displaySetResult{}, api: s.apiDisplayOn},
+		{Method: http.MethodPost, Path: "/api/v1/display/off", Policy: "display", Tag: tagDisplay, Summary: "关闭显示器（已关闭时不做任何操作）",
+			Permission: auth.ScopePower, Response: displaySetResult{}, api: s.apiDisplayOff},
+		{Method: http.MethodGet, Path: "/ws/display", Tag: tagDisplay, Summary: "显示器状态变化推送 (WebSocket)，连接后先发送当前状态",
+			Permission: auth.ScopePower, handler: s.handleDisplayEvents},
+
+		{Method: http.MethodGet, Path: "/api/v1/volume", Tag: tagVolume, Summary: "查询音量和静音状态",
+			Permission: auth.ScopeMedia, Response: VolumeInfo{}, api: s.apiVolumeGet},
+		{Method: http.MethodPut, Path: "/api/v1/volume", Policy: "volume", Tag: tagVolume, Summary: "设置音量",
+			Permission: auth.ScopeMedia, Request: volumeRequest{}, Response: VolumeInfo{}, api: s.apiVolumeSet},
+		{Method: http.MethodPost, Path: "/api/v1/volume/up", Policy: "volume", Tag: tagVolume, Summary: "模拟按下音量加键",
+			Permission: auth.ScopeMedia, api: s.apiVolumeStep(true)},
+		{Method: http.MethodPost, Path: "/api/v1/volume/down", Policy: "volume", Tag: tagVolume, Summary: "模拟按下音量减键",
+			Permission: auth.ScopeMedia, api: s.apiVolumeStep(false)},
+		{Method: http.MethodPost, Path: "/api/v1/volume/mute", Policy: "volume", Tag: tagVolume, Summary: "切换静音",
+			Permission: auth.ScopeMedia, Response: VolumeInfo{}, api: s.apiVolumeMute},
+
+		{Method: http.MethodGet, Path: "/api/v1/media", Tag: tagMedia, Summary: "查询媒体信息",
+			Permission: auth.ScopeMedia, Response: MediaInfo{}, api: apiMediaInfo},
+		{Method: http.MethodPost, Path: "/api/v1/media/{command}", Policy: "media", Tag: tagMedia, Summary: "发送媒体控制快捷键",
+			Permission: auth.ScopeMedia, Params: []routeParam{{Name: "command", In: "path", Type: "string", Required: true, Enum: []string{"playpause", "next", "prev"}}},
+			api: s.apiMediaCommand},
+
+		{Method: http.MethodGet, Path: "/api/v1/clipboard", Policy: "clipboard", Tag: tagClipboard, Summary: "读取剪贴板文本",
+			Permission: auth.ScopeClipboardRead, Response: textPayload{}, api: s.apiClipboardGet},
+		{Method: http.MethodPut, Path: "/api/v1/clipboard", Policy: "clipboard", Tag: tagClipboard, Summary: "写入剪贴板文本",
+			Permission: auth.ScopeClipboardWrite, Request: textPayload{}, api: s.apiClipboardSet},
+		{Method: http.MethodPost, Path: "/api/v1/clipboard/image", Policy: "clipboard", Tag: tagClipboard, Summary: "上传图片到剪贴板 (multipart 字段 image)",
+			Permission: auth.ScopeClipboardWrite, Request: imageUpload{}, RequestContentType: "multipart/form-data", api: s.apiClipboardImage},
+
+		{Method: http.MethodPost, Path: "/api/v1/input/text", Policy: "input", Tag: tagInput, Summary: "写入剪贴板并粘贴到当前焦点窗口",
+			Permission: auth.ScopeInput, Request: textPayload{}, api: s.apiInputText},
+		{Method: http.MethodPost, Path: "/api/v1/input/paste", Policy: "input", Tag: tagInput, Summary: "模拟粘贴 (Ctrl+V)",
+			Permission: auth.ScopeInput, api: s.apiInputPaste},
+
+		{Method: http.MethodGet, Path: "/api/v1/settings", Tag: tagSettings, Summary: "读取 Bark 通知设置",
+			Permission: auth.ScopeAdminSettings, Response: bark.BarkConfig{}, api: apiSettingsGet},
+		{Method: http.MethodPut, Path: "/api/v1/settings", Tag: tagSettings, Summary: "更新 Bark 通知设置（未提供的字段保持不变）",
+			Permission: auth.ScopeAdminSettings, Request: bark.BarkConfig{}, Response: bark.BarkConfig{}, api: apiSettingsUpdate},
+		{Method: http.MethodPost, Path: "/api/v1/bark/test", Tag: tagSettings, Summary: "发送 Bark 测试通知",
+			Permission: auth.ScopeAdminSettings, Request: barkTestRequest{}, api: apiBarkTest},
+
+		{Method: http.MethodGet, Path: "/api/v1/tokens", Tag: tagTokens, Summary: "列出访问令牌",
+			Permission: auth.ScopeAdminSettings, Response: []auth.TokenInfo{}, api: apiTokenList},
+		{Method: http.MethodPost, Path: "/api/v1/tokens", Tag: tagTokens, Summary: "创建访问令牌（明文令牌只在响应中出现一次）",
+			Permission: auth.ScopeAdminSettings, Request: tokenCreateRequest{}, Response: createdToken{}, api: apiTokenCreate},
+		{Method: http.MethodDelete, Path: "/api/v1/tokens/{id}", Tag: tagTokens, Summary: "吊销访问令牌",
+			Permission: auth.ScopeAdminSettings, Params: []routeParam{{Name: "id", In: "path", Type: "string", Required: true}}, api: apiTokenRevoke},
+		{Method: http.MethodPost, Path: "/api/v1/pair/start", Tag: tagTokens, Summary: "发起设备配对，本机弹出 6 位配对码（无需令牌，按 IP 限流）",
+			Request: pairStartRequest{}, Response: auth.PairRequest{}, api: s.apiPairStart},
+		{Method: http.MethodPost, Path: "/api/v1/pair/complete", Tag: tagTokens, Summary: "提交配对码，获得按设备命名的令牌（输错 5 次作废）",
+			Request: pairCompleteRequest{}, Response: createdToken{}, api: s.apiPairComplete},
+		{Method: http.MethodGet, Path: "/api/v1/guests", Tag: tagTokens, Summary: "列出有效的访客令牌（通过 DELETE /api/v1/tokens/{id} 提前吊销）",
+			Permission: auth.ScopeAdminSettings, Response: []auth.TokenInfo{}, api: apiGuestList},
+		{Method: http.MethodPost, Path: "/api/v1/guests", Tag: tagTokens, Summary: "创建限时访客令牌，返回访问地址和二维码（默认仅媒体控制、2 小时有效，最长 7 天）",
+			Permission: auth.ScopeAdminSettings, Request: guestCreateRequest{}, Response: createdGuest{}, api: apiGuestCreate},
+		{Method: http.MethodGet, Path: "/api/v1/tokens/scopes", Tag: tagTokens, Summary: "列出可分配的权限范围",
+			Permission: auth.ScopeAdminSettings, Response: []auth.ScopeInfo{}, api: apiTokenScopes},
+
+		{Method: http.MethodGet, Path: "/api/v1/security/policies", Tag: tagSecurity, Summary: "列出各远程操作的策略 (allow / confirm / deny)，通过 /api/v1/settings 的 action_policies 修改",
+			Permission: auth.ScopeAdminSettings, Response: []policyAction{}, api: apiPolicyList},
+		{Method: http.MethodGet, Path: "/api/v1/security/blocked", Tag: tagSecurity, Summary: "列出因多次认证失败被封禁的 IP（含已到期但仍影响下次封禁时长的记录）",
+			Permission: auth.ScopeAdminSettings, Response: []auth.BlockInfo{}, api: apiBlockedList},
+		{Method: http.MethodDelete, Path: "/api/v1/security/blocked", Tag: tagSecurity, Summary: "解除全部封禁",
+			Permission: auth.ScopeAdminSettings, api: apiBlockedClear},
+		{Method: http.MethodDelete, Path: "/api/v1/security/blocked/{ip}", Tag: tagSecurity, Summary: "解除指定 IP 的封禁",
+			Permission: auth.ScopeAdminSettings, Params: []routeParam{{Name: "ip", In: "path", Type: "string", Required: true}}, api: apiBlockedRemove},
+
+		{Method: http.MethodGet, Path: "/api/v1/audit", Tag: tagAudit, Summary: "查询远程操作的审计日志，按时间从新到旧（默认 100 条，最多 1000 条）",
+			Permission: auth.ScopeAdminSettings, Params: auditParamsDoc, Response: []audit.Entry{}, api: s.apiAuditList},
+		{Method: http.MethodGet, Path: "/api/v1/audit/export", Tag: tagAudit, Summary: "导出审计日志为 CSV 或 JSONL（过滤参数同上，默认导出全部）",
+			Permission: auth.ScopeAdminSettings, ContentType: "text/csv",
+			Params: append([]routeParam{{Name: "format", In: "query", Type: "string", Enum: []string{"csv", "jsonl"}}}, auditParamsDoc...), handler: s.handleAuditExport},
+
+		{Method: http.MethodGet, Path: "/api/v1/links/actions", Tag: tagLinks, Summary: "列出可生成签名链接的操作",
+			Permission: auth.ScopeAdminSettings, Response: []linkAction{}, api: s.apiLinkActions},
+		{Method: http.MethodPost, Path: "/api/v1/links", Tag: tagLinks, Summary: "生成一次性签名链接（ttl_seconds 为 0 时 24 小时有效，最长 30 天）",
+			Permission: auth.ScopeAdminSettings, Request: linkCreateRequest{}, Response: signedLink{}, api: s.apiLinkCreate},
+
+		{Method: http.MethodGet, Path: "/api/v1/simulator/journal", Tag: tagSimulator, Summary: "查询模拟后端状态与操作日志",
+			Permission: auth.ScopeAdminSettings, Response: simulatorJournal{}, api: s.apiSimulatorJournal},
+		{Method: http.MethodDelete, Path: "/api/v1/simulator/journal", Tag: tagSimulator, Summary: "清空模拟后端操作日志",
+			Permission: auth.ScopeAdminSettings, api: s.apiSimulatorClearJournal},
+		{Method: http.MethodPost, Path: "/api/v1/simulator/confirm", Tag: tagSimulator, Summary: "模拟本机用户在确认框上允许或拒绝",
+			Permission: auth.ScopeAdminSettings, Params: []routeParam{{Name: "result", In: "query", Type: "string", Required: true, Enum: []string{"approved", "denied"}}},
+			api: s.apiSimulatorConfirm},
+		{Method: http.MethodPost, Path: "/api/v1/simulator/display", Tag: tagSimulator, Summary: "模拟系统自行打开或关闭显示器",
+			Permission: auth.ScopeAdminSettings, Params: []routeParam{{Name: "off", In: "query", Type: "boolean", Required: true}},
+			Response: displayState{}, api: s.apiSimulatorDisplay},
+		{Method: http.MethodPost, Path: "/api/v1/simulator/countdown/cancel", Tag: tagSimulator, Summary: "模拟点击本机倒计时窗口取消",
+			Permission: auth.ScopeAdminSettings, Params: []routeParam{actionParam}, api: s.apiSimulatorClickCancel},
+
+		// ---- 页面 ----
+		{Method: http.MethodGet, Path: "/", Tag: tagPages, Summary: "移动端控制台", ContentType: "text/html", handler: handleRoot},
+		{Method: http.MethodGet, Path: "/setting", Tag: tagPages, Summary: "设置页面 (GET) / 保存设置 (POST 表单)",
+			Permission: auth.ScopeAdminSettings, ContentType: "text/html", handler: s.handleSetting},
+		{Method: http.MethodGet, Path: "/debug", Tag: tagPages, Summary: "调试日志页面", Permission: auth.ScopeAdminSettings, ContentType: "text/html", handler: handleDebugPage},
+		{Method: http.MethodGet, Path: "/ws/logs", Tag: tagPages, Summary: "实时日志 WebSocket", Permission: auth.ScopeAdminSettings,
+			handler: func(w http.ResponseWriter, r *http.Request) { serveWs(s.logHub, w, r) }},
+		{Method: http.MethodGet, Path: "/api/explorer", Tag: tagPages, Summary: "API 调试页面", ContentType: "text/html", handler: handleAPIExplorer},
+		{Method: http.MethodGet, Path: "/api/openapi.json", Tag: tagPages, Summary: "本文档 (OpenAPI 3.0)", ContentType: "application/json", handler: s.handleOpenAPI},
+		{Method: http.MethodGet, Path: "/a/{action}", Tag: tagLinks, Summary: "执行签名链接对应的操作（无需令牌，每个链接只能使用一次）", ContentType: "text/plain",
+			Params: []routeParam{{Name: "action", In: "path", Type: "string", Required: true, Enum: s.linkActionNames()}, {Name: "exp", In: "query", Type: "integer", Required: true},
+				{Name: "nonce", In: "query", Type: "string", Required: true}, {Name: "sig", In: "query", Type: "string", Required: true}},
+			handler: s.handleSignedAction},
+		{Method: http.MethodGet, Path: "/auth.js", Hidden: true, handler: handleAuthJS},
+		{Method: http.MethodGet, Path: "/favicon.ico", Hidden: true, handler: handleFavicon},
+		{Method: http.MethodGet, Path: "/icon.ico", Hidden: true, handler: handleIconICO},
+
+		// ---- 旧接口（兼容层，供 Android DeviceRepository 和脚本使用）----
+		{Method: http.MethodGet, Path: "/ping", Tag: tagLegacy, Summary: "返回 pong", ContentType: "text/plain", handler: handlePing},
+		{Method: http.MethodPost, Path: "/sleep", Policy: "sleep", Toggle: true, Tag: tagLegacy, LegacyGET: true, Summary: "切换睡眠倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleSleep},
+		{Method: http.MethodPost, Path: "/shutdown", Policy: "shutdown", Toggle: true, Tag: tagLegacy, LegacyGET: true, Summary: "切换关机倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleShutdown},
+		{Method: http.MethodPost, Path: "/restart", Policy: "restart", Toggle: true, Tag: tagLegacy, Summary: "切换重启倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleRestart},
+		{Method: http.MethodPost, Path: "/hibernate", Policy: "hibernate", Toggle: true, Tag: tagLegacy, Summary: "切换休眠倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleHibernate},
+		{Method: http.MethodPost, Path: "/lock", Policy: "lock", Toggle: true, Tag: tagLegacy, Summary: "切换锁定倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleLock},
+		{Method: http.MethodPost, Path: "/signout", Policy: "signout", Toggle: true, Tag: tagLegacy, Summary: "切换注销倒计时", Permission: auth.ScopePower, Params: countdownParams, ContentType: "application/json", handler: s.handleSignOut},
+		{Method: http.MethodPost, Path: "/abort", Tag: tagLegacy, Summary: "取消倒计时和系统中已计划的关机 (shutdown /a)", Permission: auth.ScopePower, ContentType: "application/json", handler: s.handleAbort},
+		{Method: http.MethodPost, Path: "/clip", Policy: "clipboard", Tag: tagLegacy, Summary: "写入 (POST) / 读取 (GET) 剪贴板文本", Permission: auth.ScopeClipboardWrite, ContentType: "text/plain", RequestContentType: "text/plain", handler: s.handleClip},
+		{Method: http.MethodPost, Path: "/clip/", Policy: "clipboard", Tag: tagLegacy, Summary: "同 /clip", Permission: auth.ScopeClipboardWrite, ContentType: "text/plain", RequestContentType: "text/plain", handler: s.handleClip},
+		{Method: http.MethodGet, Path: "/getclip", Policy: "clipboard", Tag: tagLegacy, Summary: "读取剪贴板文本", Permission: auth.ScopeClipboardRead, ContentType: "text/plain", handler: s.handleGetClip},
+		{Method: http.MethodPost, Path: "/monitor", Policy: "display", Tag: tagLegacy, LegacyGET: true, Summary: "切换显示器开关，返回 on/off", Permission: auth.ScopePower, ContentType: "text/plain", handler: s.handleMonitorToggle},
+		{Method: http.MethodPost, Path: "/volume/up", Policy: "volume", Tag: tagLegacy, LegacyGET: true, Summary: "音量加", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleVolumeUp},
+		{Method: http.MethodPost, Path: "/volume/down", Policy: "volume", Tag: tagLegacy, LegacyGET: true, Summary: "音量减", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleVolumeDown},
+		{Method: http.MethodGet, Path: "/volume/info", Tag: tagLegacy, Summary: "查询音量", Permission: auth.ScopeMedia, ContentType: "application/json", handler: s.handleVolumeInfo},
+		{Method: http.MethodPost, Path: "/volume/mute", Policy: "volume", Tag: tagLegacy, LegacyGET: true, Summary: "切换静音", Permission: auth.ScopeMedia, ContentType: "application/json", handler: s.handleMute},
+		{Method: http.MethodPost, Path: "/volume/set", Policy: "volume", Tag: tagLegacy, LegacyGET: true, Summary: "设置音量 (?val=0-100)", Permission: auth.ScopeMedia, ContentType: "application/json",
+			Params: []routeParam{{Name: "val", In: "query", Type: "integer", Required: true}}, handler: s.handleVolumeSet},
+		{Method: http.MethodPost, Path: "/media/playpause", Policy: "media", Tag: tagLegacy, LegacyGET: true, Summary: "播放/暂停", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleMediaPlayPause},
+		{Method: http.MethodPost, Path: "/media/play", Policy: "media", Tag: tagLegacy, LegacyGET: true, Summary: "同 /media/playpause", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleMediaPlayPause},
+		{Method: http.MethodGet, Path: "/media/info", Tag: tagLegacy, Summary: "查询媒体信息", Permission: auth.ScopeMedia, ContentType: "application/json", handler: handleMediaInfo},
+		{Method: http.MethodPost, Path: "/media/next", Policy: "media", Tag: tagLegacy, LegacyGET: true, Summary: "下一首", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleMediaNext},
+		{Method: http.MethodPost, Path: "/media/prev", Policy: "media", Tag: tagLegacy, LegacyGET: true, Summary: "上一首", Permission: auth.ScopeMedia, ContentType: "text/plain", handler: s.handleMediaPrev},
+		{Method: http.MethodPost, Path: "/text", Policy: "input", Tag: tagLegacy, Summary: "写入剪贴板并粘贴 (表单字段 text 或请求体)", Permission: auth.ScopeInput, ContentType: "text/plain", RequestContentType: "text/plain", handler: s.handleText},
+		{Method: http.MethodPost, Path: "/paste", Policy: "input", Tag: tagLegacy, LegacyGET: true, Summary: "模拟粘贴", Permission: auth.ScopeInput, ContentType: "text/plain", handler: s.handlePaste},
+		{Method: http.MethodPost, Path: "/upload/image", Policy: "clipboard", Tag: tagLegacy, Summary: "上传图片到剪贴板 (multipart 字段 image)", Permission: auth.ScopeClipboardWrite, ContentType: "text/plain", handler: s.handleUploadImage},
+		{Method: http.MethodPost, Path: "/test_bark", Tag: tagLegacy, Summary: "发送 Bark 测试通知", Permission: auth.ScopeAdminSettings, ContentType: "application/json", handler: handleTestBark},
+	}
+}
+
+// registerRoutes 将路由表注册到 mux：同一路径的 /api/v1 接口按方法合并分派，旧路由除 LegacyGET 外不限制方法。
+// 所有路由都按声明的 Permission 检查访问令牌。
+// 声明了 Policy 的路由在权限检查通过后调用 checkPolicy，Toggle 路由调用 checkTogglePolicy。
+func registerRoutes(mux *http.ServeMux, routes []route, checkPolicy, checkTogglePolicy func(http.ResponseWriter, *http.Request, string) error) {
+	apiByPath := make(map[string]apiMethods)
+	var apiPaths []string
+	registered := make(map[string]bool)
+	for _, rt := range routes {
+		var policy policyFunc
+		if rt.Policy != "" {
+			name, check := rt.Policy, checkPolicy
+			if rt.Toggle {
+				check = checkTogglePolicy
+			}
+			policy = func(w http.ResponseWriter, r *http.Request) error { return check(w, r, policyName(r, name)) }
+		}
+		if rt.api != nil {
+			if _, ok := apiByPath[rt.Path]; !ok {
+				apiByPath[rt.Path] = apiMethods{}
+				apiPaths = append(apiPaths, rt.Path)
+			}
+			apiByPath[rt.Path][rt.Method] = apiEndpoint{permission: rt.Permission, policy: policy, fn: rt.api}
+			continue
+		}
+		if registered[rt.Path] {
+			continue
+		}
+		registered[rt.Path] = true
+		mux.HandleFunc(rt.Path, withPermission(rt, policy))
+	}
+	for _, path := range apiPaths {
+		mux.Handle(path, apiByPath[path])
+	}
+	mux.HandleFunc("/api/", apiNotFound)
+}
+
+// ---- 接口使用的请求/响应类型（除 handler 外也用于生成文档）----
+
+type pingResponse struct {
+	Pong bool     `json:"pong"`
+	TLS  *tlsInfo `json:"tls,omitempty"` // 已启用 HTTPS 时返回端口和证书指纹
+}
+
+// tlsInfo 运行中的 HTTPS 服务信息，客户端应固定 (pin) Fingerprint。
+type tlsInfo struct {
+	Port        string `json:"port"`
+	Fingerprint string `json:"fingerprint"` // 证书 SHA-256 指纹，冒号分隔的大写十六进制
+}
+
+type volumeRequest struct {
+	Volume *int `json:"volume"`
+}
+
+type barkTestRequest struct {
+	Sound string `json:"sound,omitempty"`
+}
+
+// imageUpload 仅用于文档：multipart 表单中的图片字段。
+type imageUpload struct {
+	Image []byte `json:"image" format:"binary"`
+}
+
+type simulatorJournal struct {
+	Backend string                  `json:"backend"`
+	State   platform.SimulatorState `json:"state"`
+	Entries []platform.JournalEntry `json:"entries"`
+}
@@ -0,0 +1,139 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"bealinkserver/bark"
+	"bealinkserver/platform"
+	"bealinkserver/power"
+)
+
+// maxScheduleReminderMin 定时电源操作提前提醒的最大分钟数。
+const maxScheduleReminderMin = 24 * 60
+
+// scheduleCreateRequest 添加定时电源操作的请求体，at、delay_minutes、daily 三者必须且只能提供一个：
+// at 为 RFC 3339 时间或 HH:MM（今天或明天的该时间），delay_minutes 为从现在起的分钟数，daily 为每天执行的 HH:MM。
+type scheduleCreateRequest struct {
+	At           string `json:"at,omitempty"`
+	DelayMinutes int    `json:"delay_minutes,omitempty"`
+	Daily        string `json:"daily,omitempty"`
+}
+
+func scheduleReminderLead() time.Duration {
+	return time.Duration(bark.GetConfig().ScheduleReminderMin) * time.Minute
+}
+
+// remindSchedule 在定时电源操作执行前发送 Bark 提醒。
+func remindSchedule(sc power.Schedule) {
+	label := policyDescription(sc.Action)
+	bark.NotifyMessage("schedule", fmt.Sprintf("Bealink 定时%s提醒", label),
+		fmt.Sprintf("电脑将在 %s 开始%s倒计时（约 %.0f 分钟后）。\n如需取消，请在控制台删除定时任务 %s。", sc.At.Format("15:04"), label, time.Until(sc.At).Minutes(), sc.ID))
+}
+
+// notifyScheduleFailed 定时电源操作到点未能启动时发送 Bark 通知。
+func notifyScheduleFailed(sc power.Schedule) {
+	label := policyDescription(sc.Action)
+	bark.NotifyMessage("schedule_failed", fmt.Sprintf("Bealink 定时%s未执行", label),
+		fmt.Sprintf("定时任务 %s 在 %s 到点，但未能开始%s倒计时: %s", sc.ID, sc.At.Format("15:04"), label, sc.LastError))
+}
+
+// checkSchedulePolicy 在定时电源操作到点时按当前策略再检查一次：添加后策略可能已改为禁止或需确认。
+// deny 直接拒绝；confirm 在本机弹出确认框，无人在电脑前时超时拒绝。
+func (s *Server) checkSchedulePolicy(sc power.Schedule) error {
+	cfg := bark.GetConfig()
+	desc := policyDescription(sc.Action)
+	switch cfg.ActionPolicies[sc.Action] {
+	case PolicyDeny:
+		log.Printf("[Policy] 拒绝定时%s (ID: %s)：策略禁止远程执行", desc, sc.ID)
+		return fmt.Errorf("策略禁止远程%s", desc)
+	case PolicyConfirm:
+	default:
+		return nil
+	}
+	if !s.confirms.acquire(sc.Action) {
+		return fmt.Errorf("已有%s请求在等待电脑前的人确认", desc)
+	}
+	defer s.confirms.release(sc.Action)
+
+	timeout := time.Duration(cfg.ConfirmTimeoutSec) * time.Second
+	log.Printf("[Policy] 等待本机确认定时%s (ID: %s)，最长 %s", desc, sc.ID, timeout)
+	message := fmt.Sprintf("定时任务 %s 请求%s。\n是否允许？%d 秒内未选择将自动拒绝。", sc.ID, desc, cfg.ConfirmTimeoutSec)
+	result, err := s.backend.Confirmer.Confirm(context.Background(), "Bealink 定时操作确认", message, timeout)
+	if err != nil {
+		return fmt.Errorf("无法在电脑上显示确认框: %w", err)
+	}
+	log.Printf("[Policy] 定时%s (ID: %s) 确认结果: %s", desc, sc.ID, result)
+	switch result {
+	case platform.ConfirmApproved:
+		return nil
+	case platform.ConfirmDenied:
+		return fmt.Errorf("电脑前的人拒绝了定时%s", desc)
+	default:
+		return fmt.Errorf("定时%s在 %d 秒内未获确认", desc, cfg.ConfirmTimeoutSec)
+	}
+}
+
+// parseScheduleAt 解析 at：RFC 3339 时间，或 HH:MM 表示下一个该时刻。
+func parseScheduleAt(v string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, v); err == nil {
+		return t, nil
+	}
+	return power.NextDaily(v, time.Now())
+}
+
+func (s *Server) apiScheduleList(r *http.Request) (interface{}, error) {
+	return s.schedules.List(), nil
+}
+
+// apiScheduleCreate 添加定时电源操作 (?action=，取值同 power.Actions)。
+func (s *Server) apiScheduleCreate(r *http.Request) (interface{}, error) {
+	action := r.URL.Query().Get("action")
+	if action == "" {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "缺少 action 参数")
+	}
+	var req scheduleCreateRequest
+	if err := decodeJSONBody(r, &req); err != nil {
+		return nil, err
+	}
+	given := 0
+	for _, set := range []bool{req.At != "", req.DelayMinutes != 0, req.Daily != ""} {
+		if set {
+			given++
+		}
+	}
+	if given != 1 {
+		return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "at、delay_minutes、daily 必须且只能提供一个")
+	}
+
+	var at time.Time
+	switch {
+	case req.At != "":
+		t, err := parseScheduleAt(req.At)
+		if err != nil {
+			return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "at 必须是 RFC 3339 时间或 HH:MM: %s", req.At)
+		}
+		at = t
+	case req.DelayMinutes != 0:
+		if req.DelayMinutes < 1 || req.DelayMinutes > 7*24*60 {
+			return nil, apiErrorf(http.StatusBadRequest, ErrCodeInvalidParameter, "delay_minutes 必须是 1-%d 之间的整数", 7*24*60)
+		}
+		at = time.Now().Add(time.Duration(req.DelayMinutes) * time.Minute)
+	}
+	sc, err := s.schedules.Add(action, at, req.Daily, "API 请求 "+r.RemoteAddr)
+	if err != nil {
+		return nil, powerAPIError(err)
+	}
+	return sc, nil
+}
+
+func (s *Server) apiScheduleCancel(r *http.Request) (interface{}, error) {
+	sc, err := s.schedules.Cancel(r.PathValue("id"), "API 请求 "+r.RemoteAddr)
+	if err != nil {
+		return nil, powerAPIError(err)
+	}
+	return sc, nil
+}
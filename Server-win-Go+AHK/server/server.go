@@ -6,16 +6,25 @@ package server
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/tls"
 	"fmt"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
+	"bealinkserver/audit"
+	"bealinkserver/auth"
+	"bealinkserver/bark"
 	"bealinkserver/logging" // 假设这是你项目中的包
+	"bealinkserver/netacl"
+	"bealinkserver/platform"
+	"bealinkserver/power"
 
 	"github.com/grandcat/zeroconf"
 )
@@ -27,13 +36,60 @@ const (
 )
 
 var (
-	httpServer *http.Server
-	mDNSServer *zeroconf.Server
-
 	GlobalActualListenAddr string
 	GlobalActualPort       string
+	GlobalTLSPort          string // HTTPS 实际监听端口，未启用时为空
 )
 
+// Server 持有 HTTP 服务的全部依赖。系统能力通过 platform.Backend 注入，
+// 因此 server 包本身不依赖任何特定平台。
+type Server struct {
+	backend *platform.Backend
+	logHub  *logging.Hub
+	volume  *volumeCache
+	power   *power.Manager
+	// display 显示器状态变化的订阅者，见 handleDisplayEvents。
+	display *displayHub
+	// schedules 定时电源操作，Start 时从文件加载。
+	schedules *power.Scheduler
+	pairing   *auth.Pairing
+	audit     *audit.Log
+	// confirms 正在等待本机确认的操作，见 checkPolicy。
+	confirms confirmGate
+	tls      *TLSOptions // Start 后有效，未启用 HTTPS 时为 nil
+	// csrfToken 每次启动随机生成，通过 Cookie 下发给内置页面，见 csrfProtect。
+	csrfToken string
+
+	httpServer *http.Server
+	mDNSServer *zeroconf.Server
+	done       chan struct{}
+}
+
+// New 创建一个使用指定平台后端的 Server。
+func New(backend *platform.Backend, logHub *logging.Hub) *Server {
+	configDir := filepath.Dir(bark.GetConfigFilePath())
+	manager := power.NewManager(backend.Power, backend.Countdown, defaultCountdownSeconds)
+	display := newDisplayHub()
+	backend.Display.OnMonitorStateChange(display.publish)
+	s := &Server{
+		backend: backend,
+		logHub:  logHub,
+		volume:  newVolumeCache(backend.Audio),
+		power:   manager,
+		display: display,
+		pairing: auth.NewPairing(pairNotifier(backend.Notifier)),
+		audit:   audit.New(filepath.Join(configDir, audit.FileName)),
+		done:    make(chan struct{}),
+
+		csrfToken: rand.Text(),
+	}
+	s.schedules = power.NewScheduler(filepath.Join(configDir, power.ScheduleFileName), manager, scheduleReminderLead, remindSchedule, notifyScheduleFailed, s.checkSchedulePolicy)
+	return s
+}
+
+// Done 在 HTTP 服务停止（正常关闭或意外退出）后关闭。
+func (s *Server) Done() <-chan struct{} { return s.done }
+
 // getLocalIP 仍然保留，以防项目其他地方用到，但在此次日志优化中，其直接调用被 getLocalIPv4s 替代。
 func getLocalIP() string {
 	addrs, err := net.InterfaceAddrs()
@@ -70,60 +126,129 @@ func getLocalIPv4s() []string {
 	return ips
 }
 
-func Start(ctx context.Context, preferredPorts []string, logHub *logging.Hub) (actualListenAddr string, usedAlternativePort bool, err error) {
-	log.Println("核心服务 (HTTP, mDNS) 启动中...")
-	// initTemplates() // 不再在此处调用，已移至 handlers.go 的包级别 init() 函数
+// TLSOptions HTTPS 监听配置，传给 Start 时启用 HTTPS。
+type TLSOptions struct {
+	Port        string // 监听端口，例如 ":8443"
+	Certificate tls.Certificate
+	Fingerprint string // 证书 SHA-256 指纹，发布到 mDNS TXT 记录和设置页面供客户端固定
+	DisableHTTP bool   // 为 true 时不再监听明文 HTTP
+}
 
-	var listener net.Listener
-	var currentListenPort string // 当前尝试监听的端口字符串，可能包含 ":" 前缀
+// ListenOptions HTTP 监听配置。
+type ListenOptions struct {
+	Addresses []string // 监听地址（IP 或网卡名称），为空时监听所有网卡
+	Ports     []string // HTTP 依次尝试的端口，例如 ":8088"
+}
 
+// isAddrInUse 判断监听错误是否为端口已被占用。
+func isAddrInUse(err error) bool {
+	if opErr, ok := err.(*net.OpError); ok {
+		if sysErr, ok := opErr.Err.(*os.SyscallError); ok {
+			return strings.Contains(sysErr.Error(), "address already in use") || strings.Contains(sysErr.Error(), "Only one usage of each socket address")
+		}
+	}
+	return false
+}
+
+// listenAll 在 hosts 的每个地址上监听 portSpec，任一地址失败时关闭已打开的监听并返回错误。
+func listenAll(hosts []string, portSpec string) ([]net.Listener, error) {
+	port := strings.TrimPrefix(portSpec, ":")
+	var listeners []net.Listener
+	for _, host := range hosts {
+		l, err := net.Listen("tcp", net.JoinHostPort(host, port))
+		if err != nil {
+			closeListeners(listeners)
+			return nil, err
+		}
+		listeners = append(listeners, l)
+	}
+	return listeners, nil
+}
+
+func closeListeners(listeners []net.Listener) {
+	for _, l := range listeners {
+		l.Close()
+	}
+}
+
+// listenFirstAvailable 依次尝试 preferredPorts，返回第一个在全部 hosts 上监听成功的端口的监听器。
+// 端口被占用时尝试下一个，其余错误直接返回。
+func listenFirstAvailable(hosts []string, preferredPorts []string) (listeners []net.Listener, usedAlternativePort bool, err error) {
 	for i, portSpec := range preferredPorts {
-		currentListenPort = portSpec // 例如 ":8080" 或 "8080"
-		log.Printf("尝试在端口 %s 上启动HTTP服务...", strings.TrimPrefix(currentListenPort, ":"))
-		tempListener, listenErr := net.Listen("tcp", currentListenPort)
+		currentListenPort := strings.TrimPrefix(portSpec, ":") // 配置中可以是 ":8080" 或 "8080"
+		log.Printf("尝试在端口 %s 上启动HTTP服务...", currentListenPort)
+		tempListeners, listenErr := listenAll(hosts, portSpec)
 		if listenErr == nil {
-			listener = tempListener
-			if i > 0 {
-				usedAlternativePort = true
-			}
 			// 根据要求修改日志格式
-			log.Printf("HTTP 服务已在端口 %s 上成功启动监听。", strings.TrimPrefix(currentListenPort, ":"))
-			break
-		}
-		if opErr, ok := listenErr.(*net.OpError); ok {
-			if sysErr, ok := opErr.Err.(*os.SyscallError); ok {
-				if strings.Contains(sysErr.Error(), "address already in use") || strings.Contains(sysErr.Error(), "Only one usage of each socket address") {
-					log.Printf("警告: 端口 %s 已被占用。", strings.TrimPrefix(currentListenPort, ":"))
-					if i == len(preferredPorts)-1 {
-						return "", false, fmt.Errorf("所有尝试的端口 (%v) 都已被占用: %w", preferredPorts, listenErr)
-					}
-					continue
-				}
+			log.Printf("HTTP 服务已在端口 %s 上成功启动监听。", currentListenPort)
+			return tempListeners, i > 0, nil
+		}
+		if isAddrInUse(listenErr) {
+			log.Printf("警告: 端口 %s 已被占用。", currentListenPort)
+			if i == len(preferredPorts)-1 {
+				return nil, false, fmt.Errorf("所有尝试的端口 (%v) 都已被占用: %w", preferredPorts, listenErr)
 			}
+			continue
 		}
-		return "", false, fmt.Errorf("HTTP服务在端口 %s 上监听失败: %w", strings.TrimPrefix(currentListenPort, ":"), listenErr)
+		return nil, false, fmt.Errorf("HTTP服务在端口 %s 上监听失败: %w", currentListenPort, listenErr)
 	}
+	return nil, false, fmt.Errorf("未能成功在任何指定端口上监听: %v", preferredPorts)
+}
 
-	if listener == nil {
-		return "", false, fmt.Errorf("未能成功在任何指定端口上监听: %v", preferredPorts)
+// listenerPort 返回监听器的端口号字符串。
+func listenerPort(l net.Listener) string {
+	_, port, err := net.SplitHostPort(l.Addr().String())
+	if err != nil {
+		log.Printf("警告: 从监听地址 %s 解析端口失败: %v", l.Addr().String(), err)
+		return "未知"
 	}
+	return port
+}
 
-	GlobalActualListenAddr = listener.Addr().String() // 例如 "[::]:8088"
-	_, portStr, err := net.SplitHostPort(listener.Addr().String())
+// Start 启动 HTTP 服务和 mDNS 注册。tlsOpts 非 nil 时同时在 tlsOpts.Port 上提供 HTTPS，
+// 并可关闭明文 HTTP；返回的地址优先为 HTTP 监听地址。
+func (s *Server) Start(ctx context.Context, listen ListenOptions, tlsOpts *TLSOptions) (actualListenAddr string, usedAlternativePort bool, err error) {
+	log.Printf("核心服务 (HTTP, mDNS) 启动中... (平台后端: %s)", s.backend.Name)
+	// initTemplates() // 不再在此处调用，已移至 handlers.go 的包级别 init() 函数
+
+	hosts, err := netacl.ResolveListenHosts(listen.Addresses)
 	if err != nil {
-		log.Printf("警告: 从监听地址 %s 解析端口失败: %v", listener.Addr().String(), err)
-		// 回退逻辑，尝试从 currentListenPort 获取端口号
-		if strings.HasPrefix(currentListenPort, ":") {
-			GlobalActualPort = strings.TrimPrefix(currentListenPort, ":")
-		} else {
-			GlobalActualPort = currentListenPort // 如果 currentListenPort 就是 "8080"
-		}
-		if GlobalActualPort == "" { // 最后的保障
-			GlobalActualPort = "未知"
+		return "", false, err
+	}
+	if hosts[0] != "" {
+		log.Printf("仅在以下地址上监听: %s", strings.Join(hosts, ", "))
+	}
+
+	var httpListeners, tlsListeners []net.Listener
+	if tlsOpts == nil || !tlsOpts.DisableHTTP {
+		httpListeners, usedAlternativePort, err = listenFirstAvailable(hosts, listen.Ports)
+		if err != nil {
+			return "", false, err
 		}
 	} else {
-		GlobalActualPort = portStr // 例如 "8088"
+		log.Println("已关闭明文 HTTP，仅提供 HTTPS 服务。")
 	}
+	if tlsOpts != nil {
+		tlsListeners, err = listenAll(hosts, tlsOpts.Port)
+		if err != nil {
+			if httpListeners == nil {
+				return "", false, fmt.Errorf("HTTPS 服务在端口 %s 上监听失败: %w", strings.TrimPrefix(tlsOpts.Port, ":"), err)
+			}
+			log.Printf("警告: HTTPS 服务在端口 %s 上监听失败，仅提供 HTTP 服务: %v", strings.TrimPrefix(tlsOpts.Port, ":"), err)
+			tlsOpts = nil
+		} else {
+			GlobalTLSPort = listenerPort(tlsListeners[0])
+			log.Printf("HTTPS 服务已在端口 %s 上成功启动监听，证书 SHA-256 指纹: %s", GlobalTLSPort, tlsOpts.Fingerprint)
+		}
+	}
+	s.tls = tlsOpts
+
+	primary := httpListeners
+	if primary == nil {
+		primary = tlsListeners
+	}
+	GlobalActualListenAddr = primary[0].Addr().String() // 例如 "[::]:8088"
+	GlobalActualPort = listenerPort(primary[0])         // 例如 "8088"
 
 	portInt, convErr := strconv.Atoi(GlobalActualPort)
 	if convErr != nil {
@@ -139,7 +264,7 @@ func Start(ctx context.Context, preferredPorts []string, logHub *logging.Hub) (a
 
 	if portInt > 0 { // 仅当端口有效时注册mDNS
 		var mDNSErr error
-		mDNSServer, mDNSErr = zeroconf.Register(hostname, mDNSServiceType, mDNSDomain, portInt, []string{"path=/"}, nil)
+		s.mDNSServer, mDNSErr = zeroconf.Register(hostname, mDNSServiceType, mDNSDomain, portInt, s.mDNSText(httpListeners != nil), netacl.Interfaces(hosts))
 		if mDNSErr != nil {
 			log.Printf("警告: mDNS 服务注册失败: %v", mDNSErr)
 		} else {
@@ -149,93 +274,96 @@ func Start(ctx context.Context, preferredPorts []string, logHub *logging.Hub) (a
 		log.Printf("警告: 端口号无效 (%s)，跳过mDNS服务注册。", GlobalActualPort)
 	}
 
-	mux := http.NewServeMux()
-	mux.HandleFunc("/", handleRoot)
-	mux.HandleFunc("/ping", handlePing)
-	mux.HandleFunc("/sleep", handleSleep)
-	mux.HandleFunc("/shutdown", handleShutdown)
-	mux.HandleFunc("/clip", handleClip) // 兼容 /clip 和 /clip/
-	mux.HandleFunc("/clip/", handleClip)
-	mux.HandleFunc("/getclip", handleGetClip)
-	mux.HandleFunc("/monitor", handleMonitorToggle)
-	mux.HandleFunc("/volume/up", handleVolumeUp)
-	mux.HandleFunc("/volume/down", handleVolumeDown)
-	mux.HandleFunc("/volume/info", handleVolumeInfo)
-	mux.HandleFunc("/media/playpause", handleMediaPlayPause)
-	mux.HandleFunc("/media/play", handleMediaPlayPause)
-	mux.HandleFunc("/media/info", handleMediaInfo)
-	mux.HandleFunc("/volume/set", handleVolumeSet)
-	mux.HandleFunc("/media/next", handleMediaNext)
-	mux.HandleFunc("/media/prev", handleMediaPrev)
-	mux.HandleFunc("/text", handleText)
-	mux.HandleFunc("/paste", handlePaste)
-	mux.HandleFunc("/upload/image", handleUploadImage)
-	mux.HandleFunc("/debug", handleDebugPage)
-	mux.HandleFunc("/ws/logs", func(w http.ResponseWriter, r *http.Request) { serveWs(logHub, w, r) })
-	mux.HandleFunc("/setting", func(w http.ResponseWriter, r *http.Request) {
-		if r.Method == http.MethodGet {
-			handleSettingsPage(w, r)
-		} else if r.Method == http.MethodPost {
-			handleSaveSettings(w, r)
-		} else {
-			http.Error(w, "方法不允许", http.StatusMethodNotAllowed)
+	s.httpServer = &http.Server{Handler: s.Handler(), ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+	if tlsOpts != nil {
+		// 同一个 http.Server 同时 Serve 明文监听，需显式声明 h2，否则先启动的 Serve 会跳过 HTTP/2 初始化
+		s.httpServer.TLSConfig = &tls.Config{
+			Certificates: []tls.Certificate{tlsOpts.Certificate},
+			MinVersion:   tls.VersionTLS12,
+			NextProtos:   []string{"h2", "http/1.1"},
 		}
-	})
-	mux.HandleFunc("/test_bark", handleTestBark)
-	mux.HandleFunc("/favicon.ico", handleFavicon)
-	mux.HandleFunc("/icon.ico", handleIconICO)
-
-	httpServer = &http.Server{Handler: mux, ReadTimeout: 10 * time.Second, WriteTimeout: 10 * time.Second}
+	}
 
 	// 根据要求修改日志格式
 	log.Printf("HTTP 服务实际监听于端口: %s", GlobalActualPort)
 
-	// 列出本机可访问的 IPv4 地址
+	// 列出本机可访问的地址：监听所有网卡时为全部 IPv4 地址，否则为配置的监听地址
 	localIPv4s := getLocalIPv4s()
+	if hosts[0] != "" {
+		localIPv4s = nil
+		for _, h := range hosts {
+			if strings.Contains(h, ":") {
+				h = "[" + h + "]"
+			}
+			localIPv4s = append(localIPv4s, h)
+		}
+	}
 	if len(localIPv4s) > 0 {
-		log.Println("本机可访问的 IPv4 地址列表:")
+		log.Println("本机可访问的地址列表:")
 		for _, ip := range localIPv4s {
-			log.Printf("  - http://%s:%s", ip, GlobalActualPort)
+			if httpListeners != nil {
+				log.Printf("  - http://%s:%s", ip, GlobalActualPort)
+			}
+			if tlsListeners != nil {
+				log.Printf("  - https://%s:%s", ip, GlobalTLSPort)
+			}
 		}
 	} else {
 		log.Println("未能获取到本机可访问的 IPv4 地址。")
 	}
 
-	if mDNSServer != nil { // 保持 mDNS 可访问地址的日志
-		log.Printf("mDNS 可访问地址: http://%s.%s:%s", hostname, mDNSDomain, GlobalActualPort)
+	if s.mDNSServer != nil { // 保持 mDNS 可访问地址的日志
+		scheme := "http"
+		if httpListeners == nil {
+			scheme = "https"
+		}
+		log.Printf("mDNS 可访问地址: %s://%s.%s:%s", scheme, hostname, mDNSDomain, GlobalActualPort)
 	}
 
-	httpServerErrChan := make(chan error, 1)
-	go func() {
+	httpServerErrChan := make(chan error, len(httpListeners)+len(tlsListeners))
+	serve := func(name string, addr string, serveFn func() error) {
 		// 根据要求修改日志格式
-		log.Printf("HTTP 服务开始在端口 %s 上提供服务...", GlobalActualPort)
-		errServe := httpServer.Serve(listener)
+		log.Printf("%s 服务开始在 %s 上提供服务...", name, addr)
+		errServe := serveFn()
 		if errServe != nil && errServe != http.ErrServerClosed {
-			log.Printf("HTTP 服务器监听 goroutine 意外结束: %v", errServe)
+			log.Printf("%s 服务器监听 goroutine 意外结束: %v", name, errServe)
 		} else {
-			log.Println("HTTP 服务器监听 goroutine 正常结束。")
+			log.Printf("%s 服务器监听 goroutine 正常结束。", name)
 		}
 		httpServerErrChan <- errServe
-		close(httpServerErrChan)
-	}()
+	}
+	for _, l := range httpListeners {
+		go serve("HTTP", l.Addr().String(), func() error { return s.httpServer.Serve(l) })
+	}
+	for _, l := range tlsListeners {
+		go serve("HTTPS", l.Addr().String(), func() error { return s.httpServer.ServeTLS(l, "", "") })
+	}
 
+	if err := s.schedules.Load(); err != nil {
+		log.Printf("警告: 加载定时电源操作失败: %v", err)
+	}
+	go expireGuests(ctx)
 	go func() {
+		defer close(s.done)
 		select {
 		case errFromServe := <-httpServerErrChan:
 			if errFromServe != nil && errFromServe != http.ErrServerClosed {
 				log.Printf("!!! HTTP 服务器错误，服务可能已停止: %v", errFromServe)
 			}
+			s.httpServer.Close() // 任一监听意外结束时同时停止另一个，避免服务只剩一半
 		case <-ctx.Done():
 			log.Println("收到退出信号，开始关闭HTTP和mDNS服务...")
+			s.schedules.Stop()
+			s.power.CancelAll("服务退出")
 			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 			defer cancel()
-			if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			if err := s.httpServer.Shutdown(shutdownCtx); err != nil {
 				log.Printf("HTTP 服务器关闭错误: %v", err)
 			} else {
 				log.Println("HTTP 服务器已优雅关闭。")
 			}
-			if mDNSServer != nil {
-				mDNSServer.Shutdown()
+			if s.mDNSServer != nil {
+				s.mDNSServer.Shutdown()
 				log.Println("mDNS 服务已注销。")
 			}
 			log.Println("核心服务 (HTTP, mDNS) 已停止。")
@@ -243,3 +371,42 @@ func Start(ctx context.Context, preferredPorts []string, logHub *logging.Hub) (a
 	}()
 	return GlobalActualListenAddr, usedAlternativePort, nil
 }
+
+// mDNSText 返回 mDNS TXT 记录。启用 HTTPS 时附带 HTTPS 端口和证书指纹，供客户端固定证书；
+// httpAvailable 为 false 时服务端口本身就是 HTTPS。
+func (s *Server) mDNSText(httpAvailable bool) []string {
+	txt := []string{"path=/"}
+	if s.tls == nil {
+		return txt
+	}
+	scheme := "https"
+	if httpAvailable {
+		scheme = "http"
+	}
+	return append(txt, "scheme="+scheme, "https_port="+GlobalTLSPort, "sha256="+s.tls.Fingerprint)
+}
+
+// Handler 构建并返回包含全部路由的 HTTP 处理器。
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	routes := s.routes()
+	registerRoutes(mux, routes, s.checkPolicy, s.checkTogglePolicy)
+
+	// 通过 GET 执行操作的路由和剪贴板读取也要记入审计日志，剪贴板读取的响应只记录大小；
+	// 请求体为纯文本的路由不按表单解析
+	auditGET := make(map[string]bool)
+	textBody := make(map[string]bool)
+	clipRead := make(map[string]bool)
+	for _, rt := range routes {
+		if rt.LegacyGET || rt.Tag == tagLinks && rt.handler != nil {
+			auditGET[rt.Path] = true
+		}
+		if rt.Policy == "clipboard" {
+			auditGET[rt.Path], clipRead[rt.Path] = true, true
+		}
+		if rt.RequestContentType == "text/plain" {
+			textBody[rt.Path] = true
+		}
+	}
+	return s.accessControl(s.csrfProtect(s.auditRequests(mux, auditGET, textBody, clipRead)))
+}
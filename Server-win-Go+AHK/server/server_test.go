@@ -0,0 +1,170 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"bealinkserver/auth"
+	"bealinkserver/bark"
+	"bealinkserver/logging"
+	"bealinkserver/platform"
+)
+
+// TestMain 将用户配置目录指向临时目录，避免测试读写真实的 bealink_config.json、审计日志等文件。
+func TestMain(m *testing.M) {
+	dir, err := os.MkdirTemp("", "bealink-server-test")
+	if err != nil {
+		panic(err)
+	}
+	os.Setenv("XDG_CONFIG_HOME", dir)
+	os.Setenv("APPDATA", dir)
+	os.Setenv("HOME", dir)
+	bark.InitConfig()
+	code := m.Run()
+	os.RemoveAll(dir)
+	os.Exit(code)
+}
+
+// newTestServer 创建使用模拟后端的 Server，返回其处理器和模拟器。
+func newTestServer(t *testing.T) (*Server, http.Handler, *platform.Simulator) {
+	t.Helper()
+	sim := platform.NewSimulator()
+	s := New(sim.Backend(), logging.NewHub())
+	return s, s.Handler(), sim
+}
+
+// localRequest 构造来自本机 (127.0.0.1) 的请求，无需令牌即可访问全部接口。
+func localRequest(method, target string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	r.RemoteAddr = "127.0.0.1:50000"
+	return r
+}
+
+func serve(h http.Handler, r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	return w
+}
+
+// updateConfig 修改全局配置，测试结束时调用 restore 恢复被修改的字段。
+func updateConfig(t *testing.T, fn func(cfg *bark.BarkConfig), restore func(cfg *bark.BarkConfig)) {
+	t.Helper()
+	if err := bark.UpdateConfig(fn); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bark.UpdateConfig(restore) })
+}
+
+func TestSaveSettingsValidationIsBadRequest(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	r := localRequest(http.MethodPost, "/setting", strings.NewReader(`{"tls_port":"99999"}`))
+	r.Header.Set("Content-Type", "application/json")
+	w := serve(h, r)
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("无效设置的状态码 = %d, 期望 400 (响应: %s)", w.Code, w.Body.String())
+	}
+}
+
+func TestTestBarkErrorIsValidJSON(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	w := serve(h, localRequest(http.MethodPost, `/test_bark?sound="\bell`, nil))
+	if w.Code != http.StatusBadRequest {
+		t.Errorf("未配置 Bark 时状态码 = %d, 期望 400", w.Code)
+	}
+	var resp map[string]string
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("响应不是有效的 JSON: %v (%s)", err, w.Body.String())
+	}
+	if resp["status"] != "error" || resp["message"] == "" {
+		t.Errorf("响应 = %v", resp)
+	}
+}
+
+// lanRequest 构造来自局域网 (192.168.1.50) 的请求。
+func lanRequest(method, target string, body io.Reader) *http.Request {
+	r := httptest.NewRequest(method, target, body)
+	r.RemoteAddr = "192.168.1.50:50000"
+	return r
+}
+
+func TestLegacyRoutesFollowRequireAuth(t *testing.T) {
+	_, h, _ := newTestServer(t)
+	updateConfig(t, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, false
+	}, func(cfg *bark.BarkConfig) {
+		cfg.RequireAuth, cfg.LegacyAllowNoToken = true, false
+	})
+	if bark.GetConfig().LegacyAllowNoToken {
+		t.Fatal("legacy_allow_no_token 默认应关闭")
+	}
+
+	for _, target := range []string{"/volume/info", "/getclip", "/api/v1/volume", "/setting"} {
+		if w := serve(h, lanRequest(http.MethodGet, target, nil)); w.Code != http.StatusUnauthorized {
+			t.Errorf("默认配置下 %s 没有令牌的状态码 = %d, 期望 401", target, w.Code)
+		}
+	}
+	if w := serve(h, lanRequest(http.MethodPost, "/sleep", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("默认配置下 /sleep 没有令牌的状态码 = %d, 期望 401", w.Code)
+	}
+	plain, _ := createTestToken(t, h, "安卓", auth.ScopeClipboardRead)
+	if w := serve(h, withToken(lanRequest(http.MethodGet, "/getclip", nil), plain)); w.Code != http.StatusOK {
+		t.Errorf("携带令牌的旧接口请求状态码 = %d, 期望 200", w.Code)
+	}
+
+	bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.LegacyAllowNoToken = true })
+	if w := serve(h, lanRequest(http.MethodGet, "/volume/info", nil)); w.Code != http.StatusOK {
+		t.Errorf("legacy_allow_no_token 开启时旧接口状态码 = %d, 期望 200", w.Code)
+	}
+	if w := serve(h, lanRequest(http.MethodGet, "/api/v1/volume", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("/api/v1 仍应要求令牌，状态码 = %d", w.Code)
+	}
+	if w := serve(h, lanRequest(http.MethodGet, "/setting", nil)); w.Code != http.StatusUnauthorized {
+		t.Errorf("设置页面仍应要求令牌，状态码 = %d", w.Code)
+	}
+	bark.UpdateConfig(func(cfg *bark.BarkConfig) { cfg.RequireAuth, cfg.LegacyAllowNoToken = false, false })
+	if w := serve(h, lanRequest(http.MethodGet, "/volume/info", nil)); w.Code != http.StatusOK {
+		t.Errorf("require_auth 关闭时旧接口状态码 = %d, 期望 200", w.Code)
+	}
+}
+
+func TestToggleCancelledResponseIsJSON(t *testing.T) {
+	s, h, _ := newTestServer(t)
+	t.Cleanup(func() { s.power.CancelAll("测试结束") })
+	var started, cancelled struct {
+		Status string `json:"status"`
+		ID     string `json:"id"`
+	}
+	for _, resp := range []interface{}{&started, &cancelled} {
+		w := serve(h, localRequest(http.MethodPost, "/shutdown", nil))
+		if err := json.Unmarshal(w.Body.Bytes(), resp); err != nil {
+			t.Fatalf("响应不是有效的 JSON: %v (%s)", err, w.Body.String())
+		}
+	}
+	if started.Status != "started" || cancelled.Status != "cancelled" || cancelled.ID != started.ID {
+		t.Errorf("启动 = %+v, 取消 = %+v", started, cancelled)
+	}
+}
+
+func TestDisplayOffUsesSnakeCase(t *testing.T) {
+	_, h, sim := newTestServer(t)
+	for _, want := range []bool{true, false} {
+		w := serve(h, localRequest(http.MethodPost, "/api/v1/display/off", nil))
+		var resp struct {
+			Data map[string]bool `json:"data"`
+		}
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("响应不是有效的 JSON: %v (%s)", err, w.Body.String())
+		}
+		if !resp.Data["monitor_off"] || resp.Data["changed"] != want {
+			t.Errorf("data = %v, 期望 monitor_off=true changed=%v", resp.Data, want)
+		}
+	}
+	if !sim.IsMonitorOff() {
+		t.Error("显示器应已关闭")
+	}
+}
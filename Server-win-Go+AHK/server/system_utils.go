@@ -1,97 +1,78 @@
 package server
 
 import (
-	"fmt"
 	"log"
-	"os"
-	"os/exec"
-	"path/filepath"
-	"strconv"
-	"strings"
 	"sync"
-	"syscall"
 	"time"
+
+	"bealinkserver/platform"
 )
 
 // ==========================================
-// 1. 音量控制 (使用 AHK - 极速、稳定)
+// 1. 音量控制 (带缓存，具体实现由 platform.Audio 提供)
 // ==========================================
 
-// 音量缓存机制：避免频繁调用 AutoHotkey
-var (
-	volumeCache      int
-	volumeCacheMutex sync.RWMutex
-	volumeCacheTime  time.Time
-	volumeCacheTTL   = 200 * time.Millisecond // 200ms 缓存，减少 AHK 调用频率
-)
+// volumeCacheTTL 音量缓存有效期，避免频繁调用底层音频后端
+const volumeCacheTTL = 200 * time.Millisecond
+
+// VolumeInfo 音量状态，/volume/info 的响应体
+type VolumeInfo struct {
+	Volume int  `json:"volume"`
+	Muted  bool `json:"muted"`
+}
+
+// volumeCache 音量缓存机制：缓存最近一次读取/设置的音量和静音状态，减少底层调用频率
+type volumeCache struct {
+	audio platform.Audio
+
+	mu      sync.RWMutex
+	info    VolumeInfo
+	updated time.Time
+}
+
+func newVolumeCache(audio platform.Audio) *volumeCache {
+	return &volumeCache{audio: audio}
+}
 
-// AHK 脚本：获取音量
-const ahkGetVolumeScript = `
-SoundGet, vol, Master
-FileAppend, %vol%, *
-`
-
-// AHK 脚本：设置音量
-const ahkSetVolumeScript = `
-volume := %s
-SoundSet, %%volume%%, Master
-`
-
-// AHK 脚本：静音切换
-const ahkToggleMuteScript = `
-SoundSet, +1, Master, Mute
-`
-
-// GetVolume 调用本地 AutoHotkey.exe 获取当前系统音量（带缓存）
-func GetVolume() int {
+// Get 获取当前系统音量和静音状态（带缓存）
+func (c *volumeCache) Get() VolumeInfo {
 	// 检查缓存是否有效
-	volumeCacheMutex.RLock()
-	if time.Since(volumeCacheTime) < volumeCacheTTL {
-		defer volumeCacheMutex.RUnlock()
-		return volumeCache
+	c.mu.RLock()
+	if time.Since(c.updated) < volumeCacheTTL {
+		defer c.mu.RUnlock()
+		return c.info
 	}
-	volumeCacheMutex.RUnlock()
-
-	// 缓存过期，重新获取（使用 AHK）
-	ahk := getAhkPath()
-	cmd := exec.Command(ahk, "/ErrorStdOut", "*")
-	cmd.Stdin = strings.NewReader(ahkGetVolumeScript)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+	c.mu.RUnlock()
 
-	output, err := cmd.Output()
+	// 缓存过期，重新获取
+	newVol, err := c.audio.GetVolume()
 	if err != nil {
-		log.Printf("[System] 获取音量失败 (请确认 AutoHotkey.exe 存在): %v", err)
+		log.Printf("[System] %v", err)
 		// 返回缓存值（即使过期）而非 0，以稳定性优先
-		volumeCacheMutex.RLock()
-		defer volumeCacheMutex.RUnlock()
-		return volumeCache
+		c.mu.RLock()
+		defer c.mu.RUnlock()
+		return c.info
 	}
-
-	// AHK 输出通常是 "25.000000"，解析为 float 后四舍五入转 int
-	volStr := strings.TrimSpace(string(output))
-	val, err := strconv.ParseFloat(volStr, 64)
+	muted, err := c.audio.IsMuted()
 	if err != nil {
-		log.Printf("[System] 解析音量值失败: %v (原始值: %q)", err, volStr)
-		// 返回缓存值而非 0
-		volumeCacheMutex.RLock()
-		defer volumeCacheMutex.RUnlock()
-		return volumeCache
+		log.Printf("[System] %v", err)
+		// 静音状态读取失败时沿用缓存中的值
+		c.mu.RLock()
+		muted = c.info.Muted
+		c.mu.RUnlock()
 	}
 
-	// 四舍五入到最接近的整数
-	newVol := int(val + 0.5)
-
 	// 更新缓存
-	volumeCacheMutex.Lock()
-	volumeCache = newVol
-	volumeCacheTime = time.Now()
-	volumeCacheMutex.Unlock()
+	c.mu.Lock()
+	c.info = VolumeInfo{Volume: newVol, Muted: muted}
+	c.updated = time.Now()
+	c.mu.Unlock()
 
-	return newVol
+	return VolumeInfo{Volume: newVol, Muted: muted}
 }
 
-// SetVolume 异步调用本地 AutoHotkey.exe 设置音量（立即返回，不阻塞）
-func SetVolume(vol int) {
+// Set 异步设置音量（立即返回，不阻塞）
+func (c *volumeCache) Set(vol int) {
 	if vol < 0 {
 		vol = 0
 	}
@@ -100,36 +81,30 @@ func SetVolume(vol int) {
 	}
 
 	// 更新缓存（立即反映用户操作）
-	volumeCacheMutex.Lock()
-	volumeCache = vol
-	volumeCacheTime = time.Now()
-	volumeCacheMutex.Unlock()
+	c.mu.Lock()
+	c.info.Volume = vol
+	c.updated = time.Now()
+	c.mu.Unlock()
 
-	// 异步执行 AHK 脚本，避免阻塞
+	// 异步执行，避免阻塞
 	go func() {
-		script := fmt.Sprintf(ahkSetVolumeScript, strconv.Itoa(vol))
-		ahk := getAhkPath()
-		cmd := exec.Command(ahk, "/ErrorStdOut", "*")
-		cmd.Stdin = strings.NewReader(script)
-		cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-
-		if err := cmd.Run(); err != nil {
-			log.Printf("[System] 设置音量失败: %v", err)
+		if err := c.audio.SetVolume(vol); err != nil {
+			log.Printf("[System] %v", err)
 		}
 	}()
 }
 
-// ToggleMute 切换静音状态
-func ToggleMute() {
-	ahk := getAhkPath()
-	cmd := exec.Command(ahk, "/ErrorStdOut", "*")
-	cmd.Stdin = strings.NewReader(ahkToggleMuteScript)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
-	if err := cmd.Run(); err != nil {
-		log.Printf("[System] 静音切换失败: %v", err)
-	} else {
-		log.Println("[System] 静音状态已切换")
+// ToggleMute 切换静音状态，并使缓存失效以便下次读取真实状态
+func (c *volumeCache) ToggleMute() error {
+	if err := c.audio.ToggleMute(); err != nil {
+		log.Printf("[System] %v", err)
+		return err
 	}
+	c.mu.Lock()
+	c.updated = time.Time{}
+	c.mu.Unlock()
+	log.Println("[System] 静音状态已切换")
+	return nil
 }
 
 // ==========================================
@@ -147,13 +122,3 @@ type MediaInfo struct {
 func GetMediaInfo() MediaInfo {
 	return MediaInfo{Title: "媒体控制", Artist: "", Status: "Stopped", HasMedia: false}
 }
-
-// getAhkPath 获取程序目录下 ahk/ 文件夹中的 AutoHotkey.exe 路径
-func getAhkPath() string {
-	exePath, err := os.Executable()
-	if err != nil {
-		return "AutoHotkey.exe" // 回退到默认名称
-	}
-	dir := filepath.Dir(exePath)
-	return filepath.Join(dir, "ahk", "AutoHotkey.exe")
-}
@@ -0,0 +1,131 @@
+// Package tlscert 管理 HTTPS 使用的自签名 ECDSA 证书：首次运行时生成并保存到配置目录，之后重复使用。
+// 客户端无法通过 CA 验证自签名证书，应固定 (pin) 证书的 SHA-256 指纹。
+package tlscert
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	certFileName = "bealink_cert.pem"
+	keyFileName  = "bealink_key.pem"
+
+	// certValidity 证书有效期。指纹固定后更换证书需要客户端重新确认，因此有效期取得较长。
+	certValidity = 10 * 365 * 24 * time.Hour
+	// renewBefore 证书剩余有效期不足时重新生成。
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// LoadOrCreate 从 dir 读取证书和私钥，不存在、无法解析或即将过期时重新生成。
+// 返回证书及其 SHA-256 指纹（见 Fingerprint）。
+func LoadOrCreate(dir string) (tls.Certificate, string, error) {
+	certPath := filepath.Join(dir, certFileName)
+	keyPath := filepath.Join(dir, keyFileName)
+
+	cert, err := tls.LoadX509KeyPair(certPath, keyPath)
+	if err == nil {
+		leaf, parseErr := x509.ParseCertificate(cert.Certificate[0])
+		if parseErr == nil && time.Until(leaf.NotAfter) > renewBefore {
+			return cert, Fingerprint(cert.Certificate[0]), nil
+		}
+		log.Printf("提示: TLS 证书 %s 无法解析或即将过期，将重新生成。", certPath)
+	} else if !os.IsNotExist(err) {
+		log.Printf("警告: 读取 TLS 证书失败 (%v)，将重新生成。", err)
+	}
+
+	certPEM, keyPEM, err := generate()
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("创建证书目录 %s 失败: %w", dir, err)
+	}
+	if err := os.WriteFile(keyPath, keyPEM, 0600); err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("保存 TLS 私钥失败: %w", err)
+	}
+	if err := os.WriteFile(certPath, certPEM, 0644); err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("保存 TLS 证书失败: %w", err)
+	}
+	cert, err = tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return tls.Certificate{}, "", fmt.Errorf("加载新生成的 TLS 证书失败: %w", err)
+	}
+	fingerprint := Fingerprint(cert.Certificate[0])
+	log.Printf("信息: 已生成新的自签名 TLS 证书 %s，SHA-256 指纹: %s", certPath, fingerprint)
+	return cert, fingerprint, nil
+}
+
+// Fingerprint 返回 DER 编码证书的 SHA-256 指纹，格式为冒号分隔的大写十六进制 (AB:CD:...)，与浏览器显示一致。
+func Fingerprint(der []byte) string {
+	sum := sha256.Sum256(der)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
+
+// generate 生成 P-256 ECDSA 私钥和自签名证书，证书包含本机主机名、localhost 和当前的本机 IP。
+func generate() (certPEM, keyPEM []byte, err error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成 ECDSA 私钥失败: %w", err)
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, nil, fmt.Errorf("生成证书序列号失败: %w", err)
+	}
+
+	hostname, _ := os.Hostname()
+	dnsNames := []string{"localhost"}
+	if hostname != "" {
+		dnsNames = append(dnsNames, hostname, hostname+".local")
+	}
+	ips := []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	if addrs, err := net.InterfaceAddrs(); err == nil {
+		for _, addr := range addrs {
+			if ipnet, ok := addr.(*net.IPNet); ok && !ipnet.IP.IsLoopback() {
+				ips = append(ips, ipnet.IP)
+			}
+		}
+	}
+
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "Bealink " + hostname, Organization: []string{"Bealink"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(certValidity),
+		KeyUsage:              x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		DNSNames:              dnsNames,
+		IPAddresses:           ips,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("创建自签名证书失败: %w", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("编码 ECDSA 私钥失败: %w", err)
+	}
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
@@ -0,0 +1,84 @@
+package tlscert
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+// fingerprintPattern 发布的指纹格式：32 组冒号分隔的两位大写十六进制。
+var fingerprintPattern = regexp.MustCompile(`^[0-9A-F]{2}(:[0-9A-F]{2}){31}$`)
+
+func TestLoadOrCreateReusesCertificate(t *testing.T) {
+	dir := t.TempDir()
+	cert, fp, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fingerprintPattern.MatchString(fp) {
+		t.Errorf("指纹格式不正确: %s", fp)
+	}
+	sum := sha256.Sum256(cert.Certificate[0])
+	if want := strings.ToUpper(hex.EncodeToString(sum[:])); strings.ReplaceAll(fp, ":", "") != want {
+		t.Errorf("指纹 = %s, 期望证书 DER 的 SHA-256 %s", fp, want)
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := leaf.VerifyHostname("localhost"); err != nil {
+		t.Errorf("证书应包含 localhost: %v", err)
+	}
+	if runtime.GOOS != "windows" {
+		info, err := os.Stat(filepath.Join(dir, keyFileName))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("私钥文件权限 = %v, 期望 0600", info.Mode().Perm())
+		}
+	}
+
+	again, fp2, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp2 != fp || string(again.Certificate[0]) != string(cert.Certificate[0]) {
+		t.Errorf("重新加载后指纹从 %s 变为 %s，证书应被重复使用", fp, fp2)
+	}
+}
+
+func TestLoadOrCreateRegeneratesInvalidCertificate(t *testing.T) {
+	dir := t.TempDir()
+	_, fp, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, certFileName), []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	_, fp2, err := LoadOrCreate(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fp2 == fp || !fingerprintPattern.MatchString(fp2) {
+		t.Errorf("证书损坏后应重新生成，旧指纹 %s，新指纹 %s", fp, fp2)
+	}
+	if _, fp3, err := LoadOrCreate(dir); err != nil || fp3 != fp2 {
+		t.Errorf("重新生成的证书应被保存并复用: %s, %v", fp3, err)
+	}
+}
+
+func TestFingerprintFormat(t *testing.T) {
+	got := Fingerprint([]byte("abc"))
+	const want = "BA:78:16:BF:8F:01:CF:EA:41:41:40:DE:5D:AE:22:23:B0:03:61:A3:96:17:7A:9C:B4:10:FF:61:F2:00:15:AD"
+	if got != want {
+		t.Errorf("Fingerprint(\"abc\") = %s, 期望 %s", got, want)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// runTray 系统托盘目前只在 Windows 上提供，其他平台直接以前台模式运行。
+func runTray() {
+	runHeadless()
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+	"unsafe"
+
+	"bealinkserver/app"
+	"bealinkserver/platform"
+
+	"github.com/getlantern/systray"
+)
+
+const darkIconFileName = "assets/dark.ico"
+
+var (
+	actualServerAddr string
+	backend          *platform.Backend
+)
+
+func loadIconBytes(fileName string) []byte {
+	exePath, _ := os.Executable()
+	iconPath := filepath.Join(filepath.Dir(exePath), fileName)
+	iconBytes, err := os.ReadFile(iconPath)
+	if err != nil {
+		log.Printf("警告: 加载图标 %s 失败: %v", iconPath, err)
+		return nil
+	}
+	return iconBytes
+}
+
+// getIconFileName 返回深色图标文件名（始终使用深色）
+func getIconFileName() string {
+	return darkIconFileName
+}
+func openBrowser(url string) error { /* ... (代码同前) ... */
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("cmd", "/c", "start", "", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "linux":
+		cmd = exec.Command("xdg-open", url)
+	default:
+		return fmt.Errorf("不支持的操作系统: %s", runtime.GOOS)
+	}
+	log.Printf("尝试在浏览器中打开: %s", url)
+	return cmd.Start()
+}
+
+func ensureSingleInstance() bool {
+	kernel32 := syscall.NewLazyDLL("kernel32.dll")
+	procCreateMutexW := kernel32.NewProc("CreateMutexW")
+
+	// 使用更具体的互斥体名称并添加错误检查
+	mutexName, err := syscall.UTF16PtrFromString("Global\\BealinkGoServer_SingleInstance_Mutex_v1")
+	if err != nil {
+		log.Printf("错误: 创建互斥体名称失败: %v", err)
+		return false
+	}
+
+	// 创建互斥体并立即获取错误代码
+	h, _, errNo := procCreateMutexW.Call(0, 1, uintptr(unsafe.Pointer(mutexName)))
+	lastErr := errNo.(syscall.Errno)
+
+	if h == 0 {
+		log.Printf("错误: 创建互斥体失败: %v", lastErr)
+		return false
+	}
+
+	// 如果互斥体已存在，则查找主窗口并激活
+	if lastErr == syscall.ERROR_ALREADY_EXISTS {
+		go func() {
+			// 等待一小段时间确保消息框不会太快显示
+			time.Sleep(100 * time.Millisecond)
+			findAndActivateMainWindow()
+		}()
+		return false
+	}
+
+	// 保存句柄以便程序退出时清理
+	runtime.SetFinalizer(&h, func(handle *uintptr) {
+		syscall.CloseHandle(syscall.Handle(*handle))
+	})
+
+	return true
+}
+
+// runTray 以系统托盘模式运行：核心服务由 app 启动，托盘只负责菜单交互。
+func runTray() {
+	if !ensureSingleInstance() {
+		// 直接在主线程中显示消息框，确保它能显示出来
+		title, _ := syscall.UTF16PtrFromString("Bealink 提示")
+		text, _ := syscall.UTF16PtrFromString("Bealink 服务已在运行中。\n请查看系统托盘区的图标。")
+		user32 := syscall.NewLazyDLL("user32.dll")
+		procMessageBoxW := user32.NewProc("MessageBoxW")
+		const MB_OK = 0x00000000
+		const MB_ICONINFORMATION = 0x00000040
+		const MB_SYSTEMMODAL = 0x00001000
+		const MB_SETFOREGROUND = 0x00010000
+		const MB_TOPMOST = 0x00040000
+		procMessageBoxW.Call(0,
+			uintptr(unsafe.Pointer(text)),
+			uintptr(unsafe.Pointer(title)),
+			uintptr(MB_OK|MB_ICONINFORMATION|MB_SYSTEMMODAL|MB_SETFOREGROUND|MB_TOPMOST))
+		return
+	}
+	initLogging(false)
+	backend = newBackend()
+	systray.Run(onReady, onExit)
+}
+
+func onReady() {
+	log.Println("系统托盘准备就绪。")
+	coreServiceCtx, coreServiceCancel := context.WithCancel(context.Background())
+	core := app.New(backend)
+	if errServerStart := core.Start(coreServiceCtx); errServerStart != nil {
+		log.Fatalf("!!! 致命错误: HTTP服务启动失败: %v。", errServerStart)
+		iconBytes := loadIconBytes(darkIconFileName)
+		if iconBytes != nil {
+			systray.SetIcon(iconBytes)
+		}
+		systray.SetTitle("Bealink Go - 错误")
+		systray.SetTooltip(fmt.Sprintf("服务启动失败: %v", errServerStart))
+		mErrItem := systray.AddMenuItem(fmt.Sprintf("错误: %v", errServerStart), "服务无法启动")
+		mErrItem.Disable()
+		mQuitErr := systray.AddMenuItem("退出", "关闭程序")
+		go func() { <-mQuitErr.ClickedCh; coreServiceCancel(); systray.Quit() }()
+		return
+	}
+	actualServerAddr = core.Addr()
+	iconBytes := loadIconBytes(darkIconFileName)
+	if iconBytes != nil {
+		systray.SetIcon(iconBytes)
+	}
+	systray.SetTitle("Bealink Go 服务")
+	systray.SetTooltip(fmt.Sprintf("Bealink Go (监听于 %s)", actualServerAddr))
+	mSettings := systray.AddMenuItem("设置", "打开程序设置页面")
+	mAutoStart := systray.AddMenuItem("开机自启", "设置/取消开机自启")
+	autoStartEnabled, errAS := backend.AutoStart.IsEnabled()
+	if errAS == nil && autoStartEnabled {
+		mAutoStart.Check()
+	} else if errAS != nil {
+		log.Printf("错误: 检查开机自启状态失败: %v", errAS)
+		mAutoStart.Disable()
+	}
+	systray.AddSeparator()
+	mQuit := systray.AddMenuItem("退出", "关闭服务")
+
+	go func() {
+		defer log.Println("托盘菜单事件处理循环已退出。")
+		for {
+			select {
+			case <-mSettings.ClickedCh:
+				if actualServerAddr == "" {
+					log.Println("错误: 服务器地址未知，无法打开设置页面。")
+					continue
+				}
+				settingsURL := core.LocalURL("/setting")
+				if err := openBrowser(settingsURL); err != nil {
+					log.Printf("错误: 打开设置页面 (%s) 失败: %v", settingsURL, err)
+				}
+			case <-mAutoStart.ClickedCh:
+				if mAutoStart.Checked() {
+					if err := backend.AutoStart.Disable(); err == nil {
+						mAutoStart.Uncheck()
+						log.Println("开机自启已禁用。")
+					} else {
+						log.Printf("错误: 禁用开机自启失败: %v", err)
+					}
+				} else {
+					if err := backend.AutoStart.Enable(); err == nil {
+						mAutoStart.Check()
+						log.Println("开机自启已启用。")
+					} else {
+						log.Printf("错误: 启用开机自启失败: %v", err)
+					}
+				}
+			case <-mQuit.ClickedCh:
+				log.Println("收到退出请求 (来自托盘菜单)...")
+				coreServiceCancel()
+				systray.Quit()
+				return
+			case <-coreServiceCtx.Done():
+				log.Println("核心服务上下文已取消，准备退出托盘 (来自coreServiceCtx.Done)。")
+				systray.Quit()
+				return
+			}
+		}
+	}()
+	log.Println("onReady 执行完毕。")
+}
+func onExit() { log.Println("程序正在退出 (onExit)...") }
+
+func findAndActivateMainWindow() {
+	user32 := syscall.NewLazyDLL("user32.dll")
+	procFindWindowW := user32.NewProc("FindWindowW")
+	procSetForegroundWindow := user32.NewProc("SetForegroundWindow")
+
+	// 尝试查找已有的 Bealink 窗口并激活托盘图标
+	className, _ := syscall.UTF16PtrFromString("BealinkPowerEventWnd")
+	windowName, _ := syscall.UTF16PtrFromString("Bealink Power Listener")
+
+	hwnd, _, _ := procFindWindowW.Call(
+		uintptr(unsafe.Pointer(className)),
+		uintptr(unsafe.Pointer(windowName)),
+	)
+
+	if hwnd != 0 {
+		procSetForegroundWindow.Call(hwnd)
+	}
+}
@@ -0,0 +1,3 @@
+// Package winapi 封装 Bealink 使用的 Win32 API（显示器电源、锁定工作站、键盘模拟、剪贴板图片、注册表自启）。
+// 所有实现仅在 Windows 上编译，其他平台请通过 platform 包访问对应能力。
+package winapi
@@ -1,3 +1,5 @@
+//go:build windows
+
 package winapi
 
 import (
@@ -8,6 +10,7 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 	"unsafe"
@@ -97,7 +100,49 @@ const (
 	GHND        = 0x0042
 )
 
-var internalMonitorIsOff bool = false // 程序启动时，假定显示器是开启的
+// 显示器状态。电源事件窗口注册 GUID_CONSOLE_DISPLAY_STATE 通知后由 UpdateMonitorState 更新为系统报告的真实状态，
+// 在此之前（或通知不可用时）只能根据本程序发出的开关指令推测。
+var (
+	monitorMu        sync.Mutex
+	monitorIsOff     bool
+	monitorListeners []func(off bool)
+
+	// monitorPowerMu 串行化开关显示器的“检查状态再执行”，避免并发请求重复发送指令。
+	// 与 monitorMu 分开，发送指令期间电源事件窗口仍可更新状态。
+	monitorPowerMu sync.Mutex
+)
+
+// UpdateMonitorState 记录显示器的新状态，状态变化时调用 OnMonitorStateChange 注册的回调。
+// 由电源事件窗口在收到 GUID_CONSOLE_DISPLAY_STATE 通知时调用，本程序开关显示器后也会先行调用。
+func UpdateMonitorState(off bool) {
+	monitorMu.Lock()
+	if monitorIsOff == off {
+		monitorMu.Unlock()
+		return
+	}
+	monitorIsOff = off
+	listeners := append([]func(bool){}, monitorListeners...)
+	monitorMu.Unlock()
+
+	log.Printf("显示器状态变化: %s", monitorStateText(off))
+	for _, fn := range listeners {
+		fn(off)
+	}
+}
+
+// OnMonitorStateChange 注册显示器状态变化的回调，回调在通知所在的线程中同步执行，不应阻塞。
+func OnMonitorStateChange(fn func(off bool)) {
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+	monitorListeners = append(monitorListeners, fn)
+}
+
+func monitorStateText(off bool) string {
+	if off {
+		return "关闭"
+	}
+	return "开启"
+}
 
 func simulateMouseMove() {
 	var input [1]INPUT
@@ -128,72 +173,90 @@ func preventSleepAndWakeDisplay() {
 	}
 }
 
-// ToggleMonitorPower 切换显示器的电源状态（开/关）。
+// ToggleMonitorPower 切换显示器的电源状态（开/关），返回切换后显示器是否为关闭状态。
 func ToggleMonitorPower() (newStateIsOff bool, err error) {
-	var actionLParam uintptr // 用于 SendMessage 的 LPARAM 参数
-	var actionMessage string
-	var attemptToWake bool = false
-
-	if internalMonitorIsOff { // 当前推测是关闭的，目标是开启
-		actionMessage = "开启显示器"
-		attemptToWake = true
-		actionLParam = ^uintptr(0) // 用户建议的正确方式，等同于 MONITOR_ON (-1) 的位模式
-	} else { // 当前推测是开启的，目标是关闭
-		actionMessage = "关闭显示器"
-		actionLParam = uintptr(MONITOR_OFF) // MONITOR_OFF 是 2
-	}
-
-	log.Printf("准备%s...", actionMessage)
-
-	if attemptToWake {
-		// 步骤1: 通知系统显示器是必需的
-		preventSleepAndWakeDisplay()
-
-		// 步骤2: 发送标准开启指令
-		log.Printf("信息: 尝试发送 SC_MONITORPOWER (MONITOR_ON 参数: %X) 指令...", actionLParam)
-		_, _, sendMsgErrOn := procSendMessage.Call(HWND_BROADCAST, WM_SYSCOMMAND, SC_MONITORPOWER, actionLParam)
-		if sendMsgErrOn != syscall.Errno(0) {
-			if sendMsgErrOn == ERROR_ACCESS_DENIED {
-				log.Printf("警告: SendMessage (MONITOR_ON) 返回 'Access is denied'，这是已知情况。")
-			} else {
-				log.Printf("警告: SendMessage (MONITOR_ON) 调用失败: %v", sendMsgErrOn)
-			}
+	monitorPowerMu.Lock()
+	defer monitorPowerMu.Unlock()
+	off := !GetCurrentMonitorState()
+	if _, err := setMonitorPowerLocked(off); err != nil {
+		return !off, err
+	}
+	return off, nil
+}
+
+// SetMonitorPower 打开 (off=false) 或关闭 (off=true) 显示器。显示器已处于该状态时不做任何操作，返回 changed=false。
+func SetMonitorPower(off bool) (changed bool, err error) {
+	monitorPowerMu.Lock()
+	defer monitorPowerMu.Unlock()
+	return setMonitorPowerLocked(off)
+}
+
+// setMonitorPowerLocked 同 SetMonitorPower，调用方需持有 monitorPowerMu。
+func setMonitorPowerLocked(off bool) (changed bool, err error) {
+	if GetCurrentMonitorState() == off {
+		log.Printf("显示器已经是%s状态，无需操作。", monitorStateText(off))
+		return false, nil
+	}
+	if off {
+		err = turnMonitorOff()
+	} else {
+		turnMonitorOn()
+	}
+	if err != nil {
+		return false, err
+	}
+	// 先按指令更新状态，系统随后发出的 GUID_CONSOLE_DISPLAY_STATE 通知会校正它
+	UpdateMonitorState(off)
+	return true, nil
+}
+
+// turnMonitorOn 唤醒显示器：SC_MONITORPOWER 在新系统上常被拒绝，因此同时模拟鼠标移动。
+func turnMonitorOn() {
+	log.Println("准备开启显示器...")
+	// 步骤1: 通知系统显示器是必需的
+	preventSleepAndWakeDisplay()
+
+	// 步骤2: 发送标准开启指令（等同于 MONITOR_ON (-1) 的位模式）
+	onParam := ^uintptr(0)
+	log.Printf("信息: 尝试发送 SC_MONITORPOWER (MONITOR_ON 参数: %X) 指令...", onParam)
+	_, _, sendMsgErrOn := procSendMessage.Call(HWND_BROADCAST, WM_SYSCOMMAND, SC_MONITORPOWER, onParam)
+	if sendMsgErrOn != syscall.Errno(0) {
+		if sendMsgErrOn == ERROR_ACCESS_DENIED {
+			log.Printf("警告: SendMessage (MONITOR_ON) 返回 'Access is denied'，这是已知情况。")
 		} else {
-			log.Println("信息: SendMessage (MONITOR_ON) 指令已发送。")
+			log.Printf("警告: SendMessage (MONITOR_ON) 调用失败: %v", sendMsgErrOn)
 		}
+	} else {
+		log.Println("信息: SendMessage (MONITOR_ON) 指令已发送。")
+	}
 
-		time.Sleep(150 * time.Millisecond) // 给API一些时间反应
-
-		// 步骤3: 执行核心唤醒操作 - 模拟鼠标移动
-		simulateMouseMove()
+	time.Sleep(150 * time.Millisecond) // 给API一些时间反应
 
-		internalMonitorIsOff = false // 假设唤醒操作会成功
-		log.Printf("%s序列已执行。服务端推测显示器新状态为: 开启", actionMessage)
-		return internalMonitorIsOff, nil
+	// 步骤3: 执行核心唤醒操作 - 模拟鼠标移动
+	simulateMouseMove()
+	log.Println("开启显示器序列已执行。")
+}
 
-	} else { // 关闭显示器的逻辑
-		ret, _, apiErr := procSendMessage.Call(HWND_BROADCAST, WM_SYSCOMMAND, SC_MONITORPOWER, actionLParam)
-		if apiErr != syscall.Errno(0) {
-			if apiErr == ERROR_ACCESS_DENIED {
-				log.Printf("警告: SendMessage (SC_MONITORPOWER - %s) API 调用返回 'Access is denied'。操作通常仍会生效。", actionMessage)
-				internalMonitorIsOff = true // 即使有警告，也假设关闭成功
-				log.Printf("%s命令已处理。服务端推测显示器新状态为: 关闭", actionMessage)
-				return internalMonitorIsOff, nil
-			}
-			// 对于其他错误，保持当前推测状态不变，并返回错误
-			return internalMonitorIsOff, fmt.Errorf("SendMessage (SC_MONITORPOWER - %s) API 调用失败 (err: %w, ret: %d)", actionMessage, apiErr, ret)
+func turnMonitorOff() error {
+	log.Println("准备关闭显示器...")
+	ret, _, apiErr := procSendMessage.Call(HWND_BROADCAST, WM_SYSCOMMAND, SC_MONITORPOWER, uintptr(MONITOR_OFF))
+	if apiErr != syscall.Errno(0) {
+		if apiErr == ERROR_ACCESS_DENIED {
+			// 即使有警告，关闭操作通常仍会生效
+			log.Printf("警告: SendMessage (SC_MONITORPOWER - 关闭显示器) API 调用返回 'Access is denied'。操作通常仍会生效。")
+			return nil
 		}
-		// SendMessage 调用成功 (无错误)
-		internalMonitorIsOff = true
-		log.Printf("%s命令已成功发送。服务端推测显示器新状态为: 关闭", actionMessage)
-		return internalMonitorIsOff, nil
+		return fmt.Errorf("SendMessage (SC_MONITORPOWER - 关闭显示器) API 调用失败 (err: %w, ret: %d)", apiErr, ret)
 	}
+	log.Println("关闭显示器命令已成功发送。")
+	return nil
 }
 
-// GetCurrentMonitorState 返回当前程序推测的显示器状态。
-// true 表示关闭，false 表示开启。
+// GetCurrentMonitorState 返回显示器当前是否关闭（true 表示关闭）。
 func GetCurrentMonitorState() bool {
-	return internalMonitorIsOff
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
+	return monitorIsOff
 }
 
 // SendKeyPress 发送键盘按键 (使用 keybd_event)
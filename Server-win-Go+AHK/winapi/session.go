@@ -0,0 +1,15 @@
+//go:build windows
+
+package winapi
+
+import "fmt"
+
+var procLockWorkStation = user32.NewProc("LockWorkStation")
+
+// LockWorkStation 锁定工作站，效果同 Win+L。
+func LockWorkStation() error {
+	if r, _, err := procLockWorkStation.Call(); r == 0 {
+		return fmt.Errorf("LockWorkStation 失败: %w", err)
+	}
+	return nil
+}
@@ -5,8 +5,10 @@ import android.content.Context
 import androidx.room.Database
 import androidx.room.Room
 import androidx.room.RoomDatabase
+import androidx.room.migration.Migration
+import androidx.sqlite.db.SupportSQLiteDatabase
 
-@Database(entities = [Device::class], version = 1, exportSchema = false)
+@Database(entities = [Device::class], version = 2, exportSchema = false)
 abstract class AppDatabase : RoomDatabase() {
     abstract fun deviceDao(): DeviceDao
 
@@ -14,6 +16,13 @@ abstract class AppDatabase : RoomDatabase() {
         @Volatile //确保INSTANCE对所有线程可见
         private var INSTANCE: AppDatabase? = null
 
+        // 版本 2 为设备增加访问令牌
+        private val MIGRATION_1_2 = object : Migration(1, 2) {
+            override fun migrate(db: SupportSQLiteDatabase) {
+                db.execSQL("ALTER TABLE devices ADD COLUMN accessToken TEXT")
+            }
+        }
+
         fun getDatabase(context: Context): AppDatabase {
             return INSTANCE ?: synchronized(this) {
                 val instance = Room.databaseBuilder(
@@ -21,6 +30,7 @@ abstract class AppDatabase : RoomDatabase() {
                     AppDatabase::class.java,
                     "bealink_database"
                 )
+                    .addMigrations(MIGRATION_1_2)
                     // .fallbackToDestructiveMigration() // 如果需要，在 schema 变更时销毁并重建数据库
                     .build()
                 INSTANCE = instance
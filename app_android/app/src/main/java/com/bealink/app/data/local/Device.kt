@@ -10,7 +10,8 @@ data class Device(
     val id: Int = 0,
     var name: String, // 用户自定义昵称
     var hostname: String?, // 例如 my-pc (不含 .local)，或者 IP 地址
-    var macAddress: String? // MAC 地址，用于 WOL
+    var macAddress: String?, // MAC 地址，用于 WOL
+    var accessToken: String? = null // 电脑设置页面创建的访问令牌，以 Authorization: Bearer 发送
 ) {
     // 辅助函数，用于显示设备名称
     fun getDisplayName(): String {
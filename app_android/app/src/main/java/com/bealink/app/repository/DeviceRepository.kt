@@ -101,9 +101,18 @@ class DeviceRepository(
         return Pair(isOnline, if (isOnline) latency else null)
     }
 
+    // 设备配置了访问令牌时附加 Authorization 请求头；电脑开启「要求令牌」后，局域网请求没有令牌会返回 401
+    private fun Request.Builder.withAccessToken(accessToken: String?): Request.Builder {
+        if (!accessToken.isNullOrBlank()) {
+            header("Authorization", "Bearer ${accessToken.trim()}")
+        }
+        return this
+    }
+
     // 为 POST 请求创建一个新的通用方法，或者修改 makeApiCall 以支持 POST
     private suspend fun makePostApiCall(
         resolvedIp: String?,
+        accessToken: String?,
         endpointPath: String,
         jsonBody: String, // 新增参数：JSON字符串格式的请求体
         client: OkHttpClient = defaultHttpClient
@@ -127,6 +136,7 @@ class DeviceRepository(
                 val request = Request.Builder()
                     .url(url)
                     .post(requestBody) // 使用 post 方法并传入请求体
+                    .withAccessToken(accessToken)
                     .build()
 
                 client.newCall(request).execute().use { response ->
@@ -155,6 +165,7 @@ class DeviceRepository(
     // 原来的 GET 请求的通用方法 (如果其他地方还在用，保留它)
     private suspend fun makeGetApiCall(
         resolvedIp: String?,
+        accessToken: String?,
         endpointPath: String,
         client: OkHttpClient = defaultHttpClient
     ): Result<String> {
@@ -168,7 +179,7 @@ class DeviceRepository(
                 urlBuilder.addPathSegments(endpointPath.trimStart('/'))
                 val url = urlBuilder.build().toString()
                 Log.d(TAG, "发起GET API请求: $url")
-                val request = Request.Builder().url(url).get().build() // GET请求
+                val request = Request.Builder().url(url).get().withAccessToken(accessToken).build() // GET请求
 
                 client.newCall(request).execute().use { response ->
                     val responseBody = response.body?.string() ?: ""
@@ -194,11 +205,12 @@ class DeviceRepository(
     }
 
 
-    suspend fun sendSleepCommand(resolvedIp: String?): Result<String> = makeGetApiCall(resolvedIp, "/sleep") // 假设这些还是GET
-    suspend fun sendShutdownCommand(resolvedIp: String?): Result<String> = makeGetApiCall(resolvedIp, "/shutdown") // 假设这些还是GET
-    suspend fun sendMonitorToggleCommand(resolvedIp: String?): Result<String> = makeGetApiCall(resolvedIp, "/monitor") // 假设这些还是GET
+    // 服务端的操作接口只接受 POST
+    suspend fun sendSleepCommand(resolvedIp: String?, accessToken: String?): Result<String> = makePostApiCall(resolvedIp, accessToken, "/sleep", "{}")
+    suspend fun sendShutdownCommand(resolvedIp: String?, accessToken: String?): Result<String> = makePostApiCall(resolvedIp, accessToken, "/shutdown", "{}")
+    suspend fun sendMonitorToggleCommand(resolvedIp: String?, accessToken: String?): Result<String> = makePostApiCall(resolvedIp, accessToken, "/monitor", "{}")
 
-    suspend fun syncToPcClipboard(resolvedIp: String?, content: String): Result<String> {
+    suspend fun syncToPcClipboard(resolvedIp: String?, accessToken: String?, content: String): Result<String> {
         // 1. 创建 JSON 对象
         val jsonObject = JSONObject()
         try {
@@ -211,11 +223,11 @@ class DeviceRepository(
 
         // 2. 调用新的 POST 方法
         // 注意：这里的 endpointPath 现在只是 "/clip"，不再包含内容
-        return makePostApiCall(resolvedIp, "/clip", jsonBodyString)
+        return makePostApiCall(resolvedIp, accessToken, "/clip", jsonBodyString)
     }
 
     // getFromPcClipboard 仍然是 GET 请求，所以它使用 makeGetApiCall
-    suspend fun getFromPcClipboard(resolvedIp: String?): Result<String> = makeGetApiCall(resolvedIp, "/getclip")
+    suspend fun getFromPcClipboard(resolvedIp: String?, accessToken: String?): Result<String> = makeGetApiCall(resolvedIp, accessToken, "/getclip")
 
     suspend fun wakeDevice(macAddress: String?): Boolean {
         return WOLUtil.sendMagicPacket(macAddress ?: "")
@@ -12,6 +12,7 @@ import androidx.compose.ui.Modifier
 import androidx.compose.ui.text.input.ImeAction
 import androidx.compose.ui.text.input.KeyboardCapitalization
 import androidx.compose.ui.text.input.KeyboardType
+import androidx.compose.ui.text.input.PasswordVisualTransformation
 import androidx.compose.ui.unit.dp
 import androidx.compose.ui.window.Dialog
 import com.bealink.app.data.local.Device // 假设 Device.kt 在此路径
@@ -21,7 +22,7 @@ import com.bealink.app.network.WOLUtil // 假设 WOLUtil.kt 在此路径
 fun DeviceSettingsDialog(
     showDialog: Boolean,
     onDismissRequest: () -> Unit,
-    onSaveDevice: (id: Int?, name: String, hostname: String?, macAddress: String?) -> Unit,
+    onSaveDevice: (id: Int?, name: String, hostname: String?, macAddress: String?, accessToken: String?) -> Unit,
     existingDevice: Device? = null,
     onDeleteDevice: ((Device) -> Unit)? = null,
     showSnackbar: (String) -> Unit
@@ -31,6 +32,7 @@ fun DeviceSettingsDialog(
         var deviceNameInput by remember(existingDevice?.id) { mutableStateOf(existingDevice?.name ?: "") }
         var hostnameInput by remember(existingDevice?.id) { mutableStateOf(existingDevice?.hostname ?: "") }
         var macAddressUserInput by remember(existingDevice?.id) { mutableStateOf(WOLUtil.formatMacAddress(existingDevice?.macAddress) ?: "") }
+        var accessTokenInput by remember(existingDevice?.id) { mutableStateOf(existingDevice?.accessToken ?: "") }
 
         // 使用 derivedStateOf 优化设备名称的派生逻辑
         val determinedDeviceName by remember(deviceNameInput, hostnameInput, macAddressUserInput) {
@@ -97,12 +99,30 @@ fun DeviceSettingsDialog(
                         // 修改这里的 placeholder
                         placeholder = { Text("例: AA:BB:CC:11:22:33") },
                         singleLine = true,
-                        keyboardOptions = KeyboardOptions(keyboardType = KeyboardType.Ascii, imeAction = ImeAction.Done),
+                        keyboardOptions = KeyboardOptions(keyboardType = KeyboardType.Ascii, imeAction = ImeAction.Next),
                         modifier = Modifier.fillMaxWidth()
                     )
                     Text(
                         text = "用于网络唤醒 (WOL)",
                         style = MaterialTheme.typography.labelSmall,
+                        modifier = Modifier
+                            .fillMaxWidth()
+                            .padding(start = 4.dp, bottom = 8.dp)
+                    )
+
+                    OutlinedTextField(
+                        value = accessTokenInput,
+                        onValueChange = { accessTokenInput = it.trim() },
+                        label = { Text("访问令牌 (可选)") },
+                        placeholder = { Text("例: bl_...") },
+                        singleLine = true,
+                        visualTransformation = PasswordVisualTransformation(),
+                        keyboardOptions = KeyboardOptions(keyboardType = KeyboardType.Password, imeAction = ImeAction.Done),
+                        modifier = Modifier.fillMaxWidth()
+                    )
+                    Text(
+                        text = "在电脑设置页面「访问令牌」中创建，需要电源和剪贴板权限",
+                        style = MaterialTheme.typography.labelSmall,
                         modifier = Modifier
                             .fillMaxWidth()
                             .padding(start = 4.dp, bottom = 16.dp)
@@ -151,7 +171,8 @@ fun DeviceSettingsDialog(
                                         existingDevice?.id,
                                         finalName,
                                         finalHostname,
-                                        normalizedMac // 保存规范化后的MAC地址
+                                        normalizedMac, // 保存规范化后的MAC地址
+                                        accessTokenInput.takeIf { it.isNotBlank() }
                                     )
                                     onDismissRequest() // 保存成功后关闭对话框
                                 }
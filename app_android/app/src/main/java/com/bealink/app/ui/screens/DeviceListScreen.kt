@@ -144,8 +144,8 @@ fun DeviceListScreen(
                 DeviceSettingsDialog(
                     showDialog = showDialog,
                     onDismissRequest = { showDialog = false },
-                    onSaveDevice = { id, name, hostname, macAddress ->
-                        viewModel.addOrUpdateDevice(id, name, hostname, macAddress)
+                    onSaveDevice = { id, name, hostname, macAddress, accessToken ->
+                        viewModel.addOrUpdateDevice(id, name, hostname, macAddress, accessToken)
                         // Dialog 关闭由 onDismissRequest 或按钮点击后的逻辑处理
                     },
                     existingDevice = deviceToEdit,
@@ -137,7 +137,7 @@ class DeviceViewModel(
         }
     }
 
-    fun addOrUpdateDevice(id: Int?, name: String, hostname: String?, macAddress: String?) {
+    fun addOrUpdateDevice(id: Int?, name: String, hostname: String?, macAddress: String?, accessToken: String?) {
         viewModelScope.launch {
             val finalHostname = hostname?.trim()?.takeIf { it.isNotBlank() }
             val normalizedMac = WOLUtil.normalizeMacAddress(macAddress)
@@ -166,7 +166,8 @@ class DeviceViewModel(
                 id = id ?: 0,
                 name = deviceName,
                 hostname = finalHostname,
-                macAddress = normalizedMac
+                macAddress = normalizedMac,
+                accessToken = accessToken?.trim()?.takeIf { it.isNotBlank() }
             )
 
             try {
@@ -199,7 +200,7 @@ class DeviceViewModel(
     // 通用设备HTTP操作方法
     private fun performDeviceHttpAction(
         deviceState: DeviceUiState,
-        actionApiCall: suspend (String?) -> Result<String>,
+        actionApiCall: suspend (String?, String?) -> Result<String>, // (IP, 访问令牌)
         actionName: String,
         successMessageFormatter: (String) -> String
     ) {
@@ -223,7 +224,7 @@ class DeviceViewModel(
                 list.map { if (it.device.id == deviceState.device.id) it.copy(isLoadingAction = true) else it }
             }
 
-            val result = actionApiCall(ipToUse)
+            val result = actionApiCall(ipToUse, deviceState.device.accessToken)
 
             _devicesUiStateFlow.update { list ->
                 list.map { if (it.device.id == deviceState.device.id) it.copy(isLoadingAction = false) else it }
@@ -255,7 +256,7 @@ class DeviceViewModel(
             if (!textToSync.isNullOrBlank()) {
                 performDeviceHttpAction(
                     deviceState,
-                    { ip -> repository.syncToPcClipboard(ip, textToSync) },
+                    { ip, token -> repository.syncToPcClipboard(ip, token, textToSync) },
                     "同步到电脑", // This actionName is used in performDeviceHttpAction for filtering
                     successMessageFormatter = { serverResponse -> // serverResponse here is already filtered
                         "已发送\uD83D\uDCCB: ${serverResponse.take(50)}${if(serverResponse.length > 50) "..." else ""}"